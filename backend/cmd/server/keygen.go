@@ -0,0 +1,62 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+
+	"sample-go-app/internal/config"
+)
+
+const keygenUsage = `usage: server keygen <algorithm> <file>
+
+Writes a new PEM private key for signing JWTs, to be used as FORUM_JWT_KEY_FILE.
+algorithm is one of RS256, ES256 or EdDSA.`
+
+// Run the keygen subcommand with the arguments following "keygen"
+func runKeygen(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("wrong number of arguments\n%s", keygenUsage)
+	}
+	algorithm, path := args[0], args[1]
+
+	var key crypto.Signer
+	var err error
+	switch algorithm {
+	case config.AlgRS256:
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case config.AlgES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case config.AlgEdDSA:
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return fmt.Errorf("unsupported algorithm %q\n%s", algorithm, keygenUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	// Refuse to overwrite an existing key, which would invalidate every token it signed
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %s key to %s\n", algorithm, path)
+	return nil
+}
@@ -1,35 +1,97 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 
 	"sample-go-app/internal/auth"
+	"sample-go-app/internal/config"
 	db "sample-go-app/internal/database"
+	"sample-go-app/internal/handlers"
+	"sample-go-app/internal/jobs"
+	"sample-go-app/internal/mail"
 	"sample-go-app/internal/router"
+	"sample-go-app/internal/store/sqlstore"
 
 	_ "modernc.org/sqlite"
 )
 
 func main() {
+	// Key generation subcommand: server keygen <RS256|ES256|EdDSA> <file>
+	// Handled before loading the configuration, since it doesn't need any
+	if len(os.Args) > 1 && os.Args[1] == "keygen" {
+		if err := runKeygen(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Resolve the configuration: defaults < config file < FORUM_* env vars < flags
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(cfg.Args) > 0 {
+		switch cfg.Args[0] {
+		// Print the effective configuration: server [flags] config
+		case "config":
+			cfg.Dump(os.Stdout)
+			return
+		// Schema management subcommand: server [flags] migrate <up|down|status|to>
+		case "migrate":
+			if err := runMigrate(cfg.Args[1:], cfg.Database); err != nil {
+				log.Fatal(err)
+			}
+			return
+		default:
+			log.Fatalf("unknown command %q, expected config, migrate or keygen", cfg.Args[0])
+		}
+	}
 
 	fmt.Println("Hello World")
-	fmt.Println("For debugging, admin username/password: admin123")
-	fmt.Println("Non-admin username/password: 123")
+	if cfg.Admin.Seed {
+		fmt.Printf("For debugging, admin username: %s\n", cfg.Admin.Username)
+	}
+
+	// Show what the server is running with, then anything unsafe about it
+	log.Println("Effective configuration:")
+	cfg.Dump(log.Writer())
+	for _, warning := range cfg.Warnings() {
+		log.Printf("WARNING: %s", warning)
+	}
 
 	// Initialize Database
-	db.InitDatabase()
+	db.InitDatabase(&cfg.Config)
 	// Initialize JWT
-	auth.InitJWT()
+	if err := auth.InitJWT(cfg.Auth); err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	// Build the handlers on top of the SQL stores
+	stores := sqlstore.New(db.DB, db.CurrentDialect).Stores()
+	h := handlers.New(stores, &cfg.Config)
+
+	// Remove soft deleted content once its retention period is over
+	go jobs.RunPurge(context.Background(), stores.Purge, cfg.Purge)
+
+	// Deliver queued emails and queue the digests that are due
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to set up the mailer: %v", err)
+	}
+	go jobs.RunOutbox(context.Background(), stores.Emails, mailer, cfg.Mail)
+	go jobs.RunDigests(context.Background(), stores, cfg.Mail)
 
 	// Setup router and routes
-	r := router.Setup()
+	r := router.Setup(h, cfg.Server)
 
 	// Start the server
-	port := ":8080"
-	log.Printf("Starting server on port %s...", port)
-	if err := http.ListenAndServe(port, r); err != nil {
+	log.Printf("Starting server on %s...", cfg.Server.Addr)
+	if err := http.ListenAndServe(cfg.Server.Addr, r); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"sample-go-app/internal/config"
+	db "sample-go-app/internal/database"
+)
+
+const migrateUsage = `usage: server migrate <command>
+
+commands:
+  up            apply all pending migrations
+  down [n]      revert the last n applied migrations (default 1)
+  status        list migrations and whether they are applied
+  to <version>  migrate up or down to exactly the given version`
+
+// Run the migrate subcommand with the arguments following "migrate"
+func runMigrate(args []string, cfg config.DatabaseConfig) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing migrate command\n%s", migrateUsage)
+	}
+
+	db.OpenDatabase(cfg)
+	defer db.DB.Close()
+
+	switch args[0] {
+	case "up":
+		if err := db.MigrateUp(db.DB, db.CurrentDialect); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		if err := db.MigrateDown(db.DB, db.CurrentDialect, steps); err != nil {
+			return err
+		}
+	case "to":
+		if len(args) < 2 {
+			return fmt.Errorf("missing target version\n%s", migrateUsage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if err := db.MigrateTo(db.DB, db.CurrentDialect, version); err != nil {
+			return err
+		}
+	case "status":
+		return printMigrationStatus()
+	default:
+		return fmt.Errorf("unknown migrate command %q\n%s", args[0], migrateUsage)
+	}
+
+	return printMigrationStatus()
+}
+
+// Print every migration and when it was applied
+func printMigrationStatus() error {
+	states, err := db.MigrationStatus(db.DB, db.CurrentDialect)
+	if err != nil {
+		return err
+	}
+
+	for _, state := range states {
+		status := "pending"
+		if state.Applied {
+			status = "applied " + state.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(os.Stdout, "%04d_%-30s %s\n", state.Version, state.Name, status)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"sample-go-app/internal/models"
+
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// Models the identity of the user making the current request, as carried by their JWT
+type CurrentUser struct {
+	ID       int
+	Username string
+	IsAdmin  bool
+	// Session the token was issued for
+	SessionID string
+}
+
+// Converts the identity back into the user model shared with the frontend
+func (u *CurrentUser) Model() models.User {
+	user := models.User{ID: u.ID, Username: u.Username}
+	if u.IsAdmin {
+		user.IsAdmin = 1
+	}
+	return user
+}
+
+// Unexported key type, so no other package can collide with our context values
+type contextKey struct {
+	name string
+}
+
+var currentUserKey = &contextKey{"currentUser"}
+
+var ErrInvalidIdentity = errors.New("token does not carry valid user data")
+
+// Build the typed identity from the "userData" claim written by GenerateToken
+func userFromClaims(claims map[string]interface{}) (*CurrentUser, error) {
+	rawUserData, ok := claims["userData"]
+	if !ok {
+		return nil, ErrInvalidIdentity
+	}
+
+	// Round-trip through JSON so numbers are decoded into the right types
+	// instead of asserting on the float64s produced by the JWT parser
+	encoded, err := json.Marshal(rawUserData)
+	if err != nil {
+		return nil, ErrInvalidIdentity
+	}
+	var userData models.User
+	if err := json.Unmarshal(encoded, &userData); err != nil {
+		return nil, ErrInvalidIdentity
+	}
+	if userData.ID <= 0 {
+		return nil, ErrInvalidIdentity
+	}
+
+	sessionID, _ := claims["sid"].(string)
+	return &CurrentUser{
+		ID:        userData.ID,
+		Username:  userData.Username,
+		IsAdmin:   userData.IsAdmin == 1,
+		SessionID: sessionID,
+	}, nil
+}
+
+// Extracts the current user from the verified JWT once and stores it in the request context
+// Must be mounted after Verifier and jwtauth.Authenticator
+func IdentityMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil {
+				http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+
+			user, err := userFromClaims(claims)
+			if err != nil {
+				http.Error(w, `{"error": "Invalid user data"}`, http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithCurrentUser(r.Context(), user)))
+		})
+	}
+}
+
+// Stores the current user in the request context when a valid JWT is present, without requiring one
+// Lets public endpoints personalise their responses, must be mounted after Verifier
+func OptionalIdentityMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, claims, err := jwtauth.FromContext(r.Context())
+			if err != nil || token == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			user, err := userFromClaims(claims)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithCurrentUser(r.Context(), user)))
+		})
+	}
+}
+
+// Returns a copy of ctx carrying the given user
+func WithCurrentUser(ctx context.Context, user *CurrentUser) context.Context {
+	return context.WithValue(ctx, currentUserKey, user)
+}
+
+// Get the current user stored by IdentityMiddleware
+func UserFromContext(ctx context.Context) (*CurrentUser, bool) {
+	user, ok := ctx.Value(currentUserKey).(*CurrentUser)
+	return user, ok && user != nil
+}
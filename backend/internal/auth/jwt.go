@@ -1,6 +1,10 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"net/http"
 	"time"
 
@@ -9,33 +13,91 @@ import (
 	"github.com/go-chi/jwtauth/v5"
 )
 
+// Signs access tokens with the current key, set up by InitJWT
 var TokenAuth *jwtauth.JWTAuth
 
-func InitJWT() {
-	// "secret-key" is a placeholder for now
-	TokenAuth = jwtauth.New("HS256", []byte("secret-key"), nil)
+// Token lifetimes, set by InitJWT
+var (
+	// Access tokens are short-lived, so a demotion or revocation takes effect quickly
+	AccessTokenTTL time.Duration
+	// Refresh tokens rotate on every use, the session ends after this long without one
+	RefreshTokenTTL time.Duration
+)
+
+// Models a signed access token together with the claims needed to revoke it
+type AccessToken struct {
+	Token     string
+	ID        string // jti claim
+	ExpiresAt time.Time
 }
 
-// Generate a JWT token for a particular user.
-func GenerateToken(userData models.User) (string, error) {
+// Generate a JWT token for a particular user, belonging to the given session
+func GenerateToken(userData models.User, sessionID string) (AccessToken, error) {
+	tokenID, err := randomToken(16)
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(AccessTokenTTL)
 	claims := map[string]interface{}{
 		"userData": userData,
-		"exp":      time.Now().Add(time.Hour * 72).Unix(), // 3 days expiry
+		"sid":      sessionID,
+		"jti":      tokenID,
+		"iat":      now.Unix(),
+		"exp":      expiresAt.Unix(),
 	}
 	_, tokenString, err := TokenAuth.Encode(claims)
-	return tokenString, err
+	if err != nil {
+		return AccessToken{}, err
+	}
+	return AccessToken{Token: tokenString, ID: tokenID, ExpiresAt: expiresAt}, nil
+}
+
+// Generate an opaque refresh token, only its hash is ever stored
+func NewRefreshToken() (string, error) {
+	return randomToken(32)
+}
+
+// Generate an opaque email verification token, only its hash is ever stored
+func NewVerificationToken() (string, error) {
+	return randomToken(32)
+}
+
+// Generate a random session ID
+func NewSessionID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Hash a refresh or verification token for storage and lookup
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Random URL-safe string carrying n bytes of entropy
+func randomToken(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
 }
 
 // Set JWT as a secure http-only cookie
-func SetTokenCookie(w http.ResponseWriter, token string) {
+func SetTokenCookie(w http.ResponseWriter, token AccessToken) {
 	cookie := &http.Cookie{
 		Name:     "jwt",
-		Value:    token,
+		Value:    token.Token,
 		Path:     "/",
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteLaxMode,
-		Expires:  time.Now().Add(time.Hour * 72),
+		Expires:  token.ExpiresAt,
 	}
 	http.SetCookie(w, cookie)
 }
@@ -51,3 +113,32 @@ func ClearTokenCookie(w http.ResponseWriter) {
 	}
 	http.SetCookie(w, cookie)
 }
+
+// Name of the cookie holding the refresh token
+const RefreshCookieName = "refresh_token"
+
+// Set the refresh token as a secure http-only cookie, only sent to the API
+func SetRefreshCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
+	cookie := &http.Cookie{
+		Name:     RefreshCookieName,
+		Value:    token,
+		Path:     "/api",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  expiresAt,
+	}
+	http.SetCookie(w, cookie)
+}
+
+// Clear the refresh token
+func ClearRefreshCookie(w http.ResponseWriter) {
+	cookie := &http.Cookie{
+		Name:     RefreshCookieName,
+		Value:    "",
+		Path:     "/api",
+		HttpOnly: true,
+		Expires:  time.Unix(0, 0),
+	}
+	http.SetCookie(w, cookie)
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"sample-go-app/internal/config"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+var (
+	// Every key a token may be signed with, looked up by the token's kid header
+	verifyKeys jwk.Set
+	// Public halves of the asymmetric verification keys, served as the JWKS document
+	publicKeys jwk.Set
+)
+
+var ErrNoSigningKey = errors.New("no JWT signing key configured")
+
+// Load the signing and verification keys, set up TokenAuth and the token lifetimes
+func InitJWT(cfg config.AuthConfig) error {
+	var signingKey jwk.Key
+	var err error
+	switch cfg.JWTAlgorithm {
+	case config.AlgHS256:
+		if cfg.JWTSecret == "" {
+			return ErrNoSigningKey
+		}
+		signingKey, err = secretKey(cfg.JWTSecret)
+	case config.AlgRS256, config.AlgES256, config.AlgEdDSA:
+		if cfg.JWTKeyFile == "" {
+			return ErrNoSigningKey
+		}
+		signingKey, err = keyFromFile(cfg.JWTKeyFile)
+		if err == nil && algorithmOf(signingKey) != cfg.JWTAlgorithm {
+			err = fmt.Errorf("key in %s is not a %s key", cfg.JWTKeyFile, cfg.JWTAlgorithm)
+		}
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %q", cfg.JWTAlgorithm)
+	}
+	if err != nil {
+		return err
+	}
+
+	verify := jwk.NewSet()
+	public := jwk.NewSet()
+	if err := addVerifyKey(verify, public, signingKey); err != nil {
+		return err
+	}
+	for _, secret := range cfg.PreviousSecrets {
+		key, err := secretKey(secret)
+		if err != nil {
+			return err
+		}
+		if err := addVerifyKey(verify, public, key); err != nil {
+			return err
+		}
+	}
+	for _, path := range cfg.PreviousKeyFiles {
+		key, err := keyFromFile(path)
+		if err != nil {
+			return err
+		}
+		if err := addVerifyKey(verify, public, key); err != nil {
+			return err
+		}
+	}
+
+	// jwtauth only ever signs with the current key; verification goes through Verifier and verifyKeys
+	TokenAuth = jwtauth.New(cfg.JWTAlgorithm, signingKey, nil)
+	verifyKeys = verify
+	publicKeys = public
+	AccessTokenTTL = cfg.AccessTokenTTL
+	RefreshTokenTTL = cfg.RefreshTokenTTL
+	return nil
+}
+
+// Get the public verification keys, for the JWKS endpoint
+func PublicKeys() jwk.Set {
+	return publicKeys
+}
+
+// Build an HS256 key from a shared secret
+func secretKey(secret string) (jwk.Key, error) {
+	key, err := jwk.FromRaw([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+	return key, prepareKey(key)
+}
+
+// Read a PEM encoded private or public key
+func keyFromFile(path string) (jwk.Key, error) {
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key: %w", err)
+	}
+	key, err := jwk.ParseKey(pemBytes, jwk.WithPEM(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %s: %w", path, err)
+	}
+	if algorithmOf(key) == "" {
+		return nil, fmt.Errorf("unsupported JWT key type in %s", path)
+	}
+	return key, prepareKey(key)
+}
+
+// Pin the key's algorithm and derive its kid from its thumbprint,
+// so the same key always gets the same kid and tokens can't switch algorithms
+func prepareKey(key jwk.Key) error {
+	if err := key.Set(jwk.AlgorithmKey, jwa.SignatureAlgorithm(algorithmOf(key))); err != nil {
+		return err
+	}
+	return jwk.AssignKeyID(key)
+}
+
+// Signing algorithm used with a key, or "" for unsupported keys
+func algorithmOf(key jwk.Key) string {
+	switch key := key.(type) {
+	case jwk.SymmetricKey:
+		return config.AlgHS256
+	case jwk.RSAPrivateKey, jwk.RSAPublicKey:
+		return config.AlgRS256
+	case jwk.ECDSAPrivateKey:
+		if key.Crv() == jwa.P256 {
+			return config.AlgES256
+		}
+	case jwk.ECDSAPublicKey:
+		if key.Crv() == jwa.P256 {
+			return config.AlgES256
+		}
+	case jwk.OKPPrivateKey:
+		if key.Crv() == jwa.Ed25519 {
+			return config.AlgEdDSA
+		}
+	case jwk.OKPPublicKey:
+		if key.Crv() == jwa.Ed25519 {
+			return config.AlgEdDSA
+		}
+	}
+	return ""
+}
+
+// Accept tokens signed with key, publishing its public half unless it is a shared secret
+func addVerifyKey(verify, public jwk.Set, key jwk.Key) error {
+	if _, symmetric := key.(jwk.SymmetricKey); symmetric {
+		return verify.AddKey(key)
+	}
+
+	publicKey, err := jwk.PublicKeyOf(key)
+	if err != nil {
+		return err
+	}
+	if err := verify.AddKey(publicKey); err != nil {
+		return err
+	}
+	return public.AddKey(publicKey)
+}
@@ -1,68 +1,80 @@
 package auth
 
 import (
-	"fmt"
+	"context"
 	"net/http"
-
-	"github.com/go-chi/jwtauth/v5"
 )
 
-// Enforces admin-only access (i.e. for routes that ONLY admins are allowed to access)
-func AdminMiddleware() func(http.Handler) http.Handler {
+var privilegedAccessKey = &contextKey{"privilegedAccess"}
+
+// Enforces a permission that isn't tied to any particular post or comment (e.g. managing topics)
+// Only the user's global role counts
+func PermissionMiddleware(policy *Policy, permission string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract JWT claims from the context
-			_, claims, _ := jwtauth.FromContext(r.Context())
-			rawUserData, userExists := claims["userData"].(map[string]interface{}) // Extract the user claim as a map
-
-			if !userExists {
-				fmt.Println("User does not exist")
+			// Get the identity extracted by IdentityMiddleware
+			user, ok := UserFromContext(r.Context())
+			if !ok {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			isAdmin := int(rawUserData["isAdmin"].(float64))
-
-			if !(isAdmin == 1) {
+			allowed, err := policy.Can(r.Context(), user, permission, Resource{})
+			if err != nil {
+				http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
 			}
 
-			// Allow access if the user is an admin
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-// Enforces flexible role-based access
-// Checks for either admin or ownership
-// resourceOwnerID func gets the author's id for a particular resource (e.g. post / comment)
-func RoleMiddleware(resourceOwnerIDFunc func(r *http.Request) (int, error)) func(http.Handler) http.Handler {
+// Enforces flexible role-based access to a particular resource (e.g. post / comment)
+// Checks the permission against the user's roles, own-only permissions needing ownership of the resource
+// resourceFunc gets the author and topic of the resource
+func RoleMiddleware(policy *Policy, permission string, resourceFunc func(r *http.Request) (Resource, error)) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Extract JWT claims from the context
-			_, claims, _ := jwtauth.FromContext(r.Context())
-			rawUserData, userExists := claims["userData"].(map[string]interface{}) // Extract the user claim as a map
-
-			if !userExists {
-				fmt.Println("user no exist")
+			// Get the identity extracted by IdentityMiddleware
+			user, ok := UserFromContext(r.Context())
+			if !ok {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
 
-			isAdmin := int(rawUserData["isAdmin"].(float64))
-			userID := int(rawUserData["id"].(float64))
-
-			ownerID, _ := resourceOwnerIDFunc(r)
+			// Missing resources are left for the handler to report
+			resource, err := resourceFunc(r)
+			if err != nil {
+				resource = Resource{OwnerID: -1}
+			}
 
-			// if user is neither adminn nor the owner of the resource
-			if !(isAdmin == 1 || userID == ownerID) {
+			allowed, err := policy.Can(r.Context(), user, permission, resource)
+			if err != nil {
+				http.Error(w, "Failed to check permissions", http.StatusInternalServerError)
+				return
+			}
+			if !allowed {
 				http.Error(w, "Forbidden", http.StatusForbidden)
 				return
 			}
 
-			// Add user info to the context for downstream handlers
+			// Flag users acting on someone else's resource, so moderation can be audited
+			if user.ID != resource.OwnerID {
+				r = r.WithContext(context.WithValue(r.Context(), privilegedAccessKey, true))
+			}
+
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// Whether RoleMiddleware let the user act on a resource they don't own
+func IsPrivilegedAccess(ctx context.Context) bool {
+	privileged, _ := ctx.Value(privilegedAccessKey).(bool)
+	return privileged
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"sample-go-app/internal/models"
+)
+
+// Source of the roles users hold and what each role allows, implemented by the role store
+type RoleSource interface {
+	RolePermissions(ctx context.Context) (map[string][]models.RolePermission, error)
+	UserRoles(ctx context.Context, userID int) ([]models.RoleGrant, error)
+	TopicAncestors(ctx context.Context, topicID int) ([]int, error)
+}
+
+// What an action is performed on, for permission checks
+// The zero value stands for the forum as a whole
+type Resource struct {
+	// Author of the post / comment, 0 if not applicable
+	OwnerID int
+	// Topic the resource belongs to, 0 if not applicable
+	TopicID int
+}
+
+// Decides what users may do, based on their roles
+type Policy struct {
+	roles RoleSource
+
+	// Role permissions only change with migrations, so they are loaded once
+	mu          sync.Mutex
+	permissions map[string][]models.RolePermission
+}
+
+// Create a policy reading roles from the given source
+func NewPolicy(roles RoleSource) *Policy {
+	return &Policy{roles: roles}
+}
+
+// Get the permissions of every role, loading them on first use
+func (p *Policy) rolePermissions(ctx context.Context) (map[string][]models.RolePermission, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.permissions == nil {
+		permissions, err := p.roles.RolePermissions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.permissions = permissions
+	}
+	return p.permissions, nil
+}
+
+// Check whether a user may perform an action needing permission on a resource
+// The user's global role (member if they have none) applies everywhere, topic roles only within their topic and its subtopics
+func (p *Policy) Can(ctx context.Context, user *CurrentUser, permission string, resource Resource) (bool, error) {
+	// Muted users are read-only whatever roles they hold
+	if IsMuted(ctx) {
+		return false, nil
+	}
+
+	permissions, err := p.rolePermissions(ctx)
+	if err != nil {
+		return false, err
+	}
+	grants, err := p.roles.UserRoles(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	roles := []string{models.RoleMember}
+	topicGrants := []models.RoleGrant{}
+	for _, grant := range grants {
+		if grant.TopicID == 0 {
+			roles[0] = grant.Role
+		} else {
+			topicGrants = append(topicGrants, grant)
+		}
+	}
+	// Topic roles apply to the resource's topic and everything below the topic they were granted in
+	if resource.TopicID != 0 && len(topicGrants) > 0 {
+		ancestors, err := p.roles.TopicAncestors(ctx, resource.TopicID)
+		if err != nil {
+			return false, err
+		}
+		for _, grant := range topicGrants {
+			if slices.Contains(ancestors, grant.TopicID) {
+				roles = append(roles, grant.Role)
+			}
+		}
+	}
+
+	for _, role := range roles {
+		for _, granted := range permissions[role] {
+			if granted.Permission == permission && (!granted.OwnOnly || resource.OwnerID == user.ID) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// Get the rank of a user, that of the highest role they hold globally or in any topic
+func (p *Policy) Rank(ctx context.Context, userID int) (int, error) {
+	grants, err := p.roles.UserRoles(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	global, rank := models.RoleMember, 0
+	for _, grant := range grants {
+		if grant.TopicID == 0 {
+			global = grant.Role
+		} else {
+			rank = max(rank, models.RoleRanks[grant.Role])
+		}
+	}
+	return max(rank, models.RoleRanks[global]), nil
+}
+
+// Check whether a user ranks above another, as needed to sanction them
+func (p *Policy) Outranks(ctx context.Context, userID, otherID int) (bool, error) {
+	rank, err := p.Rank(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	otherRank, err := p.Rank(ctx, otherID)
+	if err != nil {
+		return false, err
+	}
+	return rank > otherRank, nil
+}
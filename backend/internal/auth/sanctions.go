@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"sample-go-app/internal/models"
+)
+
+var mutedKey = &contextKey{"muted"}
+
+// Looks up the suspensions, bans and mutes in force against a user, implemented by the sanction store
+type SanctionSource interface {
+	ActiveSanctions(ctx context.Context, userID int, now time.Time) ([]models.Sanction, error)
+}
+
+// Enforces sanctions on every authenticated request, so they apply even while the user's JWT is still valid
+// Suspended and banned users are refused, muted users are flagged so the policy only lets them read
+// Must be mounted after IdentityMiddleware
+func SanctionMiddleware(sanctions SanctionSource) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := UserFromContext(r.Context())
+			if !ok {
+				http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+
+			active, err := sanctions.ActiveSanctions(r.Context(), user.ID, time.Now().UTC())
+			if err != nil {
+				http.Error(w, `{"error": "Failed to check account status"}`, http.StatusInternalServerError)
+				return
+			}
+
+			muted := false
+			for _, sanction := range active {
+				switch sanction.Kind {
+				case models.SanctionBan:
+					http.Error(w, `{"error": "Account is banned"}`, http.StatusForbidden)
+					return
+				case models.SanctionSuspension:
+					http.Error(w, `{"error": "Account is suspended"}`, http.StatusForbidden)
+					return
+				case models.SanctionMute:
+					muted = true
+				}
+			}
+			if muted {
+				r = r.WithContext(context.WithValue(r.Context(), mutedKey, true))
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Whether SanctionMiddleware found the user muted
+func IsMuted(ctx context.Context) bool {
+	muted, _ := ctx.Value(mutedKey).(bool)
+	return muted
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Looks up access tokens that were revoked before they expired
+type Denylist interface {
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// Verify a token string against every active key, picking the key by the token's kid header
+func verifyToken(tokenString string) (jwt.Token, error) {
+	token, err := jwt.Parse([]byte(tokenString), jwt.WithKeySet(verifyKeys), jwt.WithValidate(true))
+	if err != nil {
+		return nil, jwtauth.ErrorReason(err)
+	}
+	return token, nil
+}
+
+// Replaces jwtauth.Verifier, accepting tokens signed with any active key and
+// additionally treating tokens on the denylist as invalid
+// The outcome is reported through the jwtauth context, so jwtauth.Authenticator rejects
+// bad tokens and OptionalIdentityMiddleware ignores them
+func Verifier(denylist Denylist) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenString := jwtauth.TokenFromHeader(r)
+			if tokenString == "" {
+				tokenString = jwtauth.TokenFromCookie(r)
+			}
+			if tokenString == "" {
+				next.ServeHTTP(w, r.WithContext(jwtauth.NewContext(r.Context(), nil, jwtauth.ErrNoTokenFound)))
+				return
+			}
+
+			token, err := verifyToken(tokenString)
+			if err == nil {
+				// Tokens issued before sessions existed carry no ID and can't be revoked, so refuse them
+				revoked := token.JwtID() == ""
+				if !revoked {
+					revoked, err = denylist.IsTokenRevoked(r.Context(), token.JwtID())
+				}
+				if err == nil && revoked {
+					err = ErrTokenRevoked
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(jwtauth.NewContext(r.Context(), token, err)))
+		})
+	}
+}
@@ -0,0 +1,291 @@
+// Package config holds the typed configuration of the server binary.
+//
+// Values are resolved in increasing order of precedence: built-in defaults, a YAML or TOML
+// config file, FORUM_* environment variables and finally command-line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// JWT signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Placeholder JWT secret, only acceptable for local development
+const DevJWTSecret = "secret-key"
+
+// Ways of delivering email
+const (
+	MailLog  = "log"  // write emails to the server log
+	MailFile = "file" // write each email to its own .eml file
+	MailSMTP = "smtp"
+)
+
+// Models the complete server configuration
+type Config struct {
+	Server   ServerConfig
+	Database DatabaseConfig
+	Auth     AuthConfig
+	Admin    AdminConfig
+	Purge    PurgeConfig
+	Stream   StreamConfig
+	Mail     MailConfig
+}
+
+// Models the HTTP listener
+type ServerConfig struct {
+	Addr string
+	// Origins allowed to make credentialed cross-origin requests (i.e. the frontend)
+	AllowedOrigins []string
+}
+
+// Models the database connection
+type DatabaseConfig struct {
+	Driver string // "sqlite" or "postgres"
+	DSN    string // file path or connection string respectively
+}
+
+// Models passwords and tokens
+type AuthConfig struct {
+	BcryptCost int
+
+	JWTAlgorithm string // AlgHS256, AlgRS256, AlgES256 or AlgEdDSA
+	JWTSecret    string // signing secret for HS256
+	JWTKeyFile   string // PEM private key for the asymmetric algorithms
+	// Keys that no longer sign tokens but are still accepted, so rotating keys doesn't log everyone out
+	// Files may hold private or public PEM keys, the algorithm is inferred from the key type
+	PreviousKeyFiles []string
+	PreviousSecrets  []string
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// Models the admin account created on startup, for debugging
+type AdminConfig struct {
+	Seed     bool
+	Username string
+	Password string
+}
+
+// Models the background job removing soft deleted content
+type PurgeConfig struct {
+	// How long deleted posts and comments can still be restored before they are removed for good
+	Retention time.Duration
+	// How often the job runs, 0 disables it
+	Interval time.Duration
+}
+
+// Models the real-time event stream
+type StreamConfig struct {
+	// How often idle streams get a comment, so proxies don't close them
+	Heartbeat time.Duration
+	// How many recent events are kept for clients resuming with Last-Event-ID
+	ReplayBuffer int
+}
+
+// Models outgoing email and the jobs sending it
+type MailConfig struct {
+	Driver string // MailLog, MailFile or MailSMTP
+	// Sender of every email, e.g. "Forum <noreply@example.com>"
+	From string
+	// Directory the file driver writes emails to
+	Dir          string
+	SMTPHost     string
+	SMTPPort     int // 465 for implicit TLS, otherwise STARTTLS is used when offered
+	SMTPUsername string
+	SMTPPassword string
+	// Where the frontend is served, for the links in emails
+	BaseURL string
+	// How often queued emails are sent, 0 disables sending (emails stay queued)
+	OutboxInterval time.Duration
+	// How many times an email is tried before giving up, and the wait after the first failure (doubling after each)
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	// How long the link verifying a new address stays valid
+	VerificationTTL time.Duration
+	// How often the digest job looks for digests that are due, 0 disables digests
+	DigestInterval time.Duration
+}
+
+// Get the configuration used when nothing is overridden
+func Defaults() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:           ":8080",
+			AllowedOrigins: []string{"http://localhost:3000"},
+		},
+		Database: DatabaseConfig{
+			Driver: "sqlite",
+			DSN:    "./database.db",
+		},
+		Auth: AuthConfig{
+			BcryptCost:      bcrypt.DefaultCost,
+			JWTAlgorithm:    AlgHS256,
+			JWTSecret:       DevJWTSecret,
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+		},
+		Admin: AdminConfig{
+			Seed:     true,
+			Username: "admin123",
+			Password: "admin123",
+		},
+		Purge: PurgeConfig{
+			Retention: 30 * 24 * time.Hour,
+			Interval:  time.Hour,
+		},
+		Stream: StreamConfig{
+			Heartbeat:    15 * time.Second,
+			ReplayBuffer: 1000,
+		},
+		Mail: MailConfig{
+			Driver:          MailLog,
+			From:            "Forum <noreply@localhost>",
+			Dir:             "./mail",
+			SMTPPort:        587,
+			BaseURL:         "http://localhost:3000",
+			OutboxInterval:  15 * time.Second,
+			MaxAttempts:     5,
+			RetryBackoff:    time.Minute,
+			VerificationTTL: 48 * time.Hour,
+			DigestInterval:  time.Hour,
+		},
+	}
+}
+
+// Check every value, reporting all problems at once
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		fail("server.addr must not be empty")
+	}
+	if len(c.Server.AllowedOrigins) == 0 {
+		fail("server.allowed_origins must list at least one origin")
+	}
+	for _, origin := range c.Server.AllowedOrigins {
+		// CORS allows credentials, so a wildcard would let any site make requests with the user's cookies
+		if origin == "*" {
+			fail("server.allowed_origins must list the frontend's origins, \"*\" would let any site use the user's cookies")
+		} else if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("server.allowed_origins: %q is not an origin such as http://localhost:3000", origin)
+		}
+	}
+
+	if c.Database.Driver != "sqlite" && c.Database.Driver != "postgres" {
+		fail("database.driver must be sqlite or postgres, got %q", c.Database.Driver)
+	}
+	if c.Database.DSN == "" {
+		fail("database.dsn must not be empty")
+	}
+
+	if c.Auth.BcryptCost < bcrypt.MinCost || c.Auth.BcryptCost > bcrypt.MaxCost {
+		fail("auth.bcrypt_cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	switch c.Auth.JWTAlgorithm {
+	case AlgHS256:
+		if c.Auth.JWTSecret == "" {
+			fail("auth.jwt_secret is required for HS256")
+		}
+	case AlgRS256, AlgES256, AlgEdDSA:
+		if c.Auth.JWTKeyFile == "" {
+			fail("auth.jwt_key_file is required for %s", c.Auth.JWTAlgorithm)
+		}
+	default:
+		fail("auth.jwt_algorithm must be one of HS256, RS256, ES256 or EdDSA, got %q", c.Auth.JWTAlgorithm)
+	}
+	if c.Auth.AccessTokenTTL <= 0 {
+		fail("auth.access_token_ttl must be positive")
+	}
+	if c.Auth.RefreshTokenTTL <= c.Auth.AccessTokenTTL {
+		fail("auth.refresh_token_ttl must be longer than auth.access_token_ttl")
+	}
+
+	if c.Admin.Seed && (c.Admin.Username == "" || c.Admin.Password == "") {
+		fail("admin.username and admin.password are required when admin.seed is enabled")
+	}
+
+	if c.Purge.Retention < 0 {
+		fail("purge.retention must not be negative")
+	}
+	if c.Purge.Interval < 0 {
+		fail("purge.interval must not be negative")
+	}
+
+	if c.Stream.Heartbeat <= 0 {
+		fail("stream.heartbeat must be positive")
+	}
+	if c.Stream.ReplayBuffer < 0 {
+		fail("stream.replay_buffer must not be negative")
+	}
+
+	switch c.Mail.Driver {
+	case MailLog:
+	case MailFile:
+		if c.Mail.Dir == "" {
+			fail("mail.dir is required for the file driver")
+		}
+	case MailSMTP:
+		if c.Mail.SMTPHost == "" {
+			fail("mail.smtp_host is required for the smtp driver")
+		}
+		if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+			fail("mail.smtp_port must be between 1 and 65535")
+		}
+	default:
+		fail("mail.driver must be log, file or smtp, got %q", c.Mail.Driver)
+	}
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		fail("mail.from: %q is not an address such as Forum <noreply@example.com>", c.Mail.From)
+	}
+	if u, err := url.Parse(c.Mail.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+		fail("mail.base_url: %q is not a URL such as https://forum.example.com", c.Mail.BaseURL)
+	}
+	if c.Mail.OutboxInterval < 0 {
+		fail("mail.outbox_interval must not be negative")
+	}
+	if c.Mail.MaxAttempts < 1 {
+		fail("mail.max_attempts must be at least 1")
+	}
+	if c.Mail.RetryBackoff <= 0 {
+		fail("mail.retry_backoff must be positive")
+	}
+	if c.Mail.VerificationTTL <= 0 {
+		fail("mail.verification_ttl must be positive")
+	}
+	if c.Mail.DigestInterval < 0 {
+		fail("mail.digest_interval must not be negative")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Describe settings that are valid but unsafe outside of local development
+func (c *Config) Warnings() []string {
+	var warnings []string
+	if c.Auth.JWTAlgorithm == AlgHS256 && c.Auth.JWTSecret == DevJWTSecret {
+		warnings = append(warnings, "auth.jwt_secret is the development placeholder, set FORUM_JWT_SECRET in production")
+	}
+	if c.Admin.Seed && c.Admin.Password == Defaults().Admin.Password {
+		warnings = append(warnings, "admin.seed is enabled with the default password, disable it in production")
+	}
+	if c.Mail.Driver != MailSMTP {
+		warnings = append(warnings, fmt.Sprintf("mail.driver is %s, emails are not delivered to anyone", c.Mail.Driver))
+	}
+	return warnings
+}
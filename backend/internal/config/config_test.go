@@ -0,0 +1,34 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		problem string // part of the error wanted, empty if the origins are valid
+	}{
+		{"frontend", []string{"http://localhost:3000", "https://forum.example.com"}, ""},
+		{"none", nil, "at least one origin"},
+		{"wildcard", []string{"*"}, `"*"`},
+		{"wildcard among others", []string{"https://forum.example.com", "*"}, `"*"`},
+		{"no scheme", []string{"forum.example.com"}, "is not an origin"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := Defaults()
+			c.Auth.JWTSecret = "secret"
+			c.Server.AllowedOrigins = test.origins
+			err := c.Validate()
+			switch {
+			case test.problem == "" && err != nil:
+				t.Errorf("got error %v, want none", err)
+			case test.problem != "" && (err == nil || !strings.Contains(err.Error(), test.problem)):
+				t.Errorf("got error %v, want one about %s", err, test.problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Where a setting's effective value came from
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Models one configurable value, reachable through every configuration source
+type setting struct {
+	key    string // dotted key used in config files, e.g. "database.dsn"
+	env    string
+	flag   string
+	usage  string
+	secret bool // redacted when the configuration is printed
+	get    func() string
+	set    func(string) error
+	source string
+}
+
+// Every setting, bound to the fields of c
+func (c *Config) settings() []*setting {
+	return []*setting{
+		stringSetting("server.addr", "FORUM_ADDR", "addr", "address to listen on", &c.Server.Addr),
+		listSetting("server.allowed_origins", "FORUM_ALLOWED_ORIGINS", "allowed-origins", "comma-separated origins allowed by CORS", &c.Server.AllowedOrigins),
+
+		stringSetting("database.driver", "FORUM_DB_DRIVER", "db-driver", "database driver, sqlite or postgres", &c.Database.Driver),
+		secretSetting(stringSetting("database.dsn", "FORUM_DB_DSN", "db-dsn", "SQLite file path or PostgreSQL connection string", &c.Database.DSN)),
+
+		intSetting("auth.bcrypt_cost", "FORUM_BCRYPT_COST", "bcrypt-cost", "bcrypt cost for password hashes", &c.Auth.BcryptCost),
+		stringSetting("auth.jwt_algorithm", "FORUM_JWT_ALG", "jwt-alg", "JWT signing algorithm: HS256, RS256, ES256 or EdDSA", &c.Auth.JWTAlgorithm),
+		secretSetting(stringSetting("auth.jwt_secret", "FORUM_JWT_SECRET", "", "JWT signing secret for HS256", &c.Auth.JWTSecret)),
+		stringSetting("auth.jwt_key_file", "FORUM_JWT_KEY_FILE", "jwt-key-file", "PEM private key for RS256, ES256 or EdDSA", &c.Auth.JWTKeyFile),
+		listSetting("auth.previous_key_files", "FORUM_JWT_PREVIOUS_KEY_FILES", "jwt-previous-key-files", "comma-separated PEM keys still accepted for verification", &c.Auth.PreviousKeyFiles),
+		secretSetting(listSetting("auth.previous_secrets", "FORUM_JWT_PREVIOUS_SECRETS", "", "comma-separated HS256 secrets still accepted for verification", &c.Auth.PreviousSecrets)),
+		durationSetting("auth.access_token_ttl", "FORUM_ACCESS_TOKEN_TTL", "access-token-ttl", "lifetime of access tokens", &c.Auth.AccessTokenTTL),
+		durationSetting("auth.refresh_token_ttl", "FORUM_REFRESH_TOKEN_TTL", "refresh-token-ttl", "lifetime of idle sessions", &c.Auth.RefreshTokenTTL),
+
+		boolSetting("admin.seed", "FORUM_ADMIN_SEED", "admin-seed", "create the debug admin account on startup", &c.Admin.Seed),
+		stringSetting("admin.username", "FORUM_ADMIN_USERNAME", "", "username of the debug admin account", &c.Admin.Username),
+		secretSetting(stringSetting("admin.password", "FORUM_ADMIN_PASSWORD", "", "password of the debug admin account", &c.Admin.Password)),
+
+		durationSetting("purge.retention", "FORUM_PURGE_RETENTION", "purge-retention", "how long deleted posts and comments can be restored", &c.Purge.Retention),
+		durationSetting("purge.interval", "FORUM_PURGE_INTERVAL", "purge-interval", "how often deleted content is purged, 0 to disable", &c.Purge.Interval),
+
+		durationSetting("stream.heartbeat", "FORUM_STREAM_HEARTBEAT", "stream-heartbeat", "how often idle event streams are kept alive", &c.Stream.Heartbeat),
+		intSetting("stream.replay_buffer", "FORUM_STREAM_REPLAY_BUFFER", "stream-replay-buffer", "recent events kept for resuming event streams", &c.Stream.ReplayBuffer),
+
+		stringSetting("mail.driver", "FORUM_MAIL_DRIVER", "mail-driver", "how emails are delivered: log, file or smtp", &c.Mail.Driver),
+		stringSetting("mail.from", "FORUM_MAIL_FROM", "mail-from", "sender address of every email", &c.Mail.From),
+		stringSetting("mail.dir", "FORUM_MAIL_DIR", "mail-dir", "directory the file driver writes emails to", &c.Mail.Dir),
+		stringSetting("mail.smtp_host", "FORUM_SMTP_HOST", "smtp-host", "SMTP server host", &c.Mail.SMTPHost),
+		intSetting("mail.smtp_port", "FORUM_SMTP_PORT", "smtp-port", "SMTP server port, 465 for implicit TLS", &c.Mail.SMTPPort),
+		stringSetting("mail.smtp_username", "FORUM_SMTP_USERNAME", "smtp-username", "SMTP username, empty to send without authenticating", &c.Mail.SMTPUsername),
+		secretSetting(stringSetting("mail.smtp_password", "FORUM_SMTP_PASSWORD", "", "SMTP password", &c.Mail.SMTPPassword)),
+		stringSetting("mail.base_url", "FORUM_MAIL_BASE_URL", "mail-base-url", "URL of the frontend, for links in emails", &c.Mail.BaseURL),
+		durationSetting("mail.outbox_interval", "FORUM_MAIL_OUTBOX_INTERVAL", "mail-outbox-interval", "how often queued emails are sent, 0 to disable", &c.Mail.OutboxInterval),
+		intSetting("mail.max_attempts", "FORUM_MAIL_MAX_ATTEMPTS", "mail-max-attempts", "times an email is tried before giving up", &c.Mail.MaxAttempts),
+		durationSetting("mail.retry_backoff", "FORUM_MAIL_RETRY_BACKOFF", "mail-retry-backoff", "wait before retrying a failed email, doubling each time", &c.Mail.RetryBackoff),
+		durationSetting("mail.verification_ttl", "FORUM_MAIL_VERIFICATION_TTL", "mail-verification-ttl", "how long address verification links stay valid", &c.Mail.VerificationTTL),
+		durationSetting("mail.digest_interval", "FORUM_MAIL_DIGEST_INTERVAL", "mail-digest-interval", "how often due digests are sent, 0 to disable", &c.Mail.DigestInterval),
+	}
+}
+
+func stringSetting(key, env, flag, usage string, p *string) *setting {
+	return &setting{key: key, env: env, flag: flag, usage: usage,
+		get: func() string { return *p },
+		set: func(v string) error { *p = v; return nil },
+	}
+}
+
+func listSetting(key, env, flag, usage string, p *[]string) *setting {
+	return &setting{key: key, env: env, flag: flag, usage: usage,
+		get: func() string { return strings.Join(*p, ",") },
+		set: func(v string) error { *p = splitList(v); return nil },
+	}
+}
+
+func intSetting(key, env, flag, usage string, p *int) *setting {
+	return &setting{key: key, env: env, flag: flag, usage: usage,
+		get: func() string { return strconv.Itoa(*p) },
+		set: func(v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%q is not an integer", v)
+			}
+			*p = n
+			return nil
+		},
+	}
+}
+
+func boolSetting(key, env, flag, usage string, p *bool) *setting {
+	return &setting{key: key, env: env, flag: flag, usage: usage,
+		get: func() string { return strconv.FormatBool(*p) },
+		set: func(v string) error {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%q is not a boolean", v)
+			}
+			*p = b
+			return nil
+		},
+	}
+}
+
+func durationSetting(key, env, flag, usage string, p *time.Duration) *setting {
+	return &setting{key: key, env: env, flag: flag, usage: usage,
+		get: func() string { return p.String() },
+		set: func(v string) error {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%q is not a duration such as 15m or 720h", v)
+			}
+			*p = d
+			return nil
+		},
+	}
+}
+
+// Mark a setting as secret, so it is redacted when printed
+// Secrets that are never harmless (i.e. not the DSN) get no flag, since command lines
+// are visible to every user of the machine
+func secretSetting(s *setting) *setting {
+	s.secret = true
+	return s
+}
+
+// Split a comma-separated list, dropping empty entries
+func splitList(v string) []string {
+	values := []string{}
+	for _, value := range strings.Split(v, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Models the fully resolved configuration together with where each value came from
+type Loaded struct {
+	Config
+	// Path of the config file that was read, empty if none
+	File string
+	// Arguments left over after the flags, e.g. a subcommand
+	Args     []string
+	settings []*setting
+}
+
+// Resolve the configuration from defaults, the config file, the environment and the
+// command-line flags in args (without the program name), then validate it
+// The config file is named by the -config flag or FORUM_CONFIG
+func Load(args []string) (*Loaded, error) {
+	loaded := &Loaded{Config: Defaults()}
+	loaded.settings = loaded.Config.settings()
+	for _, s := range loaded.settings {
+		s.source = SourceDefault
+	}
+
+	// Parse the flags first to find the config file, but apply them last
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", os.Getenv("FORUM_CONFIG"), "path to a YAML or TOML config file")
+	type flagValue struct {
+		s     *setting
+		value string
+	}
+	var flagValues []flagValue
+	for _, s := range loaded.settings {
+		if s.flag == "" {
+			continue
+		}
+		s := s
+		fs.Func(s.flag, s.usage, func(v string) error {
+			flagValues = append(flagValues, flagValue{s, v})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, usage(fs))
+	}
+	loaded.Args = fs.Args()
+
+	if *configFile != "" {
+		if err := loaded.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+		loaded.File = *configFile
+	}
+
+	for _, s := range loaded.settings {
+		if v, ok := os.LookupEnv(s.env); ok && v != "" {
+			if err := s.set(v); err != nil {
+				return nil, fmt.Errorf("%s: %w", s.env, err)
+			}
+			s.source = SourceEnv
+		}
+	}
+
+	for _, fv := range flagValues {
+		if err := fv.s.set(fv.value); err != nil {
+			return nil, fmt.Errorf("-%s: %w", fv.s.flag, err)
+		}
+		fv.s.source = SourceFlag
+	}
+
+	if err := loaded.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return loaded, nil
+}
+
+// Apply a YAML (.yaml / .yml) or TOML (.toml) config file
+func (l *Loaded) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	flatten("", raw, values)
+
+	byKey := map[string]*setting{}
+	for _, s := range l.settings {
+		byKey[s.key] = s
+	}
+	for key, value := range values {
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if err := s.set(value); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+		s.source = SourceFile
+	}
+	return nil
+}
+
+// Flatten nested tables into dotted keys, with lists joined by commas
+func flatten(prefix string, raw map[string]any, values map[string]string) {
+	for key, value := range raw {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch value := value.(type) {
+		case map[string]any:
+			flatten(key, value, values)
+		case []any:
+			items := make([]string, len(value))
+			for i, item := range value {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+}
+
+// Describe the available flags, for error messages
+func usage(fs *flag.FlagSet) string {
+	var b strings.Builder
+	b.WriteString("usage: server [flags] [command]\n\nflags:\n")
+	fs.VisitAll(func(f *flag.Flag) {
+		fmt.Fprintf(&b, "  -%-24s %s\n", f.Name, f.Usage)
+	})
+	return b.String()
+}
+
+// Write every effective setting with its source, secrets redacted
+func (l *Loaded) Dump(w io.Writer) {
+	if l.File != "" {
+		fmt.Fprintf(w, "config file: %s\n", l.File)
+	}
+	for _, s := range l.settings {
+		value := s.get()
+		if s.secret && value != "" {
+			value = "[redacted]"
+		}
+		fmt.Fprintf(w, "%-26s = %-40s (%s)\n", s.key, value, s.source)
+	}
+}
@@ -0,0 +1,210 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Models the applied state of a migration, as reported by MigrationStatus
+type MigrationState struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Create the table tracking which migrations have been applied
+func ensureMigrationsTable(conn *sql.DB, dialect Dialect) error {
+	timestampType := "DATETIME"
+	if dialect == Postgres {
+		timestampType = "TIMESTAMPTZ"
+	}
+
+	_, err := conn.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at ` + timestampType + ` NOT NULL
+		);
+	`)
+	return err
+}
+
+// Get the migrations sorted by version, making sure no version is declared twice
+func sortedMigrations() ([]Migration, error) {
+	migrations := make([]Migration, len(Migrations))
+	copy(migrations, Migrations)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, m := range migrations {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q has invalid version %d", m.Name, m.Version)
+		}
+		if i > 0 && migrations[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+	}
+	return migrations, nil
+}
+
+// Get the versions that have already been applied, mapped to when they were applied
+func appliedVersions(conn *sql.DB) (map[int]time.Time, error) {
+	rows, err := conn.Query(`SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// Get the highest applied migration version (0 if the schema is empty)
+func CurrentVersion(conn *sql.DB, dialect Dialect) (int, error) {
+	if err := ensureMigrationsTable(conn, dialect); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := conn.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// Report every known migration and whether it has been applied
+func MigrationStatus(conn *sql.DB, dialect Dialect) ([]MigrationState, error) {
+	if err := ensureMigrationsTable(conn, dialect); err != nil {
+		return nil, err
+	}
+	migrations, err := sortedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		states = append(states, MigrationState{Migration: m, Applied: ok, AppliedAt: appliedAt})
+	}
+	return states, nil
+}
+
+// Get the latest version known to this binary
+func LatestVersion() int {
+	latest := 0
+	for _, m := range Migrations {
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest
+}
+
+// Apply every pending migration
+func MigrateUp(conn *sql.DB, dialect Dialect) error {
+	return MigrateTo(conn, dialect, LatestVersion())
+}
+
+// Revert the given number of most recently applied migrations
+func MigrateDown(conn *sql.DB, dialect Dialect, steps int) error {
+	states, err := MigrationStatus(conn, dialect)
+	if err != nil {
+		return err
+	}
+
+	// Walk back from the newest applied migration to find the target version
+	target := 0
+	for i := len(states) - 1; i >= 0; i-- {
+		if !states[i].Applied {
+			continue
+		}
+		if steps == 0 {
+			target = states[i].Version
+			break
+		}
+		steps--
+	}
+	return MigrateTo(conn, dialect, target)
+}
+
+// Move the schema to exactly the given version, applying or reverting migrations as needed
+// Each migration runs in its own transaction together with its schema_migrations bookkeeping
+func MigrateTo(conn *sql.DB, dialect Dialect, target int) error {
+	if target < 0 || target > LatestVersion() {
+		return fmt.Errorf("unknown migration version %d (latest is %d)", target, LatestVersion())
+	}
+
+	states, err := MigrationStatus(conn, dialect)
+	if err != nil {
+		return err
+	}
+
+	// Apply pending migrations up to the target, oldest first
+	for _, state := range states {
+		if state.Applied || state.Version > target {
+			continue
+		}
+		if err := applyMigration(conn, dialect, state.Migration, true); err != nil {
+			return err
+		}
+	}
+
+	// Revert applied migrations above the target, newest first
+	for i := len(states) - 1; i >= 0; i-- {
+		state := states[i]
+		if !state.Applied || state.Version <= target {
+			continue
+		}
+		if err := applyMigration(conn, dialect, state.Migration, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run a single migration in either direction inside a transaction
+func applyMigration(conn *sql.DB, dialect Dialect, m Migration, up bool) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	// Defer a rollback in case anything goes wrong
+	defer tx.Rollback()
+
+	if up {
+		if _, err := tx.Exec(m.Up.For(dialect)); err != nil {
+			return fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+		}
+		if m.UpFunc != nil {
+			if err := m.UpFunc(tx, dialect); err != nil {
+				return fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+			}
+		}
+		if _, err := tx.Exec(dialect.Rebind(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+			m.Version, m.Name, time.Now().UTC()); err != nil {
+			return fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+		}
+	} else {
+		if _, err := tx.Exec(m.Down.For(dialect)); err != nil {
+			return fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+		}
+		if _, err := tx.Exec(dialect.Rebind(`DELETE FROM schema_migrations WHERE version = ?`), m.Version); err != nil {
+			return fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+		}
+	}
+
+	return tx.Commit()
+}
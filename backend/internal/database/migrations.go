@@ -0,0 +1,1235 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/ranking"
+	"sample-go-app/internal/slug"
+)
+
+// Models a single numbered schema change
+// Up moves the schema forward to Version, Down reverts it to the previous version
+type Migration struct {
+	Version int
+	Name    string
+	Up      Statements
+	Down    Statements
+	// Optional data migration run after Up, in the same transaction
+	// Used for backfills that are easier to express in Go than in SQL
+	UpFunc func(tx *sql.Tx, dialect Dialect) error
+}
+
+// SQL for one direction of a migration, per dialect
+// Postgres may be left empty when the SQLite statements are portable
+type Statements struct {
+	SQLite   string
+	Postgres string
+}
+
+// Get the statements to run for the given dialect
+func (s Statements) For(d Dialect) string {
+	if d == Postgres && s.Postgres != "" {
+		return s.Postgres
+	}
+	return s.SQLite
+}
+
+// All schema migrations, in the order they must be applied
+// Never edit a migration that has been released; add a new one instead
+var Migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial_schema",
+		// IF NOT EXISTS lets databases created before migrations existed adopt this version
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE IF NOT EXISTS users (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				username TEXT NOT NULL UNIQUE,
+				password TEXT NOT NULL,
+				isAdmin INTEGER DEFAULT 0
+			);
+			CREATE TABLE IF NOT EXISTS posts (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				title TEXT,
+				content TEXT,
+				topic TEXT,
+				user_id INTEGER,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE TABLE IF NOT EXISTS comments (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				post_id INTEGER NOT NULL,
+				parent_id INTEGER,
+				user_id INTEGER NOT NULL,
+				content TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY(post_id) REFERENCES posts(id),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE TABLE IF NOT EXISTS topics (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				topic STRING NOT NULL UNIQUE
+			);
+		`,
+			Postgres: `
+			CREATE TABLE IF NOT EXISTS users (
+				id SERIAL PRIMARY KEY,
+				username TEXT NOT NULL UNIQUE,
+				password TEXT NOT NULL,
+				isAdmin INTEGER DEFAULT 0
+			);
+			CREATE TABLE IF NOT EXISTS posts (
+				id SERIAL PRIMARY KEY,
+				title TEXT,
+				content TEXT,
+				topic TEXT,
+				user_id INTEGER REFERENCES users(id),
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE IF NOT EXISTS comments (
+				id SERIAL PRIMARY KEY,
+				post_id INTEGER NOT NULL REFERENCES posts(id),
+				parent_id INTEGER,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				content TEXT NOT NULL,
+				created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+			);
+			CREATE TABLE IF NOT EXISTS topics (
+				id SERIAL PRIMARY KEY,
+				topic TEXT NOT NULL UNIQUE
+			);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP TABLE IF EXISTS comments;
+			DROP TABLE IF EXISTS posts;
+			DROP TABLE IF EXISTS topics;
+			DROP TABLE IF EXISTS users;
+		`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "full_text_search",
+		// SQLite keeps external-content FTS5 tables in sync with triggers,
+		// Postgres uses generated tsvector columns with GIN indexes instead
+		Up: Statements{
+			SQLite: `
+			CREATE VIRTUAL TABLE posts_fts USING fts5(
+				title, content,
+				content = 'posts', content_rowid = 'id',
+				tokenize = 'porter unicode61'
+			);
+			CREATE TRIGGER posts_fts_insert AFTER INSERT ON posts BEGIN
+				INSERT INTO posts_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+			END;
+			CREATE TRIGGER posts_fts_delete AFTER DELETE ON posts BEGIN
+				INSERT INTO posts_fts (posts_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+			END;
+			CREATE TRIGGER posts_fts_update AFTER UPDATE OF title, content ON posts BEGIN
+				INSERT INTO posts_fts (posts_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+				INSERT INTO posts_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+			END;
+			INSERT INTO posts_fts (posts_fts) VALUES ('rebuild');
+
+			CREATE VIRTUAL TABLE comments_fts USING fts5(
+				content,
+				content = 'comments', content_rowid = 'id',
+				tokenize = 'porter unicode61'
+			);
+			CREATE TRIGGER comments_fts_insert AFTER INSERT ON comments BEGIN
+				INSERT INTO comments_fts (rowid, content) VALUES (new.id, new.content);
+			END;
+			CREATE TRIGGER comments_fts_delete AFTER DELETE ON comments BEGIN
+				INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+			END;
+			CREATE TRIGGER comments_fts_update AFTER UPDATE OF content ON comments BEGIN
+				INSERT INTO comments_fts (comments_fts, rowid, content) VALUES ('delete', old.id, old.content);
+				INSERT INTO comments_fts (rowid, content) VALUES (new.id, new.content);
+			END;
+			INSERT INTO comments_fts (comments_fts) VALUES ('rebuild');
+		`,
+			Postgres: `
+			ALTER TABLE posts ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+				setweight(to_tsvector('english', COALESCE(title, '')), 'A') ||
+				setweight(to_tsvector('english', COALESCE(content, '')), 'B')
+			) STORED;
+			CREATE INDEX posts_search_idx ON posts USING GIN (search_vector);
+
+			ALTER TABLE comments ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+				to_tsvector('english', content)
+			) STORED;
+			CREATE INDEX comments_search_idx ON comments USING GIN (search_vector);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP TRIGGER IF EXISTS comments_fts_update;
+			DROP TRIGGER IF EXISTS comments_fts_delete;
+			DROP TRIGGER IF EXISTS comments_fts_insert;
+			DROP TABLE IF EXISTS comments_fts;
+			DROP TRIGGER IF EXISTS posts_fts_update;
+			DROP TRIGGER IF EXISTS posts_fts_delete;
+			DROP TRIGGER IF EXISTS posts_fts_insert;
+			DROP TABLE IF EXISTS posts_fts;
+		`,
+			Postgres: `
+			DROP INDEX IF EXISTS comments_search_idx;
+			ALTER TABLE comments DROP COLUMN IF EXISTS search_vector;
+			DROP INDEX IF EXISTS posts_search_idx;
+			ALTER TABLE posts DROP COLUMN IF EXISTS search_vector;
+		`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "post_activity_columns",
+		// Denormalised sort keys for paginated post lists, kept up to date by the store
+		Up: Statements{
+			SQLite: `
+			ALTER TABLE posts ADD COLUMN comment_count INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE posts ADD COLUMN last_activity_at DATETIME;
+			UPDATE posts SET
+				comment_count = (SELECT COUNT(*) FROM comments c WHERE c.post_id = posts.id),
+				last_activity_at = COALESCE((SELECT MAX(c.created_at) FROM comments c WHERE c.post_id = posts.id), created_at);
+			CREATE INDEX posts_created_at_idx ON posts (created_at, id);
+			CREATE INDEX posts_topic_created_at_idx ON posts (topic, created_at, id);
+			CREATE INDEX posts_comment_count_idx ON posts (comment_count, id);
+			CREATE INDEX posts_last_activity_at_idx ON posts (last_activity_at, id);
+			CREATE INDEX comments_post_id_idx ON comments (post_id, created_at, id);
+			CREATE INDEX comments_parent_id_idx ON comments (parent_id, created_at, id);
+		`,
+			Postgres: `
+			ALTER TABLE posts ADD COLUMN comment_count INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE posts ADD COLUMN last_activity_at TIMESTAMPTZ;
+			UPDATE posts SET
+				comment_count = (SELECT COUNT(*) FROM comments c WHERE c.post_id = posts.id),
+				last_activity_at = COALESCE((SELECT MAX(c.created_at) FROM comments c WHERE c.post_id = posts.id), created_at);
+			CREATE INDEX posts_created_at_idx ON posts (created_at, id);
+			CREATE INDEX posts_topic_created_at_idx ON posts (topic, created_at, id);
+			CREATE INDEX posts_comment_count_idx ON posts (comment_count, id);
+			CREATE INDEX posts_last_activity_at_idx ON posts (last_activity_at, id);
+			CREATE INDEX comments_post_id_idx ON comments (post_id, created_at, id);
+			CREATE INDEX comments_parent_id_idx ON comments (parent_id, created_at, id);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP INDEX IF EXISTS comments_parent_id_idx;
+			DROP INDEX IF EXISTS comments_post_id_idx;
+			DROP INDEX IF EXISTS posts_last_activity_at_idx;
+			DROP INDEX IF EXISTS posts_comment_count_idx;
+			DROP INDEX IF EXISTS posts_topic_created_at_idx;
+			DROP INDEX IF EXISTS posts_created_at_idx;
+			ALTER TABLE posts DROP COLUMN last_activity_at;
+			ALTER TABLE posts DROP COLUMN comment_count;
+		`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "votes",
+		// One vote per user per post / comment, with the totals cached on the voted rows
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE post_votes (
+				post_id INTEGER NOT NULL,
+				user_id INTEGER NOT NULL,
+				value INTEGER NOT NULL CHECK (value IN (-1, 1)),
+				created_at DATETIME NOT NULL,
+				PRIMARY KEY (post_id, user_id),
+				FOREIGN KEY(post_id) REFERENCES posts(id),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE TABLE comment_votes (
+				comment_id INTEGER NOT NULL,
+				user_id INTEGER NOT NULL,
+				value INTEGER NOT NULL CHECK (value IN (-1, 1)),
+				created_at DATETIME NOT NULL,
+				PRIMARY KEY (comment_id, user_id),
+				FOREIGN KEY(comment_id) REFERENCES comments(id),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			ALTER TABLE posts ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE posts ADD COLUMN hot_rank REAL NOT NULL DEFAULT 0;
+			ALTER TABLE comments ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
+			CREATE INDEX posts_score_idx ON posts (score, id);
+			CREATE INDEX posts_hot_rank_idx ON posts (hot_rank, id);
+		`,
+			Postgres: `
+			CREATE TABLE post_votes (
+				post_id INTEGER NOT NULL REFERENCES posts(id),
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				value INTEGER NOT NULL CHECK (value IN (-1, 1)),
+				created_at TIMESTAMPTZ NOT NULL,
+				PRIMARY KEY (post_id, user_id)
+			);
+			CREATE TABLE comment_votes (
+				comment_id INTEGER NOT NULL REFERENCES comments(id),
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				value INTEGER NOT NULL CHECK (value IN (-1, 1)),
+				created_at TIMESTAMPTZ NOT NULL,
+				PRIMARY KEY (comment_id, user_id)
+			);
+			ALTER TABLE posts ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE posts ADD COLUMN hot_rank DOUBLE PRECISION NOT NULL DEFAULT 0;
+			ALTER TABLE comments ADD COLUMN score INTEGER NOT NULL DEFAULT 0;
+			CREATE INDEX posts_score_idx ON posts (score, id);
+			CREATE INDEX posts_hot_rank_idx ON posts (hot_rank, id);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP INDEX IF EXISTS posts_hot_rank_idx;
+			DROP INDEX IF EXISTS posts_score_idx;
+			ALTER TABLE comments DROP COLUMN score;
+			ALTER TABLE posts DROP COLUMN hot_rank;
+			ALTER TABLE posts DROP COLUMN score;
+			DROP TABLE IF EXISTS comment_votes;
+			DROP TABLE IF EXISTS post_votes;
+		`,
+		},
+		UpFunc: backfillHotRanks,
+	},
+	{
+		Version: 5,
+		Name:    "sessions",
+		// Server-side sessions holding rotating refresh tokens, and a denylist of revoked access tokens
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE sessions (
+				id TEXT PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				refresh_token_hash TEXT NOT NULL UNIQUE,
+				previous_token_hash TEXT,
+				access_token_id TEXT NOT NULL,
+				access_expires_at DATETIME NOT NULL,
+				user_agent TEXT NOT NULL DEFAULT '',
+				ip TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				last_used_at DATETIME NOT NULL,
+				expires_at DATETIME NOT NULL,
+				revoked_at DATETIME,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+			CREATE INDEX sessions_previous_token_hash_idx ON sessions (previous_token_hash);
+			CREATE TABLE revoked_tokens (
+				token_id TEXT PRIMARY KEY,
+				expires_at DATETIME NOT NULL
+			);
+		`,
+			Postgres: `
+			CREATE TABLE sessions (
+				id TEXT PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				refresh_token_hash TEXT NOT NULL UNIQUE,
+				previous_token_hash TEXT,
+				access_token_id TEXT NOT NULL,
+				access_expires_at TIMESTAMPTZ NOT NULL,
+				user_agent TEXT NOT NULL DEFAULT '',
+				ip TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL,
+				last_used_at TIMESTAMPTZ NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL,
+				revoked_at TIMESTAMPTZ
+			);
+			CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+			CREATE INDEX sessions_previous_token_hash_idx ON sessions (previous_token_hash);
+			CREATE TABLE revoked_tokens (
+				token_id TEXT PRIMARY KEY,
+				expires_at TIMESTAMPTZ NOT NULL
+			);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP TABLE IF EXISTS revoked_tokens;
+			DROP TABLE IF EXISTS sessions;
+		`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "soft_delete",
+		// Deleted posts and comments are kept as tombstones until the purge job removes them
+		Up: Statements{
+			SQLite: `
+			ALTER TABLE posts ADD COLUMN deleted_at DATETIME;
+			ALTER TABLE posts ADD COLUMN deleted_by INTEGER;
+			ALTER TABLE comments ADD COLUMN deleted_at DATETIME;
+			ALTER TABLE comments ADD COLUMN deleted_by INTEGER;
+			CREATE INDEX posts_deleted_at_idx ON posts (deleted_at);
+			CREATE INDEX comments_deleted_at_idx ON comments (deleted_at);
+		`,
+			Postgres: `
+			ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMPTZ;
+			ALTER TABLE posts ADD COLUMN deleted_by INTEGER REFERENCES users(id);
+			ALTER TABLE comments ADD COLUMN deleted_at TIMESTAMPTZ;
+			ALTER TABLE comments ADD COLUMN deleted_by INTEGER REFERENCES users(id);
+			CREATE INDEX posts_deleted_at_idx ON posts (deleted_at);
+			CREATE INDEX comments_deleted_at_idx ON comments (deleted_at);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP INDEX IF EXISTS comments_deleted_at_idx;
+			DROP INDEX IF EXISTS posts_deleted_at_idx;
+			ALTER TABLE comments DROP COLUMN deleted_by;
+			ALTER TABLE comments DROP COLUMN deleted_at;
+			ALTER TABLE posts DROP COLUMN deleted_by;
+			ALTER TABLE posts DROP COLUMN deleted_at;
+		`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "revisions",
+		// Every version replaced by an edit, with who wrote it and when
+		// edited_by is the author of the current version, when it isn't the original author
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE post_revisions (
+				post_id INTEGER NOT NULL,
+				revision INTEGER NOT NULL,
+				title TEXT,
+				topic TEXT,
+				content TEXT,
+				user_id INTEGER,
+				created_at DATETIME NOT NULL,
+				PRIMARY KEY (post_id, revision),
+				FOREIGN KEY(post_id) REFERENCES posts(id),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE TABLE comment_revisions (
+				comment_id INTEGER NOT NULL,
+				revision INTEGER NOT NULL,
+				content TEXT NOT NULL,
+				user_id INTEGER,
+				created_at DATETIME NOT NULL,
+				PRIMARY KEY (comment_id, revision),
+				FOREIGN KEY(comment_id) REFERENCES comments(id),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			ALTER TABLE posts ADD COLUMN edited_at DATETIME;
+			ALTER TABLE posts ADD COLUMN edited_by INTEGER;
+			ALTER TABLE posts ADD COLUMN revision_count INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE comments ADD COLUMN edited_at DATETIME;
+			ALTER TABLE comments ADD COLUMN edited_by INTEGER;
+			ALTER TABLE comments ADD COLUMN revision_count INTEGER NOT NULL DEFAULT 0;
+		`,
+			Postgres: `
+			CREATE TABLE post_revisions (
+				post_id INTEGER NOT NULL REFERENCES posts(id),
+				revision INTEGER NOT NULL,
+				title TEXT,
+				topic TEXT,
+				content TEXT,
+				user_id INTEGER REFERENCES users(id),
+				created_at TIMESTAMPTZ NOT NULL,
+				PRIMARY KEY (post_id, revision)
+			);
+			CREATE TABLE comment_revisions (
+				comment_id INTEGER NOT NULL REFERENCES comments(id),
+				revision INTEGER NOT NULL,
+				content TEXT NOT NULL,
+				user_id INTEGER REFERENCES users(id),
+				created_at TIMESTAMPTZ NOT NULL,
+				PRIMARY KEY (comment_id, revision)
+			);
+			ALTER TABLE posts ADD COLUMN edited_at TIMESTAMPTZ;
+			ALTER TABLE posts ADD COLUMN edited_by INTEGER REFERENCES users(id);
+			ALTER TABLE posts ADD COLUMN revision_count INTEGER NOT NULL DEFAULT 0;
+			ALTER TABLE comments ADD COLUMN edited_at TIMESTAMPTZ;
+			ALTER TABLE comments ADD COLUMN edited_by INTEGER REFERENCES users(id);
+			ALTER TABLE comments ADD COLUMN revision_count INTEGER NOT NULL DEFAULT 0;
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			ALTER TABLE comments DROP COLUMN revision_count;
+			ALTER TABLE comments DROP COLUMN edited_by;
+			ALTER TABLE comments DROP COLUMN edited_at;
+			ALTER TABLE posts DROP COLUMN revision_count;
+			ALTER TABLE posts DROP COLUMN edited_by;
+			ALTER TABLE posts DROP COLUMN edited_at;
+			DROP TABLE IF EXISTS comment_revisions;
+			DROP TABLE IF EXISTS post_revisions;
+		`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "moderation",
+		// User reports and the warnings / suspensions handed out when resolving them
+		// Content hidden by a moderator stays hidden (and isn't purged) until restored
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE reports (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'user')),
+				target_id INTEGER NOT NULL,
+				target_user_id INTEGER NOT NULL,
+				reporter_id INTEGER NOT NULL,
+				reason TEXT NOT NULL,
+				details TEXT NOT NULL DEFAULT '',
+				status TEXT NOT NULL DEFAULT 'open',
+				created_at DATETIME NOT NULL,
+				resolved_at DATETIME,
+				resolved_by INTEGER,
+				action TEXT,
+				note TEXT,
+				FOREIGN KEY(reporter_id) REFERENCES users(id),
+				FOREIGN KEY(resolved_by) REFERENCES users(id)
+			);
+			CREATE INDEX reports_status_created_at_idx ON reports (status, created_at, id);
+			CREATE INDEX reports_target_idx ON reports (target_type, target_id);
+			CREATE UNIQUE INDEX reports_open_reporter_idx ON reports (target_type, target_id, reporter_id) WHERE status = 'open';
+			CREATE TABLE user_sanctions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				kind TEXT NOT NULL,
+				reason TEXT NOT NULL DEFAULT '',
+				report_id INTEGER,
+				issued_by INTEGER NOT NULL,
+				created_at DATETIME NOT NULL,
+				expires_at DATETIME,
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				FOREIGN KEY(report_id) REFERENCES reports(id),
+				FOREIGN KEY(issued_by) REFERENCES users(id)
+			);
+			CREATE INDEX user_sanctions_user_id_idx ON user_sanctions (user_id, kind);
+			ALTER TABLE posts ADD COLUMN hidden_at DATETIME;
+			ALTER TABLE comments ADD COLUMN hidden_at DATETIME;
+		`,
+			Postgres: `
+			CREATE TABLE reports (
+				id SERIAL PRIMARY KEY,
+				target_type TEXT NOT NULL CHECK (target_type IN ('post', 'comment', 'user')),
+				target_id INTEGER NOT NULL,
+				target_user_id INTEGER NOT NULL,
+				reporter_id INTEGER NOT NULL REFERENCES users(id),
+				reason TEXT NOT NULL,
+				details TEXT NOT NULL DEFAULT '',
+				status TEXT NOT NULL DEFAULT 'open',
+				created_at TIMESTAMPTZ NOT NULL,
+				resolved_at TIMESTAMPTZ,
+				resolved_by INTEGER REFERENCES users(id),
+				action TEXT,
+				note TEXT
+			);
+			CREATE INDEX reports_status_created_at_idx ON reports (status, created_at, id);
+			CREATE INDEX reports_target_idx ON reports (target_type, target_id);
+			CREATE UNIQUE INDEX reports_open_reporter_idx ON reports (target_type, target_id, reporter_id) WHERE status = 'open';
+			CREATE TABLE user_sanctions (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				kind TEXT NOT NULL,
+				reason TEXT NOT NULL DEFAULT '',
+				report_id INTEGER REFERENCES reports(id),
+				issued_by INTEGER NOT NULL REFERENCES users(id),
+				created_at TIMESTAMPTZ NOT NULL,
+				expires_at TIMESTAMPTZ
+			);
+			CREATE INDEX user_sanctions_user_id_idx ON user_sanctions (user_id, kind);
+			ALTER TABLE posts ADD COLUMN hidden_at TIMESTAMPTZ;
+			ALTER TABLE comments ADD COLUMN hidden_at TIMESTAMPTZ;
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			ALTER TABLE comments DROP COLUMN hidden_at;
+			ALTER TABLE posts DROP COLUMN hidden_at;
+			DROP TABLE IF EXISTS user_sanctions;
+			DROP TABLE IF EXISTS reports;
+		`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "audit_log",
+		// Append-only record of privileged actions, triggers reject any change to existing entries
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE audit_log (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				actor_id INTEGER NOT NULL,
+				action TEXT NOT NULL,
+				target_type TEXT NOT NULL,
+				target_id TEXT NOT NULL,
+				before_state TEXT,
+				after_state TEXT,
+				ip TEXT NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT '',
+				method TEXT NOT NULL DEFAULT '',
+				path TEXT NOT NULL DEFAULT '',
+				request_id TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY(actor_id) REFERENCES users(id)
+			);
+			CREATE INDEX audit_log_created_at_idx ON audit_log (created_at, id);
+			CREATE INDEX audit_log_actor_idx ON audit_log (actor_id, created_at);
+			CREATE INDEX audit_log_target_idx ON audit_log (target_type, target_id);
+			CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log BEGIN
+				SELECT RAISE(ABORT, 'audit_log is append-only');
+			END;
+			CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log BEGIN
+				SELECT RAISE(ABORT, 'audit_log is append-only');
+			END;
+		`,
+			Postgres: `
+			CREATE TABLE audit_log (
+				id SERIAL PRIMARY KEY,
+				actor_id INTEGER NOT NULL REFERENCES users(id),
+				action TEXT NOT NULL,
+				target_type TEXT NOT NULL,
+				target_id TEXT NOT NULL,
+				before_state JSONB,
+				after_state JSONB,
+				ip TEXT NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL DEFAULT '',
+				method TEXT NOT NULL DEFAULT '',
+				path TEXT NOT NULL DEFAULT '',
+				request_id TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL
+			);
+			CREATE INDEX audit_log_created_at_idx ON audit_log (created_at, id);
+			CREATE INDEX audit_log_actor_idx ON audit_log (actor_id, created_at);
+			CREATE INDEX audit_log_target_idx ON audit_log (target_type, target_id);
+			CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'audit_log is append-only';
+			END;
+			$$ LANGUAGE plpgsql;
+			CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+				FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP TABLE IF EXISTS audit_log;
+		`,
+			Postgres: `
+			DROP TABLE IF EXISTS audit_log;
+			DROP FUNCTION IF EXISTS audit_log_append_only();
+		`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "roles",
+		// Roles, the permissions each role holds and the roles granted to users, globally or per topic
+		// users.isAdmin is kept in step with the global admin role for the frontend
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE roles (
+				name TEXT PRIMARY KEY
+			);
+			CREATE TABLE role_permissions (
+				role TEXT NOT NULL,
+				permission TEXT NOT NULL,
+				own_only INTEGER NOT NULL DEFAULT 0,
+				PRIMARY KEY (role, permission),
+				FOREIGN KEY(role) REFERENCES roles(name)
+			);
+			CREATE TABLE user_roles (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				role TEXT NOT NULL,
+				topic TEXT,
+				granted_by INTEGER,
+				granted_at DATETIME NOT NULL,
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				FOREIGN KEY(role) REFERENCES roles(name),
+				FOREIGN KEY(topic) REFERENCES topics(topic),
+				FOREIGN KEY(granted_by) REFERENCES users(id)
+			);
+			CREATE UNIQUE INDEX user_roles_global_idx ON user_roles (user_id) WHERE topic IS NULL;
+			CREATE UNIQUE INDEX user_roles_topic_idx ON user_roles (user_id, role, topic) WHERE topic IS NOT NULL;
+		`,
+			Postgres: `
+			CREATE TABLE roles (
+				name TEXT PRIMARY KEY
+			);
+			CREATE TABLE role_permissions (
+				role TEXT NOT NULL REFERENCES roles(name),
+				permission TEXT NOT NULL,
+				own_only BOOLEAN NOT NULL DEFAULT FALSE,
+				PRIMARY KEY (role, permission)
+			);
+			CREATE TABLE user_roles (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				role TEXT NOT NULL REFERENCES roles(name),
+				topic TEXT REFERENCES topics(topic),
+				granted_by INTEGER REFERENCES users(id),
+				granted_at TIMESTAMPTZ NOT NULL
+			);
+			CREATE UNIQUE INDEX user_roles_global_idx ON user_roles (user_id) WHERE topic IS NULL;
+			CREATE UNIQUE INDEX user_roles_topic_idx ON user_roles (user_id, role, topic) WHERE topic IS NOT NULL;
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP TABLE IF EXISTS user_roles;
+			DROP TABLE IF EXISTS role_permissions;
+			DROP TABLE IF EXISTS roles;
+		`,
+		},
+		UpFunc: seedRoles,
+	},
+	{
+		Version: 11,
+		Name:    "sanction_lifting",
+		// Sanctions can be lifted before they expire, and sanctioning users becomes a permission of its own
+		// Databases that ran the roles migration after the permission was added to the defaults already have it
+		Up: Statements{
+			SQLite: `
+			ALTER TABLE user_sanctions ADD COLUMN lifted_at DATETIME;
+			ALTER TABLE user_sanctions ADD COLUMN lifted_by INTEGER REFERENCES users(id);
+			INSERT INTO role_permissions (role, permission)
+			SELECT name, 'user.sanction' FROM roles
+			WHERE name IN ('admin', 'global_moderator')
+				AND NOT EXISTS (SELECT 1 FROM role_permissions p WHERE p.role = roles.name AND p.permission = 'user.sanction');
+		`,
+			Postgres: `
+			ALTER TABLE user_sanctions ADD COLUMN lifted_at TIMESTAMPTZ;
+			ALTER TABLE user_sanctions ADD COLUMN lifted_by INTEGER REFERENCES users(id);
+			INSERT INTO role_permissions (role, permission)
+			SELECT name, 'user.sanction' FROM roles
+			WHERE name IN ('admin', 'global_moderator')
+				AND NOT EXISTS (SELECT 1 FROM role_permissions p WHERE p.role = roles.name AND p.permission = 'user.sanction');
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DELETE FROM role_permissions WHERE permission = 'user.sanction';
+			ALTER TABLE user_sanctions DROP COLUMN lifted_by;
+			ALTER TABLE user_sanctions DROP COLUMN lifted_at;
+		`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "topic_ids",
+		// Posts, revisions and topic roles refer to topics by ID instead of by name, so topics can be renamed
+		// Topic names only found on posts (AddPost used to accept any name) become topics of their own
+		// Slugs are filled in by backfillTopicSlugs
+		Up: Statements{
+			SQLite: `
+			ALTER TABLE topics ADD COLUMN slug TEXT;
+			ALTER TABLE topics ADD COLUMN description TEXT NOT NULL DEFAULT '';
+			ALTER TABLE topics ADD COLUMN icon TEXT NOT NULL DEFAULT '';
+			ALTER TABLE topics ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+			INSERT INTO topics (topic)
+			SELECT DISTINCT topic FROM posts WHERE topic <> '' AND topic NOT IN (SELECT topic FROM topics);
+			UPDATE topics SET position = id;
+			CREATE UNIQUE INDEX topics_slug_idx ON topics (slug);
+			CREATE INDEX topics_position_idx ON topics (position, id);
+
+			ALTER TABLE posts ADD COLUMN topic_id INTEGER REFERENCES topics(id);
+			UPDATE posts SET topic_id = (SELECT t.id FROM topics t WHERE t.topic = posts.topic);
+			DROP INDEX posts_topic_created_at_idx;
+			ALTER TABLE posts DROP COLUMN topic;
+			CREATE INDEX posts_topic_id_created_at_idx ON posts (topic_id, created_at, id);
+
+			ALTER TABLE post_revisions ADD COLUMN topic_id INTEGER REFERENCES topics(id);
+			UPDATE post_revisions SET topic_id = (SELECT t.id FROM topics t WHERE t.topic = post_revisions.topic);
+			ALTER TABLE post_revisions DROP COLUMN topic;
+
+			CREATE TABLE user_roles_new (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				role TEXT NOT NULL,
+				topic_id INTEGER,
+				granted_by INTEGER,
+				granted_at DATETIME NOT NULL,
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				FOREIGN KEY(role) REFERENCES roles(name),
+				FOREIGN KEY(topic_id) REFERENCES topics(id),
+				FOREIGN KEY(granted_by) REFERENCES users(id)
+			);
+			INSERT INTO user_roles_new (id, user_id, role, topic_id, granted_by, granted_at)
+			SELECT r.id, r.user_id, r.role, t.id, r.granted_by, r.granted_at
+			FROM user_roles r LEFT JOIN topics t ON t.topic = r.topic;
+			DROP TABLE user_roles;
+			ALTER TABLE user_roles_new RENAME TO user_roles;
+			CREATE UNIQUE INDEX user_roles_global_idx ON user_roles (user_id) WHERE topic_id IS NULL;
+			CREATE UNIQUE INDEX user_roles_topic_idx ON user_roles (user_id, role, topic_id) WHERE topic_id IS NOT NULL;
+		`,
+			Postgres: `
+			ALTER TABLE topics ADD COLUMN slug TEXT;
+			ALTER TABLE topics ADD COLUMN description TEXT NOT NULL DEFAULT '';
+			ALTER TABLE topics ADD COLUMN icon TEXT NOT NULL DEFAULT '';
+			ALTER TABLE topics ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+			INSERT INTO topics (topic)
+			SELECT DISTINCT topic FROM posts WHERE topic <> '' AND topic NOT IN (SELECT topic FROM topics);
+			UPDATE topics SET position = id;
+			CREATE UNIQUE INDEX topics_slug_idx ON topics (slug);
+			CREATE INDEX topics_position_idx ON topics (position, id);
+
+			ALTER TABLE posts ADD COLUMN topic_id INTEGER REFERENCES topics(id);
+			UPDATE posts SET topic_id = (SELECT t.id FROM topics t WHERE t.topic = posts.topic);
+			DROP INDEX posts_topic_created_at_idx;
+			ALTER TABLE posts DROP COLUMN topic;
+			CREATE INDEX posts_topic_id_created_at_idx ON posts (topic_id, created_at, id);
+
+			ALTER TABLE post_revisions ADD COLUMN topic_id INTEGER REFERENCES topics(id);
+			UPDATE post_revisions SET topic_id = (SELECT t.id FROM topics t WHERE t.topic = post_revisions.topic);
+			ALTER TABLE post_revisions DROP COLUMN topic;
+
+			ALTER TABLE user_roles ADD COLUMN topic_id INTEGER REFERENCES topics(id);
+			UPDATE user_roles SET topic_id = (SELECT t.id FROM topics t WHERE t.topic = user_roles.topic);
+			DROP INDEX user_roles_global_idx;
+			DROP INDEX user_roles_topic_idx;
+			ALTER TABLE user_roles DROP COLUMN topic;
+			CREATE UNIQUE INDEX user_roles_global_idx ON user_roles (user_id) WHERE topic_id IS NULL;
+			CREATE UNIQUE INDEX user_roles_topic_idx ON user_roles (user_id, role, topic_id) WHERE topic_id IS NOT NULL;
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			CREATE TABLE user_roles_old (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				role TEXT NOT NULL,
+				topic TEXT,
+				granted_by INTEGER,
+				granted_at DATETIME NOT NULL,
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				FOREIGN KEY(role) REFERENCES roles(name),
+				FOREIGN KEY(topic) REFERENCES topics(topic),
+				FOREIGN KEY(granted_by) REFERENCES users(id)
+			);
+			INSERT INTO user_roles_old (id, user_id, role, topic, granted_by, granted_at)
+			SELECT r.id, r.user_id, r.role, t.topic, r.granted_by, r.granted_at
+			FROM user_roles r LEFT JOIN topics t ON t.id = r.topic_id;
+			DROP TABLE user_roles;
+			ALTER TABLE user_roles_old RENAME TO user_roles;
+			CREATE UNIQUE INDEX user_roles_global_idx ON user_roles (user_id) WHERE topic IS NULL;
+			CREATE UNIQUE INDEX user_roles_topic_idx ON user_roles (user_id, role, topic) WHERE topic IS NOT NULL;
+
+			ALTER TABLE post_revisions ADD COLUMN topic TEXT;
+			UPDATE post_revisions SET topic = (SELECT t.topic FROM topics t WHERE t.id = post_revisions.topic_id);
+			ALTER TABLE post_revisions DROP COLUMN topic_id;
+
+			ALTER TABLE posts ADD COLUMN topic TEXT;
+			UPDATE posts SET topic = (SELECT t.topic FROM topics t WHERE t.id = posts.topic_id);
+			DROP INDEX posts_topic_id_created_at_idx;
+			ALTER TABLE posts DROP COLUMN topic_id;
+			CREATE INDEX posts_topic_created_at_idx ON posts (topic, created_at, id);
+
+			DROP INDEX topics_position_idx;
+			DROP INDEX topics_slug_idx;
+			ALTER TABLE topics DROP COLUMN position;
+			ALTER TABLE topics DROP COLUMN icon;
+			ALTER TABLE topics DROP COLUMN description;
+			ALTER TABLE topics DROP COLUMN slug;
+		`,
+			Postgres: `
+			ALTER TABLE user_roles ADD COLUMN topic TEXT REFERENCES topics(topic);
+			UPDATE user_roles SET topic = (SELECT t.topic FROM topics t WHERE t.id = user_roles.topic_id);
+			DROP INDEX user_roles_global_idx;
+			DROP INDEX user_roles_topic_idx;
+			ALTER TABLE user_roles DROP COLUMN topic_id;
+			CREATE UNIQUE INDEX user_roles_global_idx ON user_roles (user_id) WHERE topic IS NULL;
+			CREATE UNIQUE INDEX user_roles_topic_idx ON user_roles (user_id, role, topic) WHERE topic IS NOT NULL;
+
+			ALTER TABLE post_revisions ADD COLUMN topic TEXT;
+			UPDATE post_revisions SET topic = (SELECT t.topic FROM topics t WHERE t.id = post_revisions.topic_id);
+			ALTER TABLE post_revisions DROP COLUMN topic_id;
+
+			ALTER TABLE posts ADD COLUMN topic TEXT;
+			UPDATE posts SET topic = (SELECT t.topic FROM topics t WHERE t.id = posts.topic_id);
+			DROP INDEX posts_topic_id_created_at_idx;
+			ALTER TABLE posts DROP COLUMN topic_id;
+			CREATE INDEX posts_topic_created_at_idx ON posts (topic, created_at, id);
+
+			DROP INDEX topics_position_idx;
+			DROP INDEX topics_slug_idx;
+			ALTER TABLE topics DROP COLUMN position;
+			ALTER TABLE topics DROP COLUMN icon;
+			ALTER TABLE topics DROP COLUMN description;
+			ALTER TABLE topics DROP COLUMN slug;
+		`,
+		},
+		UpFunc: backfillTopicSlugs,
+	},
+	{
+		Version: 13,
+		Name:    "topic_parents",
+		// Topics can be nested under a parent topic, existing topics stay at the top level
+		Up: Statements{
+			SQLite: `
+			ALTER TABLE topics ADD COLUMN parent_id INTEGER REFERENCES topics(id);
+			CREATE INDEX topics_parent_idx ON topics (parent_id);
+		`,
+			Postgres: `
+			ALTER TABLE topics ADD COLUMN parent_id INTEGER REFERENCES topics(id);
+			CREATE INDEX topics_parent_idx ON topics (parent_id);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP INDEX IF EXISTS topics_parent_idx;
+			ALTER TABLE topics DROP COLUMN parent_id;
+		`,
+		},
+	},
+	{
+		Version: 14,
+		Name:    "thread_tools",
+		// Merged posts point at the post that took their comments, and moderators may move, merge and split threads
+		Up: Statements{
+			SQLite: `
+			ALTER TABLE posts ADD COLUMN merged_into INTEGER REFERENCES posts(id);
+			INSERT INTO role_permissions (role, permission)
+			SELECT name, 'thread.manage' FROM roles
+			WHERE name IN ('admin', 'global_moderator', 'topic_moderator')
+				AND NOT EXISTS (SELECT 1 FROM role_permissions p WHERE p.role = roles.name AND p.permission = 'thread.manage');
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DELETE FROM role_permissions WHERE permission = 'thread.manage';
+			ALTER TABLE posts DROP COLUMN merged_into;
+		`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "notifications",
+		// In-app notifications, and the notification types each user has turned off or back on
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE notifications (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				user_id INTEGER NOT NULL,
+				type TEXT NOT NULL,
+				actor_id INTEGER NOT NULL,
+				post_id INTEGER NOT NULL,
+				comment_id INTEGER,
+				created_at DATETIME NOT NULL,
+				read_at DATETIME,
+				FOREIGN KEY(user_id) REFERENCES users(id),
+				FOREIGN KEY(actor_id) REFERENCES users(id)
+			);
+			CREATE INDEX notifications_user_created_at_idx ON notifications (user_id, created_at, id);
+			CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+			CREATE TABLE notification_preferences (
+				user_id INTEGER NOT NULL,
+				type TEXT NOT NULL,
+				enabled INTEGER NOT NULL,
+				PRIMARY KEY (user_id, type),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+		`,
+			Postgres: `
+			CREATE TABLE notifications (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				type TEXT NOT NULL,
+				actor_id INTEGER NOT NULL REFERENCES users(id),
+				post_id INTEGER NOT NULL,
+				comment_id INTEGER,
+				created_at TIMESTAMPTZ NOT NULL,
+				read_at TIMESTAMPTZ
+			);
+			CREATE INDEX notifications_user_created_at_idx ON notifications (user_id, created_at, id);
+			CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+			CREATE TABLE notification_preferences (
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				type TEXT NOT NULL,
+				enabled BOOLEAN NOT NULL,
+				PRIMARY KEY (user_id, type)
+			);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP TABLE IF EXISTS notification_preferences;
+			DROP TABLE IF EXISTS notifications;
+		`,
+		},
+	},
+	{
+		Version: 16,
+		Name:    "subscriptions",
+		// Topics and posts users watch or mute, and whether they watch the posts they create or comment on
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE subscriptions (
+				user_id INTEGER NOT NULL,
+				target_type TEXT NOT NULL,
+				target_id INTEGER NOT NULL,
+				muted INTEGER NOT NULL DEFAULT 0,
+				created_at DATETIME NOT NULL,
+				PRIMARY KEY (user_id, target_type, target_id),
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE INDEX subscriptions_target_idx ON subscriptions (target_type, target_id);
+			CREATE TABLE subscription_settings (
+				user_id INTEGER PRIMARY KEY,
+				on_post INTEGER NOT NULL,
+				on_comment INTEGER NOT NULL,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+		`,
+			Postgres: `
+			CREATE TABLE subscriptions (
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				target_type TEXT NOT NULL CHECK (target_type IN ('topic', 'post')),
+				target_id INTEGER NOT NULL,
+				muted BOOLEAN NOT NULL DEFAULT FALSE,
+				created_at TIMESTAMPTZ NOT NULL,
+				PRIMARY KEY (user_id, target_type, target_id)
+			);
+			CREATE INDEX subscriptions_target_idx ON subscriptions (target_type, target_id);
+			CREATE TABLE subscription_settings (
+				user_id INTEGER PRIMARY KEY REFERENCES users(id),
+				on_post BOOLEAN NOT NULL,
+				on_comment BOOLEAN NOT NULL
+			);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP TABLE IF EXISTS subscription_settings;
+			DROP TABLE IF EXISTS subscriptions;
+		`,
+		},
+	},
+	{
+		Version: 17,
+		Name:    "email",
+		// Verified addresses live on users, new ones wait in email_verifications until their token comes back
+		// Emails are queued in email_outbox and retried from there until sent
+		Up: Statements{
+			SQLite: `
+			ALTER TABLE users ADD COLUMN email TEXT;
+			ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
+			CREATE UNIQUE INDEX users_email_idx ON users (email);
+			CREATE TABLE email_verifications (
+				token_hash TEXT PRIMARY KEY,
+				user_id INTEGER NOT NULL,
+				email TEXT NOT NULL,
+				created_at DATETIME NOT NULL,
+				expires_at DATETIME NOT NULL,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE INDEX email_verifications_user_idx ON email_verifications (user_id);
+			CREATE TABLE email_settings (
+				user_id INTEGER PRIMARY KEY,
+				digest TEXT NOT NULL,
+				notifications INTEGER NOT NULL,
+				digest_sent_at DATETIME,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE TABLE email_outbox (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				to_address TEXT NOT NULL,
+				subject TEXT NOT NULL,
+				text_body TEXT NOT NULL,
+				html_body TEXT NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT NOT NULL DEFAULT '',
+				created_at DATETIME NOT NULL,
+				next_attempt_at DATETIME,
+				sent_at DATETIME,
+				failed_at DATETIME
+			);
+			CREATE INDEX email_outbox_next_attempt_idx ON email_outbox (next_attempt_at);
+		`,
+			Postgres: `
+			ALTER TABLE users ADD COLUMN email TEXT;
+			ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+			CREATE UNIQUE INDEX users_email_idx ON users (email);
+			CREATE TABLE email_verifications (
+				token_hash TEXT PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				email TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL,
+				expires_at TIMESTAMPTZ NOT NULL
+			);
+			CREATE INDEX email_verifications_user_idx ON email_verifications (user_id);
+			CREATE TABLE email_settings (
+				user_id INTEGER PRIMARY KEY REFERENCES users(id),
+				digest TEXT NOT NULL CHECK (digest IN ('off', 'daily', 'weekly')),
+				notifications BOOLEAN NOT NULL,
+				digest_sent_at TIMESTAMPTZ
+			);
+			CREATE TABLE email_outbox (
+				id SERIAL PRIMARY KEY,
+				to_address TEXT NOT NULL,
+				subject TEXT NOT NULL,
+				text_body TEXT NOT NULL,
+				html_body TEXT NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				last_error TEXT NOT NULL DEFAULT '',
+				created_at TIMESTAMPTZ NOT NULL,
+				next_attempt_at TIMESTAMPTZ,
+				sent_at TIMESTAMPTZ,
+				failed_at TIMESTAMPTZ
+			);
+			CREATE INDEX email_outbox_next_attempt_idx ON email_outbox (next_attempt_at);
+		`,
+		},
+		Down: Statements{
+			SQLite: `
+			DROP TABLE IF EXISTS email_outbox;
+			DROP TABLE IF EXISTS email_settings;
+			DROP TABLE IF EXISTS email_verifications;
+			DROP INDEX IF EXISTS users_email_idx;
+			ALTER TABLE users DROP COLUMN email_verified_at;
+			ALTER TABLE users DROP COLUMN email;
+		`,
+		},
+	},
+	{
+		Version: 18,
+		Name:    "normalize_timestamps",
+		// SQLite compares timestamps as text, so rows written by CURRENT_TIMESTAMP defaults ("2024-05-01 10:00:00")
+		// sort wrongly against those the app writes ("2024-05-01 10:00:00.5 +0000 UTC") and break keyset pagination
+		// Rewrite them in the app's format, which sorts correctly as text; Postgres stores real timestamps
+		Up: Statements{
+			SQLite: `
+			UPDATE posts SET created_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') || ' +0000 UTC'
+			WHERE created_at NOT LIKE '% +0000 UTC' AND strftime('%Y-%m-%d %H:%M:%f', created_at) IS NOT NULL;
+			UPDATE posts SET last_activity_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', last_activity_at), '0'), '.') || ' +0000 UTC'
+			WHERE last_activity_at NOT LIKE '% +0000 UTC' AND strftime('%Y-%m-%d %H:%M:%f', last_activity_at) IS NOT NULL;
+			UPDATE comments SET created_at = rtrim(rtrim(strftime('%Y-%m-%d %H:%M:%f', created_at), '0'), '.') || ' +0000 UTC'
+			WHERE created_at NOT LIKE '% +0000 UTC' AND strftime('%Y-%m-%d %H:%M:%f', created_at) IS NOT NULL;
+		`,
+			Postgres: `SELECT 1;`,
+		},
+		// The rewritten timestamps still read back as the same times
+		Down: Statements{
+			SQLite: `SELECT 1;`,
+		},
+	},
+	{
+		Version: 19,
+		Name:    "email_verifications_sent",
+		// When each verification email was sent, whatever became of it, so sending them can be throttled per user
+		Up: Statements{
+			SQLite: `
+			CREATE TABLE email_verifications_sent (
+				user_id INTEGER NOT NULL,
+				sent_at DATETIME NOT NULL,
+				FOREIGN KEY(user_id) REFERENCES users(id)
+			);
+			CREATE INDEX email_verifications_sent_user_idx ON email_verifications_sent (user_id, sent_at);
+		`,
+			Postgres: `
+			CREATE TABLE email_verifications_sent (
+				user_id INTEGER NOT NULL REFERENCES users(id),
+				sent_at TIMESTAMPTZ NOT NULL
+			);
+			CREATE INDEX email_verifications_sent_user_idx ON email_verifications_sent (user_id, sent_at);
+		`,
+		},
+		Down: Statements{
+			SQLite: `DROP TABLE IF EXISTS email_verifications_sent;`,
+		},
+	},
+}
+
+// Fill in the default roles and their permissions, and make existing admins hold the admin role
+func seedRoles(tx *sql.Tx, dialect Dialect) error {
+	for _, role := range models.Roles {
+		if _, err := tx.Exec(dialect.Rebind(`INSERT INTO roles (name) VALUES (?)`), role); err != nil {
+			return err
+		}
+		for _, permission := range models.DefaultRolePermissions[role] {
+			if _, err := tx.Exec(dialect.Rebind(`INSERT INTO role_permissions (role, permission, own_only) VALUES (?, ?, ?)`),
+				role, permission.Permission, permission.OwnOnly); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := tx.Exec(dialect.Rebind(`INSERT INTO user_roles (user_id, role, granted_at) SELECT id, ?, ? FROM users WHERE isAdmin = 1`),
+		models.RoleAdmin, time.Now().UTC())
+	return err
+}
+
+// Give every topic a unique slug derived from its name, numbering repeats (e.g. "physics-2")
+func backfillTopicSlugs(tx *sql.Tx, dialect Dialect) error {
+	rows, err := tx.Query(`SELECT id, topic FROM topics ORDER BY id`)
+	if err != nil {
+		return err
+	}
+
+	slugs := map[int]string{}
+	taken := map[string]bool{}
+	var ids []int
+	for rows.Next() {
+		var id int
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return err
+		}
+		base := slug.Make(name)
+		if base == "" {
+			base = "topic"
+		}
+		candidate := base
+		for n := 2; taken[candidate]; n++ {
+			candidate = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[candidate] = true
+		slugs[id] = candidate
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err := tx.Exec(dialect.Rebind(`UPDATE topics SET slug = ? WHERE id = ?`), slugs[id], id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compute the hot rank of every existing post
+func backfillHotRanks(tx *sql.Tx, dialect Dialect) error {
+	rows, err := tx.Query(`SELECT id, score, created_at FROM posts`)
+	if err != nil {
+		return err
+	}
+
+	ranks := map[int]float64{}
+	for rows.Next() {
+		var id, score int
+		var createdAt sql.NullTime
+		if err := rows.Scan(&id, &score, &createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if !createdAt.Valid {
+			createdAt.Time = time.Now().UTC()
+		}
+		ranks[id] = ranking.HotRank(score, createdAt.Time)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, rank := range ranks {
+		if _, err := tx.Exec(dialect.Rebind(`UPDATE posts SET hot_rank = ? WHERE id = ?`), rank, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"sample-go-app/internal/config"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/slug"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// A default topic and its subtopics
+type DefaultTopic struct {
+	Name      string
+	Subtopics []string
+}
+
+// Default topics inserted into a new database
+var DefaultTopics = []DefaultTopic{
+	{Name: "Computer Science", Subtopics: []string{"Algorithms", "Programming Languages", "Systems"}},
+	{Name: "Mathematics", Subtopics: []string{"Linear Algebra", "Calculus", "Statistics"}},
+	{Name: "Physics", Subtopics: []string{"Classical Mechanics", "Quantum Physics"}},
+	{Name: "Chemistry", Subtopics: []string{"Organic Chemistry", "Physical Chemistry"}},
+	{Name: "Biology", Subtopics: []string{"Genetics", "Ecology"}},
+	{Name: "Literature"},
+	{Name: "Economics", Subtopics: []string{"Microeconomics", "Macroeconomics"}},
+}
+
+// Insert a default topic under its parent (0 for a top-level topic)
+func seedTopic(conn *sql.DB, dialect Dialect, name string, parentID, position int) (int, error) {
+	var parent any
+	if parentID != 0 {
+		parent = parentID
+	}
+	if _, err := conn.Exec(dialect.Rebind(`
+		INSERT INTO topics (topic, slug, position, parent_id) VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING;
+	`), name, slug.Make(name), position, parent); err != nil {
+		return 0, fmt.Errorf("failed to insert topic '%s': %w", name, err)
+	}
+
+	var id int
+	if err := conn.QueryRow(dialect.Rebind(`SELECT id FROM topics WHERE topic = ?`), name).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get topic '%s': %w", name, err)
+	}
+	return id, nil
+}
+
+// Insert the default admin user (if enabled) and topics
+// Safe to run on every startup, existing rows are left alone
+func Seed(conn *sql.DB, dialect Dialect, admin config.AdminConfig, bcryptCost int) error {
+	if admin.Seed {
+		// Calculate hashed password for admin user
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(admin.Password), bcryptCost)
+		if err != nil {
+			return fmt.Errorf("failed to hash admin password: %w", err)
+		}
+
+		// Insert default admin user for debugging
+		res, err := conn.Exec(dialect.Rebind(`
+			INSERT INTO users (username, password, isAdmin)
+			VALUES (?, ?, 1)
+			ON CONFLICT (username) DO NOTHING;
+		`), admin.Username, string(hashedPassword))
+		if err != nil {
+			return fmt.Errorf("failed to insert admin user: %w", err)
+		}
+
+		// Grant a newly created admin the admin role, leaving any later role changes alone
+		if inserted, err := res.RowsAffected(); err == nil && inserted == 1 {
+			_, err = conn.Exec(dialect.Rebind(`
+				INSERT INTO user_roles (user_id, role, granted_at)
+				SELECT id, ?, ? FROM users WHERE username = ?;
+			`), models.RoleAdmin, time.Now().UTC(), admin.Username)
+			if err != nil {
+				return fmt.Errorf("failed to grant admin role: %w", err)
+			}
+		}
+	}
+
+	// Insert the default topics into a new database only, so renamed or deleted topics stay that way
+	var topics int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM topics`).Scan(&topics); err != nil {
+		return fmt.Errorf("failed to count topics: %w", err)
+	}
+	for i := 0; topics == 0 && i < len(DefaultTopics); i++ {
+		parentID, err := seedTopic(conn, dialect, DefaultTopics[i].Name, 0, i+1)
+		if err != nil {
+			return err
+		}
+		for j, name := range DefaultTopics[i].Subtopics {
+			if _, err := seedTopic(conn, dialect, name, parentID, j+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+// Package diff computes line-level differences between two versions of a text.
+package diff
+
+import "strings"
+
+// Kinds of diff lines
+const (
+	Equal  = "equal"  // present in both versions
+	Insert = "insert" // only in the new version
+	Delete = "delete" // only in the old version
+)
+
+// Above this many (old lines x new lines), the changed region is reported as replaced
+// wholesale instead of computing a minimal diff, bounding the memory used
+const maxTableSize = 4_000_000
+
+// Models one line of a diff
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Compute the diff turning old into new, based on the longest common subsequence of their lines
+func Lines(old, new string) []Line {
+	a, b := splitLines(old), splitLines(new)
+
+	// Trim the common prefix and suffix, which leaves little to compare for typical edits
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	lines = appendLines(lines, Equal, a[:prefix])
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	return appendLines(lines, Equal, a[len(a)-suffix:])
+}
+
+// Diff two runs of lines that differ at both ends
+func diffMiddle(a, b []string) []Line {
+	if len(a)*len(b) > maxTableSize {
+		return appendLines(appendLines(nil, Delete, a), Insert, b)
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// Walk the table, preferring deletions so removed lines come before their replacements
+	var lines []Line
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, Line{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, Line{Op: Delete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, Line{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	return appendLines(appendLines(lines, Delete, a[i:]), Insert, b[j:])
+}
+
+func appendLines(lines []Line, op string, texts []string) []Line {
+	for _, text := range texts {
+		lines = append(lines, Line{Op: op, Text: text})
+	}
+	return lines
+}
+
+// Split a text into lines, treating CRLF like LF; the empty text has no lines
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
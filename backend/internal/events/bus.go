@@ -0,0 +1,188 @@
+// Package events is the in-process event bus feeding real-time updates to connected clients.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of event
+const (
+	PostCreated         = "post.created"
+	PostUpdated         = "post.updated"
+	PostDeleted         = "post.deleted"
+	PostRestored        = "post.restored"
+	CommentCreated      = "comment.created"
+	CommentUpdated      = "comment.updated"
+	CommentDeleted      = "comment.deleted"
+	CommentRestored     = "comment.restored"
+	NotificationCreated = "notification.created"
+)
+
+// Events queued for a subscriber that isn't keeping up, beyond which it is dropped
+const subscriberBuffer = 64
+
+// Channel carrying the changes to a post and its comments
+func PostChannel(postID int) string {
+	return "post:" + strconv.Itoa(postID)
+}
+
+// Channel carrying the posts created in a topic
+func TopicChannel(topicID int) string {
+	return "topic:" + strconv.Itoa(topicID)
+}
+
+// Channel carrying a user's notifications, which only that user may subscribe to
+func UserChannel(userID int) string {
+	return "user:" + strconv.Itoa(userID)
+}
+
+// Models one published change
+type Event struct {
+	// Unique across restarts of the server, for Last-Event-ID
+	ID      string
+	Channel string
+	Type    string
+	// JSON encoded payload, usually the created or updated resource
+	Data json.RawMessage
+
+	seq uint64
+}
+
+// Receives the events published on a set of channels
+type Subscription struct {
+	channels map[string]bool
+	events   chan Event
+}
+
+// Get the subscription's events, the channel being closed if the subscriber fell too far behind
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Fans published events out to subscribers, keeping the most recent ones so clients can resume
+type Bus struct {
+	mu sync.Mutex
+	// Tells the IDs of this process apart from those handed out before a restart
+	epoch string
+	seq   uint64
+	// Ring buffer of the latest events, oldest first starting at start
+	replay      []Event
+	start       int
+	replaySize  int
+	subscribers map[*Subscription]bool
+}
+
+// Create a bus keeping the last replaySize events for resuming subscribers
+func NewBus(replaySize int) *Bus {
+	return &Bus{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		replaySize:  replaySize,
+		subscribers: map[*Subscription]bool{},
+	}
+}
+
+// Publish an event to every subscriber of the channel
+// Subscribers that can't keep up are dropped, and resume from the replay buffer when they reconnect
+func (b *Bus) Publish(channel, kind string, data any) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to encode %s event on %s: %v", kind, channel, err)
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event := Event{ID: fmt.Sprintf("%s-%d", b.epoch, b.seq), Channel: channel, Type: kind, Data: encoded, seq: b.seq}
+	if b.replaySize > 0 {
+		if len(b.replay) < b.replaySize {
+			b.replay = append(b.replay, event)
+		} else {
+			b.replay[b.start] = event
+			b.start = (b.start + 1) % b.replaySize
+		}
+	}
+
+	for sub := range b.subscribers {
+		if !sub.channels[channel] {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.dropLocked(sub)
+		}
+	}
+}
+
+// Subscribe to the given channels
+// With lastEventID set, the buffered events published after it are returned so the subscriber can catch up;
+// complete is false if some of them are no longer buffered (or the ID is unknown), in which case none are
+// returned and the subscriber should reload whatever it shows
+func (b *Bus) Subscribe(channels []string, lastEventID string) (sub *Subscription, missed []Event, complete bool) {
+	sub = &Subscription{channels: map[string]bool{}, events: make(chan Event, subscriberBuffer)}
+	for _, channel := range channels {
+		sub.channels[channel] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscribers[sub] = true
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+
+	seq, ok := b.parseIDLocked(lastEventID)
+	if !ok {
+		return sub, nil, false
+	}
+	// Events up to seq were seen, so the one right after it must still be buffered
+	if seq < b.seq {
+		if len(b.replay) == 0 || b.replay[b.start].seq > seq+1 {
+			return sub, nil, false
+		}
+	}
+	for i := range b.replay {
+		event := b.replay[(b.start+i)%len(b.replay)]
+		if event.seq > seq && sub.channels[event.Channel] {
+			missed = append(missed, event)
+		}
+	}
+	return sub, missed, true
+}
+
+// Stop delivering events to a subscription
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dropLocked(sub)
+}
+
+// Remove a subscriber and close its channel, unless already done (callers must hold the lock)
+func (b *Bus) dropLocked(sub *Subscription) {
+	if b.subscribers[sub] {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// Get the sequence number of an event ID handed out by this process (callers must hold the lock)
+func (b *Bus) parseIDLocked(id string) (uint64, bool) {
+	epoch, seqText, found := strings.Cut(id, "-")
+	if !found || epoch != b.epoch {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(seqText, 10, 64)
+	if err != nil || seq > b.seq {
+		return 0, false
+	}
+	return seq, true
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Entries fetched per query while exporting the audit log
+const auditExportBatch = 500
+
+// Encode the state of an audited target, nil when there is nothing to record
+func snapshot(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// Record a privileged action the current user just performed, along with the request behind it
+// Failures are logged rather than reported, since the action itself already succeeded
+func (h *Handler) audit(r *http.Request, action, targetType string, targetID string, before, after any) {
+	user, _ := auth.UserFromContext(r.Context())
+	entry := models.AuditEntry{
+		ActorID:    user.ID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Before:     snapshot(before),
+		After:      snapshot(after),
+		IP:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RequestID:  middleware.GetReqID(r.Context()),
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := h.auditLog.AddAuditEntry(r.Context(), &entry); err != nil {
+		log.Printf("Failed to record %s of %s %s in the audit log: %v", action, targetType, targetID, err)
+	}
+}
+
+// Get the current state of a post for the audit log, nil if it can't be read
+func (h *Handler) postSnapshot(r *http.Request, id int) any {
+	post, err := h.posts.GetPost(r.Context(), id)
+	if err != nil {
+		return nil
+	}
+	return post
+}
+
+// Get the current state of a comment for the audit log, nil if it can't be read
+func (h *Handler) commentSnapshot(r *http.Request, id int) any {
+	comment, err := h.comments.GetComment(r.Context(), id)
+	if err != nil {
+		return nil
+	}
+	return comment
+}
+
+// Parse the audit log filters, writing an error response if one is invalid
+func auditFilter(w http.ResponseWriter, r *http.Request) (store.AuditFilter, bool) {
+	query := r.URL.Query()
+	filter := store.AuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+		TargetID:   query.Get("target_id"),
+	}
+	if actor := query.Get("actor"); actor != "" {
+		id, err := strconv.Atoi(actor)
+		if err != nil || id <= 0 {
+			http.Error(w, `{"error": "Invalid actor"}`, http.StatusBadRequest)
+			return store.AuditFilter{}, false
+		}
+		filter.ActorID = id
+	}
+
+	var fromOK, toOK bool
+	filter.From, fromOK = parseSearchDate(query.Get("from"), false)
+	filter.To, toOK = parseSearchDate(query.Get("to"), true)
+	if !fromOK || !toOK {
+		http.Error(w, `{"error": "Invalid date, use YYYY-MM-DD or RFC 3339"}`, http.StatusBadRequest)
+		return store.AuditFilter{}, false
+	}
+	return filter, true
+}
+
+// Get a page of the audit log (needs PermAuditView)
+// Supports ?actor=, ?action=, ?target_type=, ?target_id=, ?from=, ?to=
+// and the paging parameters ?limit=, ?cursor= and ?sort= (newest, oldest)
+func (h *Handler) ListAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+	params, ok := pageParams(w, r, store.AuditSorts)
+	if !ok {
+		return
+	}
+
+	entries, err := h.auditLog.ListAuditEntries(r.Context(), filter, params.Probe())
+	if err != nil {
+		if !writePaginationError(w, err) {
+			http.Error(w, `{"error": "Failed to fetch audit log"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	page := pagination.NewPage(entries, params, func(entry models.AuditEntry) pagination.Cursor {
+		return store.AuditCursor(params.Sort, entry)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		http.Error(w, `{"error": "Failed to encode audit log"}`, http.StatusInternalServerError)
+	}
+}
+
+// Download every audit log entry matching the filters as CSV (needs PermAuditView)
+// Takes the same filters as ListAuditLog, plus ?sort=
+func (h *Handler) ExportAuditLog(w http.ResponseWriter, r *http.Request) {
+	filter, ok := auditFilter(w, r)
+	if !ok {
+		return
+	}
+	sort := r.URL.Query().Get("sort")
+	if sort == "" {
+		sort = store.AuditSorts[0]
+	} else if sort != store.SortNewest && sort != store.SortOldest {
+		http.Error(w, `{"error": "Invalid sort order"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Fetch the first batch before writing anything, so a failure can still be reported properly
+	params := pagination.Params{Limit: auditExportBatch, Sort: sort}
+	entries, err := h.auditLog.ListAuditEntries(r.Context(), filter, params)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch audit log"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit-log.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"id", "created_at", "actor_id", "actor_username", "action", "target_type", "target_id",
+		"before", "after", "ip", "user_agent", "method", "path", "request_id"})
+	for len(entries) > 0 {
+		for _, entry := range entries {
+			out.Write([]string{strconv.Itoa(entry.ID), entry.CreatedAt.Format(time.RFC3339Nano), strconv.Itoa(entry.ActorID),
+				csvText(entry.ActorUsername), csvText(entry.Action), csvText(entry.TargetType), csvText(entry.TargetID),
+				csvText(string(entry.Before)), csvText(string(entry.After)), csvText(entry.IP), csvText(entry.UserAgent),
+				csvText(entry.Method), csvText(entry.Path), csvText(entry.RequestID)})
+		}
+		if len(entries) < auditExportBatch {
+			break
+		}
+
+		// Continue after the last entry written
+		cursor := store.AuditCursor(sort, entries[len(entries)-1])
+		params.After = &cursor
+		if entries, err = h.auditLog.ListAuditEntries(r.Context(), filter, params); err != nil {
+			// The status is already sent, so all that can be done is cutting the file short
+			log.Printf("Failed to export audit log: %v", err)
+			break
+		}
+	}
+	out.Flush()
+}
+
+// Quote user supplied text for a CSV cell, so spreadsheets show it rather than run it as a formula
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
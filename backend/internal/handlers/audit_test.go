@@ -0,0 +1,37 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"sample-go-app/internal/models"
+)
+
+func TestExportAuditLogDefusesFormulas(t *testing.T) {
+	s := newTestServer(t)
+	s.topic("Physics", 0)
+	alice := s.user("alice", "")
+	moderator := s.user("=HYPERLINK(\"http://example.com\")", models.RoleGlobalModerator)
+	admin := s.user("admin", models.RoleAdmin)
+
+	post := alice.post("Hello", "Physics")
+	moderator.expect(http.MethodDelete, fmt.Sprintf("/api/posts/%d", post.ID), nil, http.StatusOK)
+
+	moderator.expect(http.MethodGet, "/api/admin/audit/export", nil, http.StatusForbidden)
+	rows, err := csv.NewReader(bytes.NewReader(admin.expect(http.MethodGet, "/api/admin/audit/export", nil, http.StatusOK))).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d CSV rows, want a header and one entry: %v", len(rows), rows)
+	}
+	if rows[0][3] != "actor_username" || rows[1][4] != models.AuditPostDelete {
+		t.Fatalf("unexpected CSV: %v", rows)
+	}
+	if want := "'=HYPERLINK(\"http://example.com\")"; rows[1][3] != want {
+		t.Errorf("actor_username cell = %q, want %q", rows[1][3], want)
+	}
+}
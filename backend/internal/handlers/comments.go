@@ -1,42 +1,45 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	db "sample-go-app/internal/database"
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/events"
 	"sample-go-app/internal/models"
-
-	"github.com/go-chi/chi/v5"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
 )
 
 // Get the Comment details from its ID
-func GetComment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetComment(w http.ResponseWriter, r *http.Request) {
 	// Extract the comment ID from the route parameter
-	commentID := chi.URLParam(r, "comment_id")
-
-	row := db.DB.QueryRow(`
-		SELECT c.id, c.post_id, c.user_id, COALESCE(u.username, 'Unknown') AS username, c.content, c.created_at
-		FROM comments c
-		LEFT JOIN users u ON c.user_id = u.id
-		WHERE c.id = ?
-	`, commentID)
-
-	comment := models.Comment{}
-	// Scan the result into the comment
-	if err := row.Scan(&comment.ID, &comment.PostID, &comment.Author, &comment.Username, &comment.Content, &comment.CreatedAt); err != nil {
-		if err == sql.ErrNoRows {
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	comment, err := h.comments.GetComment(r.Context(), commentID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
 		} else {
 			http.Error(w, `{"error": "Failed to get comment"}`, http.StatusInternalServerError)
 		}
 		return
 	}
+	comments := []models.Comment{comment}
+	if err := h.fillCommentVotes(r.Context(), comments); err != nil {
+		http.Error(w, `{"error": "Failed to fetch votes"}`, http.StatusInternalServerError)
+		return
+	}
+	comment = comments[0]
 
-	// Set the response headers and return the post as JSON
+	// Set the response headers and return the comment as JSON
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(comment); err != nil {
@@ -45,34 +48,42 @@ func GetComment(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Retrieve all subcomments for a given comment
-func GetSubComments(w http.ResponseWriter, r *http.Request) {
+// Retrieve a page of subcomments for a given comment
+// Supports ?limit=, ?cursor= and ?sort= (newest, oldest)
+func (h *Handler) GetSubComments(w http.ResponseWriter, r *http.Request) {
 	// Extract the comment ID from the URL parameter
-	commentID := chi.URLParam(r, "comment_id")
-
-	// Query the database for subcomments associated with the comment
-	rows, err := db.DB.Query(`
-		SELECT c.id, c.post_id, c.parent_id, c.user_id, COALESCE(u.username, 'Unknown') AS username, c.content, c.created_at
-		FROM comments c
-		LEFT JOIN users u ON c.user_id = u.id
-		WHERE c.parent_id = ?
-		ORDER BY c.created_at DESC
-	`, commentID)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to fetch subcomments"}`, http.StatusInternalServerError)
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
 		return
 	}
-	defer rows.Close()
-
-	// Create a slice to hold the subcomments
-	subcomments := []models.Comment{}
-	for rows.Next() {
-		var subcomment models.Comment
-		if err := rows.Scan(&subcomment.ID, &subcomment.PostID, &subcomment.ParentID, &subcomment.Author, &subcomment.Username, &subcomment.Content, &subcomment.CreatedAt); err != nil {
-			http.Error(w, `{"error": "Failed to parse subcomment data"}`, http.StatusInternalServerError)
-			return
+
+	params, ok := pageParams(w, r, store.CommentSorts)
+	if !ok {
+		return
+	}
+
+	// Fails for the comments of deleted and hidden posts too
+	if _, err := h.comments.GetComment(r.Context(), commentID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to get comment"}`, http.StatusInternalServerError)
 		}
-		subcomments = append(subcomments, subcomment)
+		return
+	}
+
+	subcomments, err := h.comments.ListSubComments(r.Context(), commentID, params.Probe())
+	if err != nil {
+		if !writePaginationError(w, err) {
+			http.Error(w, `{"error": "Failed to fetch subcomments"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	page := pagination.NewPage(subcomments, params, func(c models.Comment) pagination.Cursor { return store.CommentCursor(params.Sort, c) })
+	if err := h.fillCommentVotes(r.Context(), page.Items); err != nil {
+		http.Error(w, `{"error": "Failed to fetch votes"}`, http.StatusInternalServerError)
+		return
 	}
 
 	// Set the response content type to JSON
@@ -80,17 +91,21 @@ func GetSubComments(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
 	// Return the subcomments as a JSON response
-	if err := json.NewEncoder(w).Encode(subcomments); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		http.Error(w, `{"error": "Failed to encode subcomments"}`, http.StatusInternalServerError)
 		return
 	}
 }
 
 // Add a new subcomment to a comment
-func AddSubComment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddSubComment(w http.ResponseWriter, r *http.Request) {
 	// Extract the post/comment ID from the URL parameter
-	postID := chi.URLParam(r, "post_id")
-	commentID := chi.URLParam(r, "comment_id")
+	postID, postOK := idParam(r, "post_id")
+	commentID, commentOK := idParam(r, "comment_id")
+	if !postOK || !commentOK {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
 
 	// Parse the request body into a new comment
 	subcomment := &models.Comment{}
@@ -99,24 +114,34 @@ func AddSubComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Stamp the author from the JWT, rather than trusting the request body
+	user, ok := authorFromRequest(w, r, subcomment.Author)
+	if !ok {
+		return
+	}
+	subcomment.Author = user.ID
+	subcomment.Username = user.Username
+
 	if subcomment.Content == "" {
 		http.Error(w, `{"error": "Subcomment content is required"}`, http.StatusBadRequest)
 		return
 	}
 
+	// Insert the subcomment (parent set to the comment ID)
+	subcomment.PostID = postID
+	subcomment.ParentID = commentID
 	subcomment.CreatedAt = time.Now().UTC()
-	// Insert the subcomment into the database (set parent_id to the comment ID)
-	res, err := db.DB.Exec("INSERT INTO comments (post_id, parent_id, user_id, content, created_at) VALUES (?, ?, ?, ?, ?)", postID, commentID, subcomment.Author, subcomment.Content, subcomment.CreatedAt)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to create subcomment"}`, http.StatusInternalServerError)
+	if err := h.comments.CreateComment(r.Context(), subcomment); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to create subcomment"}`, http.StatusInternalServerError)
+		}
 		return
 	}
-
-	// Retrieve the last inserted ID for the subcomment, and update subcomment
-	id, _ := res.LastInsertId()
-	subcomment.ID = int(id)
-	subcomment.PostID, _ = strconv.Atoi(postID)
-	subcomment.ParentID, _ = strconv.Atoi(commentID)
+	h.events.Publish(events.PostChannel(subcomment.PostID), events.CommentCreated, subcomment)
+	h.notifyComment(r, subcomment)
+	h.autoSubscribe(r, subcomment.Author, subcomment.PostID, true)
 
 	// Return the created subcomment as JSON
 	w.Header().Set("Content-Type", "application/json")
@@ -127,9 +152,13 @@ func AddSubComment(w http.ResponseWriter, r *http.Request) {
 }
 
 // Update an existing comment
-func UpdateComment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateComment(w http.ResponseWriter, r *http.Request) {
 	// Extract the comment ID from the route parameter
-	commentID := chi.URLParam(r, "comment_id")
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
 
 	// Parse the request body into a Comment
 	comment := &models.Comment{}
@@ -144,19 +173,27 @@ func UpdateComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update the comment in the database
-	res, err := db.DB.Exec("UPDATE comments SET content = ? WHERE id = ?", comment.Content, commentID)
-	if err != nil || res == nil {
-		http.Error(w, `{"error": "Failed to update comment"}`, http.StatusInternalServerError)
-		return
+	// Moderators editing someone else's comment are audited
+	override := auth.IsPrivilegedAccess(r.Context())
+	var before any
+	if override {
+		before = h.commentSnapshot(r, commentID)
 	}
 
-	// Check if the comment was found and updated
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+	// Update the comment in the store, recording the editor
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.comments.UpdateCommentContent(r.Context(), commentID, comment.Content, user.ID, time.Now().UTC()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to update comment"}`, http.StatusInternalServerError)
+		}
 		return
 	}
+	if override {
+		h.audit(r, models.AuditCommentUpdate, models.AuditTargetComment, strconv.Itoa(commentID), before, h.commentSnapshot(r, commentID))
+	}
+	h.publishComment(r, events.CommentUpdated, commentID)
 
 	// Send a success response
 	w.Header().Set("Content-Type", "application/json")
@@ -164,77 +201,62 @@ func UpdateComment(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"success": true}`))
 }
 
-// Delete subcomments recursively
-func DeleteSubComments(tx *sql.Tx, parentID string) error {
-	// Fetch subcomments of the given parentID
-	rows, err := tx.Query(`SELECT id FROM comments WHERE parent_id = ?`, parentID)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	// Iterate through subcomments and delete them recursively
-	for rows.Next() {
-		var subcommentID string
-		if err := rows.Scan(&subcommentID); err != nil {
-			return err
-		}
-
-		// Recursively delete subcomments
-		if err := DeleteSubComments(tx, subcommentID); err != nil {
-			return err
-		}
-
-		// Delete the subcomment itself
-		if _, err := tx.Exec(`DELETE FROM comments WHERE id = ?`, subcommentID); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-// Delete a comment and all its subcomments
-func DeleteComment(w http.ResponseWriter, r *http.Request) {
+// Soft delete a comment, leaving a "[deleted]" tombstone so its subcomments stay visible
+func (h *Handler) DeleteComment(w http.ResponseWriter, r *http.Request) {
 	// Extract the comment ID from the route parameter
-	commentID := chi.URLParam(r, "comment_id")
-
-	// Begin a transaction
-	tx, err := db.DB.Begin()
-	if err != nil {
-		http.Error(w, `{"error": "Failed to start transaction"}`, http.StatusInternalServerError)
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
 		return
 	}
+	user, _ := auth.UserFromContext(r.Context())
 
-	// Attempt to delete subcomments recursively
-	if err := DeleteSubComments(tx, commentID); err != nil {
-		tx.Rollback()
-		http.Error(w, `{"error": "Failed to delete subcomments"}`, http.StatusInternalServerError)
-		return
+	// Moderators deleting someone else's comment are audited
+	override := auth.IsPrivilegedAccess(r.Context())
+	var before any
+	if override {
+		before = h.commentSnapshot(r, commentID)
 	}
 
-	// Delete the main comment
-	res, err := tx.Exec(`DELETE FROM comments WHERE id = ?`, commentID)
-	if err != nil {
-		tx.Rollback()
-		http.Error(w, `{"error": "Failed to delete comment"}`, http.StatusInternalServerError)
+	if err := h.comments.DeleteComment(r.Context(), commentID, user.ID, time.Now().UTC()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to delete comment"}`, http.StatusInternalServerError)
+		}
 		return
 	}
+	if override {
+		h.audit(r, models.AuditCommentDelete, models.AuditTargetComment, strconv.Itoa(commentID), before, h.commentSnapshot(r, commentID))
+	}
+	h.publishComment(r, events.CommentDeleted, commentID)
 
-	// Check if any rows were affected
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		tx.Rollback()
-		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// Restore a soft deleted comment (needs PermContentRestore in the comment's topic)
+func (h *Handler) RestoreComment(w http.ResponseWriter, r *http.Request) {
+	// Extract the comment ID from the route parameter
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Deleted comment not found"}`, http.StatusNotFound)
 		return
 	}
 
-	// Commit the transaction
-	if err := tx.Commit(); err != nil {
-		tx.Rollback()
-		http.Error(w, `{"error": "Failed to commit transaction"}`, http.StatusInternalServerError)
+	before := h.commentSnapshot(r, commentID)
+	if err := h.comments.RestoreComment(r.Context(), commentID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Deleted comment not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to restore comment"}`, http.StatusInternalServerError)
+		}
 		return
 	}
+	h.audit(r, models.AuditCommentRestore, models.AuditTargetComment, strconv.Itoa(commentID), before, h.commentSnapshot(r, commentID))
+	h.publishComment(r, events.CommentRestored, commentID)
 
 	// Send a success response
 	w.Header().Set("Content-Type", "application/json")
@@ -242,18 +264,14 @@ func DeleteComment(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"success": true}`))
 }
 
-// Get the owner's ID of a comment
-func GetCommentOwnerID(r *http.Request) (int, error) {
+// Get the owner of a comment and the topic of its post, for permission checks
+func (h *Handler) CommentResource(r *http.Request) (auth.Resource, error) {
 	// Extract the comment ID from the route parameter
-	commentID := chi.URLParam(r, "comment_id")
-
-	var ownerID int
-	// Query the database for the user_id associated with the given commentID
-	err := db.DB.QueryRow("SELECT user_id FROM comments WHERE id = ?", commentID).Scan(&ownerID)
-	if err != nil {
-		return -1, err
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		return auth.Resource{}, store.ErrNotFound
 	}
 
-	// Return the owner ID if no errors occurred
-	return ownerID, nil
+	ownerID, topicID, err := h.comments.GetCommentOwner(r.Context(), commentID)
+	return auth.Resource{OwnerID: ownerID, TopicID: topicID}, err
 }
@@ -0,0 +1,74 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+)
+
+func TestCommentsFollowTheirPost(t *testing.T) {
+	s := newTestServer(t)
+	s.topic("Physics", 0)
+	alice := s.user("alice", "")
+	admin := s.user("admin", models.RoleAdmin)
+
+	post := alice.post("Hello", "Physics")
+	postPath := fmt.Sprintf("/api/posts/%d", post.ID)
+	var comment, reply models.Comment
+	alice.decode(http.MethodPost, postPath+"/comments", map[string]string{"content": "first"}, http.StatusCreated, &comment)
+	commentPath := fmt.Sprintf("%s/comments/%d", postPath, comment.ID)
+	alice.decode(http.MethodPost, commentPath, map[string]string{"content": "reply"}, http.StatusCreated, &reply)
+	if reply.ParentID != comment.ID {
+		t.Fatalf("reply parent = %d, want %d", reply.ParentID, comment.ID)
+	}
+
+	// While the post is deleted its comments can't be read, edited, voted on or replied to
+	alice.expect(http.MethodDelete, postPath, nil, http.StatusOK)
+	reads := []string{postPath + "/comments", commentPath, commentPath + "/subcomments", commentPath + "/revisions"}
+	for _, path := range reads {
+		s.anonymous().expect(http.MethodGet, path, nil, http.StatusNotFound)
+	}
+	alice.expect(http.MethodPatch, commentPath, map[string]string{"content": "edited"}, http.StatusNotFound)
+	alice.expect(http.MethodPost, commentPath+"/vote", map[string]int{"value": 1}, http.StatusNotFound)
+	alice.expect(http.MethodPost, commentPath, map[string]string{"content": "another reply"}, http.StatusNotFound)
+
+	// Restoring the post brings them back
+	admin.expect(http.MethodPost, postPath+"/restore", nil, http.StatusOK)
+	for _, path := range reads {
+		s.anonymous().expect(http.MethodGet, path, nil, http.StatusOK)
+	}
+	alice.expect(http.MethodPatch, commentPath, map[string]string{"content": "edited"}, http.StatusOK)
+	alice.expect(http.MethodPost, commentPath+"/vote", map[string]int{"value": 1}, http.StatusOK)
+}
+
+func TestDeletedCommentKeepsItsReplies(t *testing.T) {
+	s := newTestServer(t)
+	s.topic("Physics", 0)
+	alice := s.user("alice", "")
+	bob := s.user("bob", "")
+
+	post := alice.post("Hello", "Physics")
+	postPath := fmt.Sprintf("/api/posts/%d", post.ID)
+	var comment models.Comment
+	alice.decode(http.MethodPost, postPath+"/comments", map[string]string{"content": "first"}, http.StatusCreated, &comment)
+	commentPath := fmt.Sprintf("%s/comments/%d", postPath, comment.ID)
+	bob.expect(http.MethodPost, commentPath, map[string]string{"content": "reply"}, http.StatusCreated)
+
+	bob.expect(http.MethodDelete, commentPath, nil, http.StatusForbidden)
+	alice.expect(http.MethodDelete, commentPath, nil, http.StatusOK)
+
+	// The comment stays as a tombstone, its reply still below it
+	var tombstone models.Comment
+	s.anonymous().decode(http.MethodGet, commentPath, nil, http.StatusOK, &tombstone)
+	if !tombstone.Deleted || tombstone.Content == "first" {
+		t.Errorf("deleted comment = %+v, want a tombstone", tombstone)
+	}
+	var replies pagination.Page[models.Comment]
+	s.anonymous().decode(http.MethodGet, commentPath+"/subcomments", nil, http.StatusOK, &replies)
+	if len(replies.Items) != 1 || replies.Items[0].Content != "reply" {
+		t.Errorf("replies = %+v, want the one reply", replies.Items)
+	}
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/mail"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Longest email address accepted, the limit of an SMTP path
+const maxEmailLength = 254
+
+// How long a user waits between verification emails, whichever addresses they go to
+const verificationResendInterval = time.Minute
+
+// Most verification emails sent to a user's addresses per day
+const maxDailyVerifications = 5
+
+// Body of a request to set the current user's address
+type emailRequest struct {
+	Email string `json:"email"`
+}
+
+// Email settings to change, missing fields are left as they are
+type emailSettingsRequest struct {
+	Digest        *string `json:"digest"`
+	Notifications *bool   `json:"notifications"`
+}
+
+// Body of a request to verify an address
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// Check an email address is a bare address (no display name) and lowercase it,
+// so that an address belongs to one user however it is typed
+func normalizeEmail(address string) (string, bool) {
+	address = strings.TrimSpace(address)
+	parsed, err := netmail.ParseAddress(address)
+	if err != nil || parsed.Address != address || len(address) > maxEmailLength {
+		return "", false
+	}
+	return strings.ToLower(address), true
+}
+
+// Email notifications to the recipients who asked for them and have a verified address
+func (h *Handler) emailNotifications(r *http.Request, notifications []models.Notification) {
+	for _, notification := range notifications {
+		settings, err := h.emails.EmailSettings(r.Context(), notification.UserID)
+		if err != nil {
+			log.Printf("Failed to get email settings of user %d: %v", notification.UserID, err)
+			continue
+		}
+		if !settings.Notifications || settings.Email == "" {
+			continue
+		}
+		recipient, err := h.users.GetUserByID(r.Context(), notification.UserID)
+		if err != nil {
+			log.Printf("Failed to get user %d: %v", notification.UserID, err)
+			continue
+		}
+
+		data := mail.NotificationData{
+			Username:     recipient.Username,
+			Notification: notification,
+			Summary:      mail.NotificationSummary(notification),
+			BaseURL:      h.cfg.Mail.BaseURL,
+		}
+		if err := mail.Queue(r.Context(), h.emails, mail.TemplateNotification, settings.Email, data); err != nil {
+			log.Printf("Failed to queue notification email to user %d: %v", notification.UserID, err)
+		}
+	}
+}
+
+// Write email settings as a JSON response
+func writeEmailSettings(w http.ResponseWriter, status int, settings models.EmailSettings) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(settings); err != nil {
+		http.Error(w, `{"error": "Failed to encode email settings"}`, http.StatusInternalServerError)
+	}
+}
+
+// Get the current user's address, the one waiting to be verified, and what is emailed to them
+func (h *Handler) GetEmailSettings(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	settings, err := h.emails.EmailSettings(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch email settings"}`, http.StatusInternalServerError)
+		return
+	}
+	writeEmailSettings(w, http.StatusOK, settings)
+}
+
+// Set the current user's address, emailing it a link to verify it with
+// The address only replaces the current one once verified, sending it again replaces the earlier link
+// Verification emails are limited per user to one a minute and maxDailyVerifications a day
+func (h *Handler) SetEmail(w http.ResponseWriter, r *http.Request) {
+	var req emailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	address, ok := normalizeEmail(req.Email)
+	if !ok {
+		http.Error(w, `{"error": "Invalid email address"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	settings, err := h.emails.EmailSettings(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch email settings"}`, http.StatusInternalServerError)
+		return
+	}
+	if settings.Email == address {
+		http.Error(w, `{"error": "Email already verified"}`, http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UTC()
+	recent, err := h.emails.CountEmailVerifications(r.Context(), user.ID, now.Add(-verificationResendInterval))
+	if err != nil {
+		http.Error(w, `{"error": "Failed to check verification emails"}`, http.StatusInternalServerError)
+		return
+	}
+	if recent > 0 {
+		http.Error(w, `{"error": "Verification email already sent, try again later"}`, http.StatusTooManyRequests)
+		return
+	}
+	today, err := h.emails.CountEmailVerifications(r.Context(), user.ID, now.Add(-store.EmailVerificationLogRetention))
+	if err != nil {
+		http.Error(w, `{"error": "Failed to check verification emails"}`, http.StatusInternalServerError)
+		return
+	}
+	if today >= maxDailyVerifications {
+		http.Error(w, `{"error": "Too many verification emails today, try again tomorrow"}`, http.StatusTooManyRequests)
+		return
+	}
+	inUse, err := h.emails.EmailInUse(r.Context(), address, user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to check email"}`, http.StatusInternalServerError)
+		return
+	}
+	if inUse {
+		http.Error(w, `{"error": "Email already in use"}`, http.StatusConflict)
+		return
+	}
+
+	token, err := auth.NewVerificationToken()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to create verification token"}`, http.StatusInternalServerError)
+		return
+	}
+	verification := models.EmailVerification{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     address,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.cfg.Mail.VerificationTTL),
+	}
+	if err := h.emails.AddEmailVerification(r.Context(), verification); err != nil {
+		http.Error(w, `{"error": "Failed to set email"}`, http.StatusInternalServerError)
+		return
+	}
+	data := mail.VerifyEmailData{
+		Username: user.Username,
+		Email:    address,
+		Link:     h.cfg.Mail.BaseURL + "/verify-email?token=" + token,
+		Expires:  verification.ExpiresAt,
+	}
+	if err := mail.Queue(r.Context(), h.emails, mail.TemplateVerifyEmail, address, data); err != nil {
+		log.Printf("Failed to queue verification email to user %d: %v", user.ID, err)
+		http.Error(w, `{"error": "Failed to send verification email"}`, http.StatusInternalServerError)
+		return
+	}
+
+	settings, err = h.emails.EmailSettings(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch email settings"}`, http.StatusInternalServerError)
+		return
+	}
+	writeEmailSettings(w, http.StatusAccepted, settings)
+}
+
+// Remove the current user's address, and the one waiting to be verified, so nothing is emailed to them
+func (h *Handler) RemoveEmail(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.emails.RemoveEmail(r.Context(), user.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "No email address"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to remove email"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// Choose how often the current user gets a digest ("digest": off, daily or weekly)
+// and whether their notifications are emailed as they happen ("notifications")
+func (h *Handler) UpdateEmailSettings(w http.ResponseWriter, r *http.Request) {
+	var req emailSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Digest != nil && !slices.Contains(models.DigestFrequencies, *req.Digest) {
+		http.Error(w, `{"error": "Unknown digest frequency"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	settings, err := h.emails.EmailSettings(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch email settings"}`, http.StatusInternalServerError)
+		return
+	}
+	if req.Digest != nil {
+		settings.Digest = *req.Digest
+	}
+	if req.Notifications != nil {
+		settings.Notifications = *req.Notifications
+	}
+	if err := h.emails.SetEmailSettings(r.Context(), user.ID, settings); err != nil {
+		http.Error(w, `{"error": "Failed to update email settings"}`, http.StatusInternalServerError)
+		return
+	}
+	writeEmailSettings(w, http.StatusOK, settings)
+}
+
+// Verify an address with the token emailed to it, making it the address of the user who set it
+// Needs no login, since the link may be opened on another device than the one the address was set from
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.emails.VerifyEmail(r.Context(), auth.HashToken(req.Token), time.Now().UTC()); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, `{"error": "Invalid or expired token"}`, http.StatusNotFound)
+		case errors.Is(err, store.ErrConflict):
+			http.Error(w, `{"error": "Email already in use"}`, http.StatusConflict)
+		default:
+			http.Error(w, `{"error": "Failed to verify email"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
@@ -0,0 +1,73 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"sample-go-app/internal/models"
+)
+
+// Token in the link of a verification email
+var verificationToken = regexp.MustCompile(`verify-email\?token=([A-Za-z0-9_-]+)`)
+
+func TestVerifyEmail(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user("alice", "")
+
+	var settings models.EmailSettings
+	alice.decode(http.MethodPut, "/api/account/email", map[string]string{"email": " Alice@Example.com "}, http.StatusAccepted, &settings)
+	if settings.PendingEmail != "alice@example.com" || settings.Email != "" {
+		t.Fatalf("settings after setting the address = %+v", settings)
+	}
+
+	emails, err := s.stores.Emails.DueEmails(context.Background(), time.Now().UTC(), 10)
+	if err != nil {
+		t.Fatalf("due emails: %v", err)
+	}
+	if len(emails) != 1 || emails[0].To != "alice@example.com" {
+		t.Fatalf("queued emails = %+v, want one verification email", emails)
+	}
+	match := verificationToken.FindStringSubmatch(emails[0].Text)
+	if match == nil {
+		t.Fatalf("no verification link in %q", emails[0].Text)
+	}
+
+	s.anonymous().expect(http.MethodPost, "/api/account/email/verify", map[string]string{"token": "wrong"}, http.StatusNotFound)
+	s.anonymous().expect(http.MethodPost, "/api/account/email/verify", map[string]string{"token": match[1]}, http.StatusOK)
+	var verified models.EmailSettings
+	alice.decode(http.MethodGet, "/api/account/email", nil, http.StatusOK, &verified)
+	if verified.Email != "alice@example.com" || verified.VerifiedAt == nil || verified.PendingEmail != "" {
+		t.Errorf("settings after verifying = %+v", verified)
+	}
+}
+
+func TestVerificationEmailsAreThrottled(t *testing.T) {
+	s := newTestServer(t)
+	alice := s.user("alice", "")
+	bob := s.user("bob", "")
+
+	// Changing the address doesn't get around the wait
+	alice.expect(http.MethodPut, "/api/account/email", map[string]string{"email": "a@example.com"}, http.StatusAccepted)
+	alice.expect(http.MethodPut, "/api/account/email", map[string]string{"email": "a@example.com"}, http.StatusTooManyRequests)
+	alice.expect(http.MethodPut, "/api/account/email", map[string]string{"email": "b@example.com"}, http.StatusTooManyRequests)
+
+	// Nor does waiting once the day's verifications are used up
+	now := time.Now().UTC()
+	for i := range 5 {
+		verification := models.EmailVerification{
+			TokenHash: fmt.Sprintf("hash-%d", i),
+			UserID:    bob.userID,
+			Email:     fmt.Sprintf("bob%d@example.com", i),
+			CreatedAt: now.Add(-time.Duration(20-i) * time.Hour),
+			ExpiresAt: now.Add(time.Hour),
+		}
+		if err := s.stores.Emails.AddEmailVerification(context.Background(), verification); err != nil {
+			t.Fatalf("add verification: %v", err)
+		}
+	}
+	bob.expect(http.MethodPut, "/api/account/email", map[string]string{"email": "bob@example.com"}, http.StatusTooManyRequests)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/config"
+	"sample-go-app/internal/events"
+	"sample-go-app/internal/live"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Holds the dependencies shared by every HTTP handler
+type Handler struct {
+	posts         store.PostStore
+	comments      store.CommentStore
+	topics        store.TopicStore
+	users         store.UserStore
+	search        store.SearchStore
+	votes         store.VoteStore
+	sessions      store.SessionStore
+	revisions     store.RevisionStore
+	reports       store.ReportStore
+	sanctions     store.SanctionStore
+	auditLog      store.AuditStore
+	roles         store.RoleStore
+	threads       store.ThreadStore
+	notifications store.NotificationStore
+	subscriptions store.SubscriptionStore
+	emails        store.EmailStore
+	events        *events.Bus
+	live          *live.Rooms
+	policy        *auth.Policy
+	cfg           *config.Config
+}
+
+// Create the handlers on top of the given stores
+func New(stores store.Stores, cfg *config.Config) *Handler {
+	return &Handler{
+		posts:         stores.Posts,
+		comments:      stores.Comments,
+		topics:        stores.Topics,
+		users:         stores.Users,
+		search:        stores.Search,
+		votes:         stores.Votes,
+		sessions:      stores.Sessions,
+		revisions:     stores.Revisions,
+		reports:       stores.Reports,
+		sanctions:     stores.Sanctions,
+		auditLog:      stores.Audit,
+		roles:         stores.Roles,
+		threads:       stores.Threads,
+		notifications: stores.Notifications,
+		subscriptions: stores.Subscriptions,
+		emails:        stores.Emails,
+		events:        events.NewBus(cfg.Stream.ReplayBuffer),
+		live:          live.NewRooms(),
+		policy:        auth.NewPolicy(stores.Roles),
+		cfg:           cfg,
+	}
+}
+
+// Parse an integer ID from the route parameter with the given name
+func idParam(r *http.Request, name string) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, name))
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// Get the policy deciding what each user may do, used by the route middleware
+func (h *Handler) Policy() *auth.Policy {
+	return h.policy
+}
+
+// Check the current user holds a permission for a resource, writing an error response if not
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request, permission string, resource auth.Resource) bool {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return false
+	}
+	allowed, err := h.policy.Can(r.Context(), user, permission, resource)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to check permissions"}`, http.StatusInternalServerError)
+		return false
+	}
+	if !allowed {
+		http.Error(w, `{"error": "Forbidden"}`, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// Parse the ?limit=, ?cursor= and ?sort= parameters of a list endpoint
+// sorts lists the accepted sort orders, the first being the default
+func pageParams(w http.ResponseWriter, r *http.Request, sorts []string) (pagination.Params, bool) {
+	params, err := pagination.FromRequest(r, sorts...)
+	if err != nil {
+		writePaginationError(w, err)
+		return pagination.Params{}, false
+	}
+	return params, true
+}
+
+// Report a bad paging parameter, returning false if err is not a pagination error
+func writePaginationError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, pagination.ErrInvalidSort):
+		http.Error(w, `{"error": "Invalid sort order"}`, http.StatusBadRequest)
+	case errors.Is(err, pagination.ErrInvalidLimit):
+		http.Error(w, `{"error": "Invalid limit"}`, http.StatusBadRequest)
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		http.Error(w, `{"error": "Invalid cursor"}`, http.StatusBadRequest)
+	default:
+		return false
+	}
+	return true
+}
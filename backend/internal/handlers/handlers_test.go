@@ -0,0 +1,153 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"testing"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/config"
+	"sample-go-app/internal/handlers"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/router"
+	"sample-go-app/internal/slug"
+	"sample-go-app/internal/store"
+	"sample-go-app/internal/store/memory"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// The API served over an in-memory store
+type testServer struct {
+	t      *testing.T
+	server *httptest.Server
+	stores store.Stores
+}
+
+// Start the API on an empty in-memory store, the server is closed when the test ends
+// The auth cookies are only sent over HTTPS, so the server uses TLS
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	cfg := config.Defaults()
+	cfg.Auth.BcryptCost = bcrypt.MinCost
+	if err := auth.InitJWT(cfg.Auth); err != nil {
+		t.Fatalf("init JWT: %v", err)
+	}
+
+	stores := memory.New().Stores()
+	server := httptest.NewTLSServer(router.Setup(handlers.New(stores, &cfg), cfg.Server))
+	t.Cleanup(server.Close)
+	return &testServer{t: t, server: server, stores: stores}
+}
+
+// Create a topic, under parentID unless it is 0
+func (s *testServer) topic(name string, parentID int) models.Topic {
+	s.t.Helper()
+	topic := models.Topic{TopicName: name, Slug: slug.Make(name), ParentID: parentID}
+	if err := s.stores.Topics.CreateTopic(context.Background(), &topic); err != nil {
+		s.t.Fatalf("create topic %q: %v", name, err)
+	}
+	return topic
+}
+
+// A logged in user, sending requests with their cookies
+type client struct {
+	t      *testing.T
+	http   *http.Client
+	base   string
+	userID int
+}
+
+// Create an account, give it the global role unless it is empty, and log in as it
+func (s *testServer) user(username, role string) *client {
+	s.t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		s.t.Fatalf("cookie jar: %v", err)
+	}
+	// Server.Client returns the same client every time, so each user gets a copy with their own jar
+	httpClient := *s.server.Client()
+	httpClient.Jar = jar
+	c := &client{t: s.t, http: &httpClient, base: s.server.URL}
+
+	credentials := map[string]string{"username": username, "password": "password"}
+	c.expect(http.MethodPost, "/api/create_account", credentials, http.StatusCreated)
+	user, err := s.stores.Users.GetUserByUsername(context.Background(), username)
+	if err != nil {
+		s.t.Fatalf("get user %q: %v", username, err)
+	}
+	c.userID = user.ID
+	if role != "" {
+		if err := s.stores.Roles.GrantRole(context.Background(), &models.RoleGrant{UserID: user.ID, Role: role}); err != nil {
+			s.t.Fatalf("grant %s to %q: %v", role, username, err)
+		}
+	}
+	c.expect(http.MethodPost, "/api/login", credentials, http.StatusOK)
+	return c
+}
+
+// A client that isn't logged in
+func (s *testServer) anonymous() *client {
+	httpClient := *s.server.Client()
+	return &client{t: s.t, http: &httpClient, base: s.server.URL}
+}
+
+// Send a request with body encoded as JSON (none if nil), returning the status and response body
+func (c *client) do(method, path string, body any) (int, []byte) {
+	c.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("encode %s %s: %v", method, path, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, c.base+path, reader)
+	if err != nil {
+		c.t.Fatalf("build %s %s: %v", method, path, err)
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		c.t.Fatalf("read %s %s: %v", method, path, err)
+	}
+	return res.StatusCode, data
+}
+
+// Send a request, failing the test unless it gets the wanted status, and return the response body
+func (c *client) expect(method, path string, body any, status int) []byte {
+	c.t.Helper()
+	got, data := c.do(method, path, body)
+	if got != status {
+		c.t.Fatalf("%s %s: got status %d, want %d: %s", method, path, got, status, data)
+	}
+	return data
+}
+
+// Send a request expecting the wanted status, and decode its JSON response into out
+func (c *client) decode(method, path string, body any, status int, out any) {
+	c.t.Helper()
+	data := c.expect(method, path, body, status)
+	if err := json.Unmarshal(data, out); err != nil {
+		c.t.Fatalf("decode %s %s: %v: %s", method, path, err, data)
+	}
+}
+
+// Create a post, returning it as the API sent it back
+func (c *client) post(title, topic string) models.Post {
+	c.t.Helper()
+	var post models.Post
+	c.decode(http.MethodPost, "/api/posts", map[string]string{"title": title, "content": title + " content", "topic": topic},
+		http.StatusCreated, &post)
+	return post
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"sample-go-app/internal/auth"
+)
+
+// Get the current user for a write request
+// claimedAuthor is the author supplied in the request body (0 if omitted); the request is
+// rejected if it names anyone other than the logged-in user
+func authorFromRequest(w http.ResponseWriter, r *http.Request, claimedAuthor int) (*auth.CurrentUser, bool) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if claimedAuthor != 0 && claimedAuthor != user.ID {
+		http.Error(w, `{"error": "Cannot create content on behalf of another user"}`, http.StatusForbidden)
+		return nil, false
+	}
+
+	return user, true
+}
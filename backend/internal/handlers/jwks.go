@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"sample-go-app/internal/auth"
+)
+
+// Serve the public keys that verify access tokens, so other services can check them
+// Keys that were rotated out but still verify unexpired tokens are included
+func (h *Handler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/jwk-set+json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(auth.PublicKeys()); err != nil {
+		http.Error(w, `{"error": "Failed to encode keys"}`, http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/events"
+	"sample-go-app/internal/live"
+	"sample-go-app/internal/store"
+
+	"github.com/coder/websocket"
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// Limits of a live thread connection
+const (
+	// Largest message a client can send, in bytes
+	liveReadLimit = 4096
+	// Messages a client can send per second on average, and in a burst
+	liveMessageRate  = 5
+	liveMessageBurst = 10
+	// How often the server pings, and how long a client can take to answer before it is dropped
+	livePingInterval = 30 * time.Second
+	livePongTimeout  = 10 * time.Second
+	// How long a client can take to accept a message before it is dropped
+	liveWriteTimeout = 10 * time.Second
+)
+
+// Sent when the access token behind a connection expires, so the client refreshes it and reconnects
+const closeTokenExpired websocket.StatusCode = 4001
+
+// Body of a message from a live thread client
+type liveRequest struct {
+	Type   string `json:"type"`
+	Typing bool   `json:"typing"`
+}
+
+// Allows a steady rate of messages with some burst, like a token bucket
+type rateLimiter struct {
+	tokens float64
+	last   time.Time
+}
+
+// Take a token if one is left
+func (l *rateLimiter) allow(now time.Time) bool {
+	if !l.last.IsZero() {
+		l.tokens = min(liveMessageBurst, l.tokens+now.Sub(l.last).Seconds()*liveMessageRate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Whether the request comes from an allowed origin (or no browser at all)
+// Browsers send cookies with cross-site WebSocket handshakes, so other sites must not be let in
+func (h *Handler) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || slices.Contains(h.cfg.Server.AllowedOrigins, origin)
+}
+
+// Write a message to a live thread client as JSON
+func writeLiveMessage(ctx context.Context, conn *websocket.Conn, message live.Message) error {
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, liveWriteTimeout)
+	defer cancel()
+	return conn.Write(ctx, websocket.MessageText, encoded)
+}
+
+// Ping a live thread client and wait for its pong
+func pingLive(ctx context.Context, conn *websocket.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, livePongTimeout)
+	defer cancel()
+	return conn.Ping(ctx)
+}
+
+// Follow a post over a WebSocket, authenticated by the jwt cookie
+// Pushes the post's events (new, edited and deleted comments, as on the event stream),
+// who is viewing the post ("presence") and who is typing a reply ("typing")
+// Clients send {"type": "typing", "typing": true/false} while writing a reply, muted users can't
+func (h *Handler) LiveThread(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+	if _, err := h.posts.GetPost(r.Context(), postID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to get post"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	if !h.allowedOrigin(r) {
+		http.Error(w, `{"error": "Origin not allowed"}`, http.StatusForbidden)
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+	muted := auth.IsMuted(r.Context())
+	var expiry time.Time
+	if token, _, err := jwtauth.FromContext(r.Context()); err == nil && token != nil {
+		expiry = token.Expiration()
+	}
+
+	// The origin was checked against the configured ones above, which are rarely the API's own host
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{InsecureSkipVerify: true})
+	if err != nil {
+		return
+	}
+	conn.SetReadLimit(liveReadLimit)
+	ctx := r.Context()
+
+	sub, _, _ := h.events.Subscribe([]string{events.PostChannel(postID)}, "")
+	defer h.events.Unsubscribe(sub)
+	client := h.live.Join(postID, live.Viewer{ID: user.ID, Username: user.Username})
+	defer h.live.Leave(postID, client)
+
+	// Read the client's messages until it goes away, then stop the writing loop below
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var limiter rateLimiter
+		limiter.tokens = liveMessageBurst
+		for {
+			_, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			if !limiter.allow(time.Now()) {
+				conn.Close(websocket.StatusPolicyViolation, "rate limit exceeded")
+				return
+			}
+
+			var req liveRequest
+			if err := json.Unmarshal(data, &req); err != nil || req.Type != live.MessageTyping {
+				conn.Close(websocket.StatusUnsupportedData, "expected a typing message")
+				return
+			}
+			if !muted {
+				h.live.Typing(postID, client, req.Typing, time.Now())
+			}
+		}
+	}()
+
+	var expired <-chan time.Time
+	if !expiry.IsZero() {
+		timer := time.NewTimer(time.Until(expiry))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ping := time.NewTicker(livePingInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+		select {
+		case <-done:
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+		case <-expired:
+			conn.Close(closeTokenExpired, "token expired")
+			return
+		case event, ok := <-sub.Events():
+			// Either queue being closed means this client fell behind, it can reconnect and reload
+			if !ok {
+				conn.Close(websocket.StatusTryAgainLater, "too slow")
+				return
+			}
+			err = writeLiveMessage(ctx, conn, live.Message{Type: event.Type, ID: event.ID, Data: event.Data})
+		case message, ok := <-client.Messages():
+			if !ok {
+				conn.Close(websocket.StatusTryAgainLater, "too slow")
+				return
+			}
+			err = writeLiveMessage(ctx, conn, message)
+		case <-ping.C:
+			err = pingLive(ctx, conn)
+		}
+		// The client stopped accepting messages or answering pings, so don't wait on a close handshake either
+		if err != nil {
+			conn.CloseNow()
+			return
+		}
+	}
+}
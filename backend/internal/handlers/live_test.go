@@ -0,0 +1,168 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sample-go-app/internal/events"
+	"sample-go-app/internal/live"
+	"sample-go-app/internal/models"
+
+	"github.com/coder/websocket"
+)
+
+// URL of a post's live thread on the test server
+func (c *client) liveURL(postID int) string {
+	return "wss" + strings.TrimPrefix(c.base, "https") + fmt.Sprintf("/api/posts/%d/live", postID)
+}
+
+// Open a post's live thread with the client's cookies, closed when the test ends
+func (c *client) live(postID int) *websocket.Conn {
+	c.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, c.liveURL(postID), &websocket.DialOptions{HTTPClient: c.http})
+	if err != nil {
+		c.t.Fatalf("open live thread of post %d: %v", postID, err)
+	}
+	c.t.Cleanup(func() { conn.CloseNow() })
+	return conn
+}
+
+// Read the next message of a live thread, failing the test unless it has the wanted type, and decode its data into out
+func readLive(t *testing.T, conn *websocket.Conn, kind string, out any) live.Message {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatalf("read %s message: %v", kind, err)
+	}
+	var message live.Message
+	if err := json.Unmarshal(data, &message); err != nil || message.Type != kind {
+		t.Fatalf("got message %s, want a %s message", data, kind)
+	}
+	if err := json.Unmarshal(message.Data, out); err != nil {
+		t.Fatalf("decode %s message: %v: %s", kind, err, message.Data)
+	}
+	return message
+}
+
+// Fail the test unless the live thread's viewers are the wanted users, in order
+func expectViewers(t *testing.T, conn *websocket.Conn, want ...*client) {
+	t.Helper()
+	var viewers []live.Viewer
+	readLive(t, conn, live.MessagePresence, &viewers)
+	ids := []int{}
+	for _, viewer := range viewers {
+		ids = append(ids, viewer.ID)
+	}
+	wantIDs := []int{}
+	for _, c := range want {
+		wantIDs = append(wantIDs, c.userID)
+	}
+	if !reflect.DeepEqual(ids, wantIDs) {
+		t.Fatalf("got viewers %+v, want users %v", viewers, wantIDs)
+	}
+}
+
+func sendLive(t *testing.T, conn *websocket.Conn, message string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := conn.Write(ctx, websocket.MessageText, []byte(message)); err != nil {
+		t.Fatalf("send %s: %v", message, err)
+	}
+}
+
+func TestLiveThread(t *testing.T) {
+	s := newTestServer(t)
+	s.topic("Physics", 0)
+	alice := s.user("alice", "")
+	bob := s.user("bob", "")
+	post := alice.post("Hello", "Physics")
+
+	aliceConn := alice.live(post.ID)
+	expectViewers(t, aliceConn, alice)
+	bobConn := bob.live(post.ID)
+	expectViewers(t, aliceConn, alice, bob)
+	expectViewers(t, bobConn, alice, bob)
+
+	// Typing indicators go to the other viewers only
+	sendLive(t, bobConn, `{"type": "typing", "typing": true}`)
+	var typing live.TypingStatus
+	readLive(t, aliceConn, live.MessageTyping, &typing)
+	if typing.User.ID != bob.userID || !typing.Typing {
+		t.Fatalf("got typing status %+v, want bob typing", typing)
+	}
+
+	// The post's events come through to every viewer as they happen
+	var comment models.Comment
+	alice.decode(http.MethodPost, fmt.Sprintf("/api/posts/%d/comments", post.ID), map[string]string{"content": "first"}, http.StatusCreated, &comment)
+	for _, conn := range []*websocket.Conn{aliceConn, bobConn} {
+		var pushed models.Comment
+		if message := readLive(t, conn, events.CommentCreated, &pushed); message.ID == "" || pushed.ID != comment.ID {
+			t.Fatalf("got event %+v about comment %d, want comment %d", message, pushed.ID, comment.ID)
+		}
+	}
+
+	// Anything but a typing indicator ends the connection, and the others see the user leave
+	sendLive(t, bobConn, "hello")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, _, err := bobConn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusUnsupportedData {
+		t.Fatalf("read after an unexpected message: got %v, want a close with status %d", err, websocket.StatusUnsupportedData)
+	}
+	readLive(t, aliceConn, live.MessageTyping, &typing)
+	if typing.User.ID != bob.userID || typing.Typing {
+		t.Fatalf("got typing status %+v, want bob stopped typing", typing)
+	}
+	expectViewers(t, aliceConn, alice)
+}
+
+func TestLiveThreadHandshake(t *testing.T) {
+	s := newTestServer(t)
+	s.topic("Physics", 0)
+	alice := s.user("alice", "")
+	post := alice.post("Hello", "Physics")
+
+	tests := []struct {
+		name   string
+		client *client
+		postID int
+		origin string
+		status int
+	}{
+		{"anonymous", s.anonymous(), post.ID, "", http.StatusUnauthorized},
+		{"missing post", alice, post.ID + 1, "", http.StatusNotFound},
+		// Browsers send cookies with cross-site handshakes, so other sites must be turned away
+		{"other origin", alice, post.ID, "https://evil.example", http.StatusForbidden},
+		{"allowed origin", alice, post.ID, "http://localhost:3000", http.StatusSwitchingProtocols},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			header := http.Header{}
+			if test.origin != "" {
+				header.Set("Origin", test.origin)
+			}
+			conn, res, err := websocket.Dial(ctx, test.client.liveURL(test.postID), &websocket.DialOptions{HTTPClient: test.client.http, HTTPHeader: header})
+			if conn != nil {
+				conn.CloseNow()
+			}
+			if res == nil {
+				t.Fatalf("dial: %v", err)
+			}
+			if res.StatusCode != test.status {
+				t.Fatalf("got status %d, want %d", res.StatusCode, test.status)
+			}
+		})
+	}
+}
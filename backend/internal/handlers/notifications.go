@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/events"
+	"sample-go-app/internal/mention"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+// A page of notifications, along with how many of the user's notifications are unread
+type notificationPage struct {
+	pagination.Page[models.Notification]
+	UnreadCount int `json:"unread_count"`
+}
+
+// Collects the notifications caused by one post or comment, at most one per user
+type notificationBatch struct {
+	template      models.Notification
+	notifications []models.Notification
+	notified      map[int]bool
+}
+
+// Start a batch of notifications about content by the given author, who is never notified of their own content
+func newNotificationBatch(template models.Notification) *notificationBatch {
+	return &notificationBatch{template: template, notified: map[int]bool{template.ActorID: true}}
+}
+
+// Notify a user, unless they already are (replies being added before mentions, so they take precedence)
+// Users that can't be notified (e.g. the author of a deleted comment) have an ID of 0 and are skipped
+func (b *notificationBatch) add(userID int, kind string) {
+	if userID <= 0 || b.notified[userID] {
+		return
+	}
+	b.notified[userID] = true
+	notification := b.template
+	notification.UserID = userID
+	notification.Type = kind
+	b.notifications = append(b.notifications, notification)
+}
+
+// Never notify a user about this batch's post or comment, e.g. because they muted the post
+func (b *notificationBatch) skip(userID int) {
+	b.notified[userID] = true
+}
+
+// Notify the users @mentioned in content, ignoring names that aren't users
+func (h *Handler) addMentions(r *http.Request, batch *notificationBatch, content string) {
+	for _, username := range mention.Mentions(content) {
+		user, err := h.users.GetUserByUsername(r.Context(), username)
+		if err != nil {
+			if !errors.Is(err, store.ErrNotFound) {
+				log.Printf("Failed to look up mentioned user %q: %v", username, err)
+			}
+			continue
+		}
+		batch.add(user.ID, models.NotificationMention)
+	}
+}
+
+// Store a batch of notifications, push them to the recipients' streams and email those who asked for it
+// Failures are logged rather than reported, since the post or comment itself was already created
+func (h *Handler) sendNotifications(r *http.Request, batch *notificationBatch) {
+	if len(batch.notifications) == 0 {
+		return
+	}
+	added, err := h.notifications.AddNotifications(r.Context(), batch.notifications)
+	if err != nil {
+		log.Printf("Failed to send notifications about post %d: %v", batch.template.PostID, err)
+		return
+	}
+	for _, notification := range added {
+		h.events.Publish(events.UserChannel(notification.UserID), events.NotificationCreated, notification)
+	}
+	h.emailNotifications(r, added)
+}
+
+// Notify the author of what a new comment replies to (the post, or the parent comment), anyone it mentions
+// and the post's watchers, leaving out users who muted the post
+func (h *Handler) notifyComment(r *http.Request, comment *models.Comment) {
+	post, err := h.posts.GetPost(r.Context(), comment.PostID)
+	if err != nil {
+		log.Printf("Failed to get post %d: %v", comment.PostID, err)
+	}
+	batch := newNotificationBatch(models.Notification{
+		ActorID:       comment.Author,
+		ActorUsername: comment.Username,
+		PostID:        comment.PostID,
+		PostTitle:     post.Title,
+		CommentID:     comment.ID,
+		CreatedAt:     comment.CreatedAt,
+	})
+	watching, muted, err := h.subscriptions.PostSubscribers(r.Context(), comment.PostID)
+	if err != nil {
+		log.Printf("Failed to get subscribers of post %d: %v", comment.PostID, err)
+	}
+	for _, userID := range muted {
+		batch.skip(userID)
+	}
+
+	if comment.ParentID == 0 {
+		batch.add(post.Author, models.NotificationPostReply)
+	} else {
+		parent, err := h.comments.GetComment(r.Context(), comment.ParentID)
+		if err != nil {
+			log.Printf("Failed to get comment %d: %v", comment.ParentID, err)
+		}
+		batch.add(parent.Author, models.NotificationCommentReply)
+	}
+
+	h.addMentions(r, batch, comment.Content)
+	for _, userID := range watching {
+		batch.add(userID, models.NotificationNewComment)
+	}
+	h.sendNotifications(r, batch)
+}
+
+// Notify the users a new post mentions, and those watching its topic (or a topic above it)
+func (h *Handler) notifyPost(r *http.Request, post *models.Post) {
+	batch := newNotificationBatch(models.Notification{
+		ActorID:       post.Author,
+		ActorUsername: post.Username,
+		PostID:        post.ID,
+		PostTitle:     post.Title,
+		CreatedAt:     post.CreatedAt,
+	})
+	h.addMentions(r, batch, post.Title+"\n"+post.Content)
+	watchers, err := h.subscriptions.TopicSubscribers(r.Context(), post.TopicID)
+	if err != nil {
+		log.Printf("Failed to get subscribers of topic %d: %v", post.TopicID, err)
+	}
+	for _, userID := range watchers {
+		batch.add(userID, models.NotificationNewPost)
+	}
+	h.sendNotifications(r, batch)
+}
+
+// Write notifications (or notification counts / preferences) as a JSON response
+func writeNotifications(w http.ResponseWriter, status int, notifications any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(notifications); err != nil {
+		http.Error(w, `{"error": "Failed to encode notifications"}`, http.StatusInternalServerError)
+	}
+}
+
+// Get a page of the current user's notifications, with their unread count
+// Supports ?unread=true and the paging parameters ?limit=, ?cursor= and ?sort= (newest, oldest)
+func (h *Handler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+
+	unreadOnly := false
+	if unread := r.URL.Query().Get("unread"); unread != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(unread); err != nil {
+			http.Error(w, `{"error": "Invalid unread flag"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	params, ok := pageParams(w, r, store.NotificationSorts)
+	if !ok {
+		return
+	}
+
+	notifications, err := h.notifications.ListNotifications(r.Context(), user.ID, unreadOnly, params.Probe())
+	if err != nil {
+		if !writePaginationError(w, err) {
+			http.Error(w, `{"error": "Failed to fetch notifications"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	unreadCount, err := h.notifications.CountUnreadNotifications(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch notifications"}`, http.StatusInternalServerError)
+		return
+	}
+
+	page := notificationPage{
+		Page: pagination.NewPage(notifications, params, func(n models.Notification) pagination.Cursor {
+			return store.NotificationCursor(params.Sort, n)
+		}),
+		UnreadCount: unreadCount,
+	}
+	writeNotifications(w, http.StatusOK, page)
+}
+
+// Get how many of the current user's notifications are unread
+func (h *Handler) GetUnreadNotificationCount(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	count, err := h.notifications.CountUnreadNotifications(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch notifications"}`, http.StatusInternalServerError)
+		return
+	}
+	writeNotifications(w, http.StatusOK, map[string]int{"unread_count": count})
+}
+
+// Mark one of the current user's notifications read
+func (h *Handler) MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r, "notification_id")
+	if !ok {
+		http.Error(w, `{"error": "Notification not found"}`, http.StatusNotFound)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.notifications.MarkNotificationRead(r.Context(), user.ID, id, time.Now().UTC()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Notification not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to mark notification read"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// Mark every notification of the current user read
+func (h *Handler) MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	marked, err := h.notifications.MarkAllNotificationsRead(r.Context(), user.ID, time.Now().UTC())
+	if err != nil {
+		http.Error(w, `{"error": "Failed to mark notifications read"}`, http.StatusInternalServerError)
+		return
+	}
+	writeNotifications(w, http.StatusOK, map[string]int{"marked": marked})
+}
+
+// Get which types of notification the current user receives
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	preferences, err := h.notifications.NotificationPreferences(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch notification preferences"}`, http.StatusInternalServerError)
+		return
+	}
+	writeNotifications(w, http.StatusOK, preferences)
+}
+
+// Turn types of notification on or off for the current user, e.g. {"mention": false}
+// Types left out of the request keep their current setting
+func (h *Handler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	var preferences map[string]bool
+	if err := json.NewDecoder(r.Body).Decode(&preferences); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	for kind := range preferences {
+		if !slices.Contains(models.NotificationTypes, kind) {
+			http.Error(w, `{"error": "Unknown notification type"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.notifications.SetNotificationPreferences(r.Context(), user.ID, preferences); err != nil {
+		http.Error(w, `{"error": "Failed to update notification preferences"}`, http.StatusInternalServerError)
+		return
+	}
+	h.GetNotificationPreferences(w, r)
+}
@@ -1,95 +1,121 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
-	db "sample-go-app/internal/database"
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/events"
 	"sample-go-app/internal/models"
-
-	"github.com/go-chi/chi/v5"
-	_ "modernc.org/sqlite"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
 )
 
-// Gets all posts in the database
-func GetAllPosts(w http.ResponseWriter, r *http.Request) {
-	// Query the database for posts
-	rows, err := db.DB.Query(`
-		SELECT p.id, p.title, p.topic, p.user_id, COALESCE(u.username, 'Unknown') AS username, p.created_at
-		FROM posts p
-		LEFT JOIN users u ON p.user_id = u.id
-		ORDER BY p.created_at DESC
-	`)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to fetch posts"}`, http.StatusInternalServerError)
+// Gets a page of all posts in the database
+// Supports ?limit=, ?cursor= and ?sort= (newest, oldest, most_commented, activity, hot,
+// top, top_day, top_week, top_month, top_year)
+func (h *Handler) GetAllPosts(w http.ResponseWriter, r *http.Request) {
+	params, ok := pageParams(w, r, store.PostSorts)
+	if !ok {
 		return
 	}
-	defer rows.Close()
 
-	// Read the posts data into an array of posts
-	posts := []models.Post{}
-	for rows.Next() {
-		var post models.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Topic, &post.Author, &post.Username, &post.CreatedAt); err != nil {
-			http.Error(w, `{"error": "Failed to parse post data"}`, http.StatusInternalServerError)
-			return
+	posts, err := h.posts.ListPosts(r.Context(), params.Probe())
+	if err != nil {
+		if !writePaginationError(w, err) {
+			http.Error(w, `{"error": "Failed to fetch posts"}`, http.StatusInternalServerError)
 		}
-		posts = append(posts, post)
+		return
+	}
+	page := pagination.NewPage(posts, params, func(p models.Post) pagination.Cursor { return store.PostCursor(params.Sort, p) })
+	if err := h.fillPostVotes(r.Context(), page.Items); err != nil {
+		http.Error(w, `{"error": "Failed to fetch votes"}`, http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(posts); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		http.Error(w, `{"error": "Failed to encode posts"}`, http.StatusInternalServerError)
 		return
 	}
 }
 
-// Get all the posts associated with a relevant topic
-func GetPostsByTopic(w http.ResponseWriter, r *http.Request) {
-	// Extract the topic from the route parameter
-	topic := chi.URLParam(r, "topic")
-
-	// Query the database for posts matching the topic
-	rows, err := db.DB.Query(`
-		SELECT p.id, p.title, p.topic, p.user_id, COALESCE(u.username, 'Unknown') AS username, p.created_at
-		FROM posts p
-		LEFT JOIN users u ON p.user_id = u.id
-		WHERE p.topic = ?
-		ORDER BY p.created_at DESC
-	`, topic)
-	if err != nil {
-		fmt.Println(err)
-		http.Error(w, `{"error": "Failed to fetch posts"}`, http.StatusInternalServerError)
+// Get a page of the posts associated with a relevant topic
+// Supports the same paging parameters as GetAllPosts, and ?subtopics=true to include posts from all subtopics
+func (h *Handler) GetPostsByTopic(w http.ResponseWriter, r *http.Request) {
+	// Look up the topic by the slug, name or ID in the route parameter
+	topic, ok := h.topicParam(w, r)
+	if !ok {
 		return
 	}
-	defer rows.Close()
 
-	// Read the data into an array of posts
-	posts := []models.Post{}
-	for rows.Next() {
-		var post models.Post
-		if err := rows.Scan(&post.ID, &post.Title, &post.Topic, &post.Author, &post.Username, &post.CreatedAt); err != nil {
-			http.Error(w, `{"error": "Failed to parse post data"}`, http.StatusInternalServerError)
+	subtopics := false
+	if raw := r.URL.Query().Get("subtopics"); raw != "" {
+		var err error
+		if subtopics, err = strconv.ParseBool(raw); err != nil {
+			http.Error(w, `{"error": "Invalid subtopics flag"}`, http.StatusBadRequest)
 			return
 		}
-		posts = append(posts, post)
+	}
+
+	params, ok := pageParams(w, r, store.PostSorts)
+	if !ok {
+		return
+	}
+
+	posts, err := h.posts.ListPostsByTopic(r.Context(), topic.ID, subtopics, params.Probe())
+	if err != nil {
+		if !writePaginationError(w, err) {
+			http.Error(w, `{"error": "Failed to fetch posts"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	page := pagination.NewPage(posts, params, func(p models.Post) pagination.Cursor { return store.PostCursor(params.Sort, p) })
+	if err := h.fillPostVotes(r.Context(), page.Items); err != nil {
+		http.Error(w, `{"error": "Failed to fetch votes"}`, http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(posts); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		http.Error(w, `{"error": "Failed to encode posts"}`, http.StatusInternalServerError)
 		return
 	}
 }
 
+// Set the topic of a post from its topic_id, or else its topic (a slug or name), writing a 400 if it doesn't exist
+func (h *Handler) resolvePostTopic(w http.ResponseWriter, r *http.Request, post *models.Post) bool {
+	var topic models.Topic
+	var err error
+	switch {
+	case post.TopicID != 0:
+		topic, err = h.topics.GetTopic(r.Context(), post.TopicID)
+	case post.Topic != "":
+		topic, err = h.topics.FindTopic(r.Context(), post.Topic)
+	default:
+		http.Error(w, `{"error": "Post title, topic and content are required"}`, http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Unknown topic"}`, http.StatusBadRequest)
+		} else {
+			http.Error(w, `{"error": "Failed to get topic"}`, http.StatusInternalServerError)
+		}
+		return false
+	}
+	post.TopicID = topic.ID
+	post.Topic = topic.TopicName
+	return true
+}
+
 // Add a new post
-func AddPost(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddPost(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body into a Post
 	post := &models.Post{}
 	if err := json.NewDecoder(r.Body).Decode(post); err != nil {
@@ -97,22 +123,36 @@ func AddPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Stamp the author from the JWT, rather than trusting the request body
+	user, ok := authorFromRequest(w, r, post.Author)
+	if !ok {
+		return
+	}
+	post.Author = user.ID
+	post.Username = user.Username
+
 	post.CreatedAt = time.Now().UTC()
 	if post.Content == "" || post.Title == "" {
-		http.Error(w, `{"error": "Post title and content are required"}`, http.StatusBadRequest)
+		http.Error(w, `{"error": "Post title, topic and content are required"}`, http.StatusBadRequest)
+		return
+	}
+	if !h.resolvePostTopic(w, r, post) {
 		return
 	}
 
-	// Insert post into DB
-	res, err := db.DB.Exec("INSERT INTO posts (title, topic, content, user_id, created_at) VALUES (?, ?, ?, ?, ?)", post.Title, post.Topic, post.Content, post.Author, post.CreatedAt)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to create post"}`, http.StatusInternalServerError)
+	// Check the user may post in the topic
+	if !h.authorize(w, r, models.PermPostCreate, auth.Resource{OwnerID: user.ID, TopicID: post.TopicID}) {
 		return
 	}
 
-	// Retrieve the last inserted ID
-	id, _ := res.LastInsertId()
-	post.ID = int(id)
+	// Insert post into the store
+	if err := h.posts.CreatePost(r.Context(), post); err != nil {
+		http.Error(w, `{"error": "Failed to create post"}`, http.StatusInternalServerError)
+		return
+	}
+	h.events.Publish(events.TopicChannel(post.TopicID), events.PostCreated, post)
+	h.notifyPost(r, post)
+	h.autoSubscribe(r, post.Author, post.ID, false)
 
 	// Return the created post as JSON
 	w.Header().Set("Content-Type", "application/json")
@@ -123,27 +163,33 @@ func AddPost(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get the details of a specific post
-func GetPostDetails(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetPostDetails(w http.ResponseWriter, r *http.Request) {
 	// Extract the post ID from the route parameter
-	id := chi.URLParam(r, "post_id")
-
-	row := db.DB.QueryRow(`
-		SELECT p.id, p.title, p.topic, p.content, p.user_id, COALESCE(u.username, 'Unknown') AS username, p.created_at
-		FROM posts p
-		LEFT JOIN users u ON p.user_id = u.id
-		WHERE p.id = ?
-	`, id)
-
-	// Scan the result into a post
-	post := models.Post{}
-	if err := row.Scan(&post.ID, &post.Title, &post.Topic, &post.Content, &post.Author, &post.Username, &post.CreatedAt); err != nil {
-		if err == sql.ErrNoRows {
+	id, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	post, err := h.posts.GetPost(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
 		} else {
 			http.Error(w, `{"error": "Failed to get post"}`, http.StatusInternalServerError)
 		}
 		return
 	}
+	posts := []models.Post{post}
+	if err := h.fillPostVotes(r.Context(), posts); err != nil {
+		http.Error(w, `{"error": "Failed to fetch votes"}`, http.StatusInternalServerError)
+		return
+	}
+	post = posts[0]
+	if err := h.fillPostSubscription(r.Context(), &post); err != nil {
+		http.Error(w, `{"error": "Failed to fetch subscription"}`, http.StatusInternalServerError)
+		return
+	}
 
 	// Set the response headers and return the post as JSON
 	w.Header().Set("Content-Type", "application/json")
@@ -155,9 +201,13 @@ func GetPostDetails(w http.ResponseWriter, r *http.Request) {
 }
 
 // Update an existing post
-func UpdatePost(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdatePost(w http.ResponseWriter, r *http.Request) {
 	// Extract the post ID from the route parameter
-	id := chi.URLParam(r, "post_id")
+	id, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
 
 	// Parse the request body into a Post model
 	post := &models.Post{}
@@ -171,20 +221,42 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error": "Post title, topic and content are required"}`, http.StatusBadRequest)
 		return
 	}
-
-	// Update the post in the database
-	res, err := db.DB.Exec("UPDATE posts SET title = ?, topic = ?, content = ? WHERE id = ?", post.Title, post.Topic, post.Content, id)
-	if err != nil || res == nil {
-		http.Error(w, `{"error": "Failed to update post"}`, http.StatusInternalServerError)
+	if !h.resolvePostTopic(w, r, post) {
 		return
 	}
 
-	// Check if the post was found and updated
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
+	// Moving the post to another topic needs the edit permission there too, so topic moderators can't move posts out of reach
+	resource, err := h.PostResource(r)
+	if err != nil {
 		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
 		return
 	}
+	if post.TopicID != resource.TopicID && !h.authorize(w, r, models.PermPostEdit, auth.Resource{OwnerID: resource.OwnerID, TopicID: post.TopicID}) {
+		return
+	}
+
+	// Moderators editing someone else's post are audited
+	override := auth.IsPrivilegedAccess(r.Context())
+	var before any
+	if override {
+		before = h.postSnapshot(r, id)
+	}
+
+	// Update the post in the store, recording the editor
+	post.ID = id
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.posts.UpdatePost(r.Context(), *post, user.ID, time.Now().UTC()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to update post"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	if override {
+		h.audit(r, models.AuditPostUpdate, models.AuditTargetPost, strconv.Itoa(id), before, h.postSnapshot(r, id))
+	}
+	h.publishPost(r, events.PostUpdated, id)
 
 	// Send a success response
 	w.Header().Set("Content-Type", "application/json")
@@ -192,48 +264,61 @@ func UpdatePost(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"success": true}`))
 }
 
-// Delete an existing post and its comments
-func DeletePost(w http.ResponseWriter, r *http.Request) {
+// Soft delete an existing post, hiding it and its comments until restored or purged
+func (h *Handler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	// Extract the post ID from the route parameter
-	id := chi.URLParam(r, "post_id")
-
-	// Start a transaction
-	tx, err := db.DB.Begin()
-	if err != nil {
-		http.Error(w, `{"error": "Failed to start transaction"}`, http.StatusInternalServerError)
+	id, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
 		return
 	}
+	user, _ := auth.UserFromContext(r.Context())
 
-	// Defer a rollback in case anything goes wrong
-	defer tx.Rollback()
-
-	// Step 1: Delete comments associated with the post
-	_, err = tx.Exec("DELETE FROM comments WHERE post_id = ?", id)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to delete comments"}`, http.StatusInternalServerError)
-		return
+	// Moderators deleting someone else's post are audited
+	override := auth.IsPrivilegedAccess(r.Context())
+	var before any
+	if override {
+		before = h.postSnapshot(r, id)
 	}
 
-	// Step 2: Delete the post itself
-	res, err := tx.Exec("DELETE FROM posts WHERE id = ?", id)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to delete post"}`, http.StatusInternalServerError)
+	if err := h.posts.DeletePost(r.Context(), id, user.ID, time.Now().UTC()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to delete post"}`, http.StatusInternalServerError)
+		}
 		return
 	}
+	if override {
+		h.audit(r, models.AuditPostDelete, models.AuditTargetPost, strconv.Itoa(id), before, nil)
+	}
+	h.publishPost(r, events.PostDeleted, id)
 
-	// Check if any rows were affected
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// Restore a soft deleted post (needs PermContentRestore in the post's topic)
+func (h *Handler) RestorePost(w http.ResponseWriter, r *http.Request) {
+	// Extract the post ID from the route parameter
+	id, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Deleted post not found"}`, http.StatusNotFound)
 		return
 	}
 
-	// Commit the transaction
-	err = tx.Commit()
-	if err != nil {
-		http.Error(w, `{"error": "Failed to commit transaction"}`, http.StatusInternalServerError)
+	if err := h.posts.RestorePost(r.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Deleted post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to restore post"}`, http.StatusInternalServerError)
+		}
 		return
 	}
+	h.audit(r, models.AuditPostRestore, models.AuditTargetPost, strconv.Itoa(id), nil, h.postSnapshot(r, id))
+	h.publishPost(r, events.PostRestored, id)
 
 	// Send a success response
 	w.Header().Set("Content-Type", "application/json")
@@ -241,34 +326,42 @@ func DeletePost(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"success": true}`))
 }
 
-// Get all the top-level comments associated with a post
-func GetPostComments(w http.ResponseWriter, r *http.Request) {
+// Get a page of the top-level comments associated with a post
+// Supports ?limit=, ?cursor= and ?sort= (newest, oldest)
+func (h *Handler) GetPostComments(w http.ResponseWriter, r *http.Request) {
 	// Extract the post ID from the route parameter
-	post_id := chi.URLParam(r, "post_id")
-
-	// Query the database for comments associated with the post, where parent_id is NULL (top-level comments)
-	rows, err := db.DB.Query(`
-		SELECT c.id, c.post_id, c.user_id, COALESCE(u.username, 'Unknown') AS username, c.content, c.created_at
-		FROM comments c
-		LEFT JOIN users u ON c.user_id = u.id
-		WHERE c.post_id = ? AND c.parent_id IS NULL
-		ORDER BY c.created_at DESC
-	`, post_id)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to fetch comments"}`, http.StatusInternalServerError)
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
 		return
 	}
-	defer rows.Close()
 
-	// Create a slice to hold the comments
-	comments := []models.Comment{}
-	for rows.Next() {
-		var comment models.Comment
-		if err := rows.Scan(&comment.ID, &comment.PostID, &comment.Author, &comment.Username, &comment.Content, &comment.CreatedAt); err != nil {
-			http.Error(w, `{"error": "Failed to parse comment data"}`, http.StatusInternalServerError)
-			return
+	params, ok := pageParams(w, r, store.CommentSorts)
+	if !ok {
+		return
+	}
+
+	// Comments are hidden along with their post, so only list them for visible posts
+	if _, err := h.posts.GetPost(r.Context(), postID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to get post"}`, http.StatusInternalServerError)
 		}
-		comments = append(comments, comment)
+		return
+	}
+
+	comments, err := h.comments.ListPostComments(r.Context(), postID, params.Probe())
+	if err != nil {
+		if !writePaginationError(w, err) {
+			http.Error(w, `{"error": "Failed to fetch comments"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	page := pagination.NewPage(comments, params, func(c models.Comment) pagination.Cursor { return store.CommentCursor(params.Sort, c) })
+	if err := h.fillCommentVotes(r.Context(), page.Items); err != nil {
+		http.Error(w, `{"error": "Failed to fetch votes"}`, http.StatusInternalServerError)
+		return
 	}
 
 	// Set the response content type to JSON
@@ -276,16 +369,20 @@ func GetPostComments(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
 	// Return the comments as a JSON response
-	if err := json.NewEncoder(w).Encode(comments); err != nil {
+	if err := json.NewEncoder(w).Encode(page); err != nil {
 		http.Error(w, `{"error": "Failed to encode comments"}`, http.StatusInternalServerError)
 		return
 	}
 }
 
 // Add a new top-level comment to the post
-func AddPostComment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddPostComment(w http.ResponseWriter, r *http.Request) {
 	// Extract the post ID from the route parameter
-	post_id := chi.URLParam(r, "post_id")
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
 
 	// Parse the request body into a Comment model
 	comment := &models.Comment{}
@@ -294,23 +391,34 @@ func AddPostComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Stamp the author from the JWT, rather than trusting the request body
+	user, ok := authorFromRequest(w, r, comment.Author)
+	if !ok {
+		return
+	}
+	comment.Author = user.ID
+	comment.Username = user.Username
+
 	if comment.Content == "" {
 		http.Error(w, `{"error": "Comment content is required"}`, http.StatusBadRequest)
 		return
 	}
 
+	// Insert the comment as a top-level comment (no parent)
+	comment.PostID = postID
+	comment.ParentID = 0
 	comment.CreatedAt = time.Now().UTC()
-	// Insert the comment into the database as a top-level comment (parent_id is NULL)
-	res, err := db.DB.Exec("INSERT INTO comments (post_id, user_id, content, created_at, parent_id) VALUES (?, ?, ?, ?, NULL)", post_id, comment.Author, comment.Content, comment.CreatedAt)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to create comment"}`, http.StatusInternalServerError)
+	if err := h.comments.CreateComment(r.Context(), comment); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to create comment"}`, http.StatusInternalServerError)
+		}
 		return
 	}
-
-	// Retrieve the last inserted ID for the comment
-	id, _ := res.LastInsertId()
-	comment.ID = int(id)
-	comment.PostID, _ = strconv.Atoi(post_id)
+	h.events.Publish(events.PostChannel(comment.PostID), events.CommentCreated, comment)
+	h.notifyComment(r, comment)
+	h.autoSubscribe(r, comment.Author, comment.PostID, true)
 
 	// Return the created comment as JSON
 	w.Header().Set("Content-Type", "application/json")
@@ -320,18 +428,14 @@ func AddPostComment(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Get the owner's ID of a post
-func GetPostOwnerID(r *http.Request) (int, error) {
+// Get the owner and topic of a post, for permission checks
+func (h *Handler) PostResource(r *http.Request) (auth.Resource, error) {
 	// Extract the post ID from the route parameter
-	id := chi.URLParam(r, "post_id")
-
-	var ownerID int
-	// Query the database for the user_id associated with the given postID
-	err := db.DB.QueryRow("SELECT user_id FROM posts WHERE id = ?", id).Scan(&ownerID)
-	if err != nil {
-		return -1, err
+	id, ok := idParam(r, "post_id")
+	if !ok {
+		return auth.Resource{}, store.ErrNotFound
 	}
 
-	// Return the owner ID if no errors occurred
-	return ownerID, nil
+	ownerID, topicID, err := h.posts.GetPostOwner(r.Context(), id)
+	return auth.Resource{OwnerID: ownerID, TopicID: topicID}, err
 }
@@ -0,0 +1,84 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+)
+
+func TestPostLifecycle(t *testing.T) {
+	s := newTestServer(t)
+	s.topic("Physics", 0)
+	alice := s.user("alice", "")
+	bob := s.user("bob", "")
+	admin := s.user("admin", models.RoleAdmin)
+
+	post := alice.post("Hello", "Physics")
+	if post.ID == 0 || post.Author != alice.userID || post.Topic != "Physics" {
+		t.Fatalf("created post = %+v", post)
+	}
+	s.anonymous().expect(http.MethodPost, "/api/posts", map[string]string{"title": "x", "content": "x", "topic": "Physics"},
+		http.StatusUnauthorized)
+	alice.expect(http.MethodPost, "/api/posts", map[string]string{"title": "x", "content": "x", "topic": "Nowhere"},
+		http.StatusBadRequest)
+
+	// Only the author edits their post, keeping the old version as a revision
+	path := fmt.Sprintf("/api/posts/%d", post.ID)
+	edit := map[string]string{"title": "Hello again", "content": "Edited", "topic": "Physics"}
+	bob.expect(http.MethodPatch, path, edit, http.StatusForbidden)
+	alice.expect(http.MethodPatch, path, edit, http.StatusOK)
+	var revisions []models.Revision
+	s.anonymous().decode(http.MethodGet, path+"/revisions", nil, http.StatusOK, &revisions)
+	if len(revisions) != 2 {
+		t.Fatalf("got %d revisions, want 2", len(revisions))
+	}
+
+	// Deleted posts disappear until a moderator restores them
+	bob.expect(http.MethodDelete, path, nil, http.StatusForbidden)
+	alice.expect(http.MethodDelete, path, nil, http.StatusOK)
+	s.anonymous().expect(http.MethodGet, path, nil, http.StatusNotFound)
+	alice.expect(http.MethodPost, path+"/restore", nil, http.StatusForbidden)
+	admin.expect(http.MethodPost, path+"/restore", nil, http.StatusOK)
+	var restored models.Post
+	s.anonymous().decode(http.MethodGet, path, nil, http.StatusOK, &restored)
+	if restored.Title != "Hello again" {
+		t.Errorf("restored title = %q, want %q", restored.Title, "Hello again")
+	}
+}
+
+func TestListPostsPages(t *testing.T) {
+	s := newTestServer(t)
+	s.topic("Physics", 0)
+	alice := s.user("alice", "")
+
+	var want []int
+	for i := range 5 {
+		want = append(want, alice.post(fmt.Sprintf("Post %d", i), "Physics").ID)
+	}
+	slices.Reverse(want)
+
+	// Walk the pages one post at a time, newest first
+	var got []int
+	query := url.Values{"limit": {"1"}}
+	for range len(want) + 1 {
+		var page pagination.Page[models.Post]
+		s.anonymous().decode(http.MethodGet, "/api/posts?"+query.Encode(), nil, http.StatusOK, &page)
+		for _, post := range page.Items {
+			got = append(got, post.ID)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		query.Set("cursor", *page.NextCursor)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("paged post IDs = %v, want %v", got, want)
+	}
+
+	s.anonymous().expect(http.MethodGet, "/api/posts?cursor=garbage", nil, http.StatusBadRequest)
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+// Longest free-text explanation a report can carry, in characters
+const maxReportDetails = 1000
+
+// Body of a request resolving a report
+type resolveReportRequest struct {
+	Action string `json:"action"`
+	Note   string `json:"note"`
+	// How long to suspend the author for, as a Go duration (e.g. "72h"), required for the suspend action
+	SuspendFor string `json:"suspend_for"`
+}
+
+// Write a report as a JSON response
+func writeReport(w http.ResponseWriter, status int, report models.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, `{"error": "Failed to encode report"}`, http.StatusInternalServerError)
+	}
+}
+
+// Report a post, comment or user to the admins
+func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	var report models.Report
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if report.TargetType != models.ReportPost && report.TargetType != models.ReportComment && report.TargetType != models.ReportUser {
+		http.Error(w, `{"error": "Invalid target type"}`, http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(models.ReportReasons, report.Reason) {
+		http.Error(w, `{"error": "Invalid reason"}`, http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(report.Details) > maxReportDetails {
+		http.Error(w, `{"error": "Details are too long"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	report.ReporterID = user.ID
+	report.ReporterUsername = user.Username
+	report.CreatedAt = time.Now().UTC()
+	report.ResolvedAt, report.ResolvedBy, report.Action, report.Note = nil, 0, "", ""
+
+	if err := h.reports.CreateReport(r.Context(), &report); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, `{"error": "Reported content not found"}`, http.StatusNotFound)
+		case errors.Is(err, store.ErrConflict):
+			http.Error(w, `{"error": "You have already reported this"}`, http.StatusConflict)
+		default:
+			http.Error(w, `{"error": "Failed to create report"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	writeReport(w, http.StatusCreated, report)
+}
+
+// Get a page of the moderation queue (needs PermReportManage)
+// Supports ?status= (open by default, or resolved, dismissed, all), ?target_type=, ?reason=, ?target_user=
+// and the paging parameters ?limit=, ?cursor= and ?sort= (oldest, newest)
+func (h *Handler) ListReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := store.ReportFilter{
+		Status:     query.Get("status"),
+		TargetType: query.Get("target_type"),
+		Reason:     query.Get("reason"),
+	}
+	switch filter.Status {
+	case "":
+		filter.Status = models.ReportOpen
+	case "all":
+		filter.Status = ""
+	case models.ReportOpen, models.ReportResolved, models.ReportDismissed:
+	default:
+		http.Error(w, `{"error": "Invalid status"}`, http.StatusBadRequest)
+		return
+	}
+	if value := query.Get("target_user"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			http.Error(w, `{"error": "Invalid target user"}`, http.StatusBadRequest)
+			return
+		}
+		filter.TargetUserID = id
+	}
+
+	params, ok := pageParams(w, r, store.ReportSorts)
+	if !ok {
+		return
+	}
+
+	reports, err := h.reports.ListReports(r.Context(), filter, params.Probe())
+	if err != nil {
+		if !writePaginationError(w, err) {
+			http.Error(w, `{"error": "Failed to fetch reports"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	page := pagination.NewPage(reports, params, func(report models.Report) pagination.Cursor {
+		return store.ReportCursor(params.Sort, report)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		http.Error(w, `{"error": "Failed to encode reports"}`, http.StatusInternalServerError)
+	}
+}
+
+// Get a single report (needs PermReportManage)
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	reportID, ok := idParam(r, "report_id")
+	if !ok {
+		http.Error(w, `{"error": "Report not found"}`, http.StatusNotFound)
+		return
+	}
+
+	report, err := h.reports.GetReport(r.Context(), reportID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Report not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to fetch report"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	writeReport(w, http.StatusOK, report)
+}
+
+// Settle a report (needs PermReportManage), applying the chosen action and closing every open report on the same target
+func (h *Handler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	reportID, ok := idParam(r, "report_id")
+	if !ok {
+		http.Error(w, `{"error": "Report not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var req resolveReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.reports.GetReport(r.Context(), reportID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Report not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to fetch report"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	resolution := store.Resolution{Action: req.Action, Note: req.Note, ResolvedBy: user.ID, At: time.Now().UTC()}
+	switch req.Action {
+	case models.ActionDismiss, models.ActionWarn:
+	case models.ActionHide, models.ActionDelete:
+		// Only content can be hidden or deleted
+		if report.TargetType == models.ReportUser {
+			http.Error(w, `{"error": "Users cannot be hidden or deleted"}`, http.StatusBadRequest)
+			return
+		}
+	case models.ActionSuspend:
+		duration, err := time.ParseDuration(req.SuspendFor)
+		if err != nil || duration <= 0 {
+			http.Error(w, `{"error": "Invalid suspension length"}`, http.StatusBadRequest)
+			return
+		}
+		resolution.SuspendUntil = resolution.At.Add(duration)
+	default:
+		http.Error(w, `{"error": "Invalid action"}`, http.StatusBadRequest)
+		return
+	}
+	// Warnings and suspensions sanction the reported user
+	if (req.Action == models.ActionWarn || req.Action == models.ActionSuspend) && !h.checkOutranks(w, r, report.TargetUserID) {
+		return
+	}
+
+	resolved, err := h.reports.ResolveReport(r.Context(), reportID, resolution)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrConflict):
+			http.Error(w, `{"error": "Report is already closed"}`, http.StatusConflict)
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, `{"error": "Reported content not found"}`, http.StatusNotFound)
+		default:
+			http.Error(w, `{"error": "Failed to resolve report"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	h.audit(r, models.AuditReportResolve, models.AuditTargetReport, strconv.Itoa(reportID), report, resolved)
+	writeReport(w, http.StatusOK, resolved)
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/diff"
+	"sample-go-app/internal/events"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Line-level differences between two versions of a post or comment
+type revisionDiff struct {
+	From    int         `json:"from"`
+	To      int         `json:"to"`
+	Title   []diff.Line `json:"title,omitempty"`
+	Topic   []diff.Line `json:"topic,omitempty"`
+	Content []diff.Line `json:"content"`
+}
+
+// Write the error response for a failure to list the versions of a post or comment
+func writeRevisionsError(w http.ResponseWriter, err error, notFound string) {
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, `{"error": "`+notFound+`"}`, http.StatusNotFound)
+	} else {
+		http.Error(w, `{"error": "Failed to fetch revisions"}`, http.StatusInternalServerError)
+	}
+}
+
+// Get the revision with the given number from a query or route value, writing an error response if there is none
+// An empty value selects fallback instead
+func pickRevision(w http.ResponseWriter, revisions []models.Revision, value string, fallback int) (models.Revision, bool) {
+	number := fallback
+	if value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, `{"error": "Invalid revision"}`, http.StatusBadRequest)
+			return models.Revision{}, false
+		}
+		number = n
+	}
+	if number < 1 || number > len(revisions) {
+		http.Error(w, `{"error": "Revision not found"}`, http.StatusNotFound)
+		return models.Revision{}, false
+	}
+	return revisions[number-1], true
+}
+
+// Write the diff between the ?from and ?to revisions, by default the previous and the current one
+func writeRevisionDiff(w http.ResponseWriter, r *http.Request, revisions []models.Revision, withTitle bool) {
+	current := len(revisions)
+	to, ok := pickRevision(w, revisions, r.URL.Query().Get("to"), current)
+	if !ok {
+		return
+	}
+	from, ok := pickRevision(w, revisions, r.URL.Query().Get("from"), max(to.Revision-1, 1))
+	if !ok {
+		return
+	}
+
+	result := revisionDiff{From: from.Revision, To: to.Revision, Content: diff.Lines(from.Content, to.Content)}
+	if withTitle {
+		result.Title = diff.Lines(from.Title, to.Title)
+		result.Topic = diff.Lines(from.Topic, to.Topic)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, `{"error": "Failed to encode diff"}`, http.StatusInternalServerError)
+	}
+}
+
+// Write the versions of a post or comment as a JSON response
+func writeRevisions(w http.ResponseWriter, revisions []models.Revision) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(revisions); err != nil {
+		http.Error(w, `{"error": "Failed to encode revisions"}`, http.StatusInternalServerError)
+	}
+}
+
+// Get the revision a rollback targets, writing an error response if it is missing or already current
+func rollbackTarget(w http.ResponseWriter, r *http.Request, revisions []models.Revision) (models.Revision, bool) {
+	revision, ok := pickRevision(w, revisions, chi.URLParam(r, "revision"), 0)
+	if !ok {
+		return models.Revision{}, false
+	}
+	if revision.Current {
+		http.Error(w, `{"error": "Revision is already current"}`, http.StatusBadRequest)
+		return models.Revision{}, false
+	}
+	return revision, true
+}
+
+// List every version of a post, oldest first
+func (h *Handler) GetPostRevisions(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	revisions, err := h.revisions.ListPostRevisions(r.Context(), postID)
+	if err != nil {
+		writeRevisionsError(w, err, "Post not found")
+		return
+	}
+	writeRevisions(w, revisions)
+}
+
+// Compare two versions of a post line by line
+func (h *Handler) DiffPostRevisions(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	revisions, err := h.revisions.ListPostRevisions(r.Context(), postID)
+	if err != nil {
+		writeRevisionsError(w, err, "Post not found")
+		return
+	}
+	writeRevisionDiff(w, r, revisions, true)
+}
+
+// Restore an earlier version of a post (needs PermContentRollback in the post's topic)
+// The rollback is recorded as a new edit, so the history is never rewritten
+func (h *Handler) RollbackPost(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	revisions, err := h.revisions.ListPostRevisions(r.Context(), postID)
+	if err != nil {
+		writeRevisionsError(w, err, "Post not found")
+		return
+	}
+	revision, ok := rollbackTarget(w, r, revisions)
+	if !ok {
+		return
+	}
+
+	// Revisions of a post in a since deleted topic have nowhere to go back to
+	if revision.TopicID == 0 {
+		http.Error(w, `{"error": "The topic of this revision no longer exists"}`, http.StatusConflict)
+		return
+	}
+
+	before := h.postSnapshot(r, postID)
+	user, _ := auth.UserFromContext(r.Context())
+	post := models.Post{ID: postID, Title: revision.Title, TopicID: revision.TopicID, Content: revision.Content}
+	if err := h.posts.UpdatePost(r.Context(), post, user.ID, time.Now().UTC()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to roll back post"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	h.audit(r, models.AuditPostRollback, models.AuditTargetPost, strconv.Itoa(postID), before, h.postSnapshot(r, postID))
+	h.publishPost(r, events.PostUpdated, postID)
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// List every version of a comment, oldest first
+func (h *Handler) GetCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	revisions, err := h.revisions.ListCommentRevisions(r.Context(), commentID)
+	if err != nil {
+		writeRevisionsError(w, err, "Comment not found")
+		return
+	}
+	writeRevisions(w, revisions)
+}
+
+// Compare two versions of a comment line by line
+func (h *Handler) DiffCommentRevisions(w http.ResponseWriter, r *http.Request) {
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	revisions, err := h.revisions.ListCommentRevisions(r.Context(), commentID)
+	if err != nil {
+		writeRevisionsError(w, err, "Comment not found")
+		return
+	}
+	writeRevisionDiff(w, r, revisions, false)
+}
+
+// Restore an earlier version of a comment (needs PermContentRollback in the comment's topic), recorded as a new edit
+func (h *Handler) RollbackComment(w http.ResponseWriter, r *http.Request) {
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	revisions, err := h.revisions.ListCommentRevisions(r.Context(), commentID)
+	if err != nil {
+		writeRevisionsError(w, err, "Comment not found")
+		return
+	}
+	revision, ok := rollbackTarget(w, r, revisions)
+	if !ok {
+		return
+	}
+
+	before := h.commentSnapshot(r, commentID)
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.comments.UpdateCommentContent(r.Context(), commentID, revision.Content, user.ID, time.Now().UTC()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to roll back comment"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	h.audit(r, models.AuditCommentRollback, models.AuditTargetComment, strconv.Itoa(commentID), before, h.commentSnapshot(r, commentID))
+	h.publishComment(r, events.CommentUpdated, commentID)
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Write a user's role grants as a JSON response
+func writeRoleGrants(w http.ResponseWriter, status int, grants any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(grants); err != nil {
+		http.Error(w, `{"error": "Failed to encode roles"}`, http.StatusInternalServerError)
+	}
+}
+
+// List every role with its permissions (needs PermRoleManage)
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.roles.RolePermissions(r.Context())
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch roles"}`, http.StatusInternalServerError)
+		return
+	}
+
+	roles := []models.Role{}
+	for _, name := range models.Roles {
+		roles = append(roles, models.Role{Name: name, Permissions: permissions[name]})
+	}
+	writeRoleGrants(w, http.StatusOK, roles)
+}
+
+// List the roles granted to a user (needs PermRoleManage)
+// A user without a global role is a member
+func (h *Handler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := idParam(r, "user_id")
+	if !ok {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+
+	grants, err := h.roles.UserRoles(r.Context(), userID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch roles"}`, http.StatusInternalServerError)
+		return
+	}
+	writeRoleGrants(w, http.StatusOK, grants)
+}
+
+// Grant a user a role (needs PermRoleManage), either globally (replacing their global role) or for a topic
+// Only topic moderators are scoped to a topic
+func (h *Handler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := idParam(r, "user_id")
+	if !ok {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var grant models.RoleGrant
+	if err := json.NewDecoder(r.Body).Decode(&grant); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(models.Roles, grant.Role) {
+		http.Error(w, `{"error": "Invalid role"}`, http.StatusBadRequest)
+		return
+	}
+
+	// The topic can be given by ID, or by slug / name
+	if grant.TopicID == 0 && grant.Topic != "" {
+		topic, err := h.findTopic(r.Context(), grant.Topic)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				http.Error(w, `{"error": "User or topic not found"}`, http.StatusNotFound)
+			} else {
+				http.Error(w, `{"error": "Failed to get topic"}`, http.StatusInternalServerError)
+			}
+			return
+		}
+		grant.TopicID = topic.ID
+	}
+	if (grant.Role == models.RoleTopicModerator) != (grant.TopicID != 0) {
+		http.Error(w, `{"error": "Topic moderators need a topic, other roles are global"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Admins can't change their own global role, so the forum is never left without one by accident
+	user, _ := auth.UserFromContext(r.Context())
+	if userID == user.ID && grant.TopicID == 0 {
+		http.Error(w, `{"error": "You cannot change your own global role"}`, http.StatusBadRequest)
+		return
+	}
+
+	before, err := h.roles.UserRoles(r.Context(), userID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch roles"}`, http.StatusInternalServerError)
+		return
+	}
+
+	grant.ID = 0
+	grant.UserID = userID
+	grant.GrantedBy = user.ID
+	grant.GrantedAt = time.Now().UTC()
+	if err := h.roles.GrantRole(r.Context(), &grant); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, `{"error": "User or topic not found"}`, http.StatusNotFound)
+		case errors.Is(err, store.ErrConflict):
+			http.Error(w, `{"error": "User already holds this role"}`, http.StatusConflict)
+		default:
+			http.Error(w, `{"error": "Failed to grant role"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	after, _ := h.roles.UserRoles(r.Context(), userID)
+	h.audit(r, models.AuditRoleGrant, models.AuditTargetUser, strconv.Itoa(userID), before, after)
+	writeRoleGrants(w, http.StatusCreated, grant)
+}
+
+// Take back a role granted to a user (needs PermRoleManage)
+// Revoking the global role makes the user a member again
+func (h *Handler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := idParam(r, "user_id")
+	if !ok {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+	grantID, ok := idParam(r, "grant_id")
+	if !ok {
+		http.Error(w, `{"error": "Role not found"}`, http.StatusNotFound)
+		return
+	}
+
+	before, err := h.roles.UserRoles(r.Context(), userID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch roles"}`, http.StatusInternalServerError)
+		return
+	}
+	index := slices.IndexFunc(before, func(grant models.RoleGrant) bool { return grant.ID == grantID })
+	if index < 0 {
+		http.Error(w, `{"error": "Role not found"}`, http.StatusNotFound)
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+	if userID == user.ID && before[index].TopicID == 0 {
+		http.Error(w, `{"error": "You cannot change your own global role"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.roles.RevokeRole(r.Context(), grantID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Role not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to revoke role"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	after, _ := h.roles.UserRoles(r.Context(), userID)
+	h.audit(r, models.AuditRoleRevoke, models.AuditTargetUser, strconv.Itoa(userID), before, after)
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
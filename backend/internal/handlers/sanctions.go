@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Longest reason a sanction can carry, in characters
+const maxSanctionReason = 1000
+
+// Body of a request sanctioning a user
+type sanctionRequest struct {
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+	// How long the sanction lasts, as a Go duration (e.g. "72h")
+	// Required for suspensions, optional for mutes (which are otherwise permanent) and not allowed for bans
+	Duration string `json:"duration"`
+}
+
+// Get the sanction store, for SanctionMiddleware
+func (h *Handler) Sanctions() auth.SanctionSource {
+	return h.sanctions
+}
+
+// Write sanctions as a JSON response
+func writeSanctions(w http.ResponseWriter, status int, sanctions any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(sanctions); err != nil {
+		http.Error(w, `{"error": "Failed to encode sanctions"}`, http.StatusInternalServerError)
+	}
+}
+
+// Check the user isn't suspended or banned, writing an error response if they are
+func (h *Handler) checkNotLockedOut(w http.ResponseWriter, r *http.Request, userID int) bool {
+	active, err := h.sanctions.ActiveSanctions(r.Context(), userID, time.Now().UTC())
+	if err != nil {
+		http.Error(w, `{"error": "Failed to check account status"}`, http.StatusInternalServerError)
+		return false
+	}
+	for _, sanction := range active {
+		switch sanction.Kind {
+		case models.SanctionBan:
+			http.Error(w, `{"error": "Account is banned"}`, http.StatusForbidden)
+			return false
+		case models.SanctionSuspension:
+			http.Error(w, `{"error": "Account is suspended"}`, http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// Check the current user outranks the user they sanction, writing an error response if not
+// Keeps moderators from sanctioning admins or each other, and from lifting the sanctions placed on them
+func (h *Handler) checkOutranks(w http.ResponseWriter, r *http.Request, userID int) bool {
+	user, _ := auth.UserFromContext(r.Context())
+	outranks, err := h.policy.Outranks(r.Context(), user.ID, userID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to check permissions"}`, http.StatusInternalServerError)
+		return false
+	}
+	if !outranks {
+		http.Error(w, `{"error": "You cannot sanction a user of equal or higher rank"}`, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// List every sanction placed on a user, newest first (needs PermUserSanction)
+func (h *Handler) ListUserSanctions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := idParam(r, "user_id")
+	if !ok {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+
+	sanctions, err := h.sanctions.ListSanctions(r.Context(), userID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch sanctions"}`, http.StatusInternalServerError)
+		return
+	}
+	writeSanctions(w, http.StatusOK, sanctions)
+}
+
+// Warn, suspend, ban or mute a user (needs PermUserSanction)
+// Suspensions and bans end the user's sessions straight away
+func (h *Handler) SanctionUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := idParam(r, "user_id")
+	if !ok {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var req sanctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > maxSanctionReason {
+		http.Error(w, `{"error": "A reason of up to 1000 characters is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	if userID == user.ID {
+		http.Error(w, `{"error": "You cannot sanction yourself"}`, http.StatusBadRequest)
+		return
+	}
+	if !h.checkOutranks(w, r, userID) {
+		return
+	}
+
+	sanction := models.Sanction{UserID: userID, Kind: req.Kind, Reason: req.Reason, IssuedBy: user.ID, CreatedAt: time.Now().UTC()}
+	switch req.Kind {
+	case models.SanctionBan:
+		if req.Duration != "" {
+			http.Error(w, `{"error": "Bans are permanent, suspend the user instead"}`, http.StatusBadRequest)
+			return
+		}
+	case models.SanctionWarning:
+		if req.Duration != "" {
+			http.Error(w, `{"error": "Warnings don't expire"}`, http.StatusBadRequest)
+			return
+		}
+	case models.SanctionSuspension, models.SanctionMute:
+		if req.Duration == "" && req.Kind == models.SanctionMute {
+			break
+		}
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil || duration <= 0 {
+			http.Error(w, `{"error": "Invalid duration"}`, http.StatusBadRequest)
+			return
+		}
+		expiresAt := sanction.CreatedAt.Add(duration)
+		sanction.ExpiresAt = &expiresAt
+	default:
+		http.Error(w, `{"error": "Invalid kind"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sanctions.AddSanction(r.Context(), &sanction); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to sanction user"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	h.audit(r, models.AuditSanctionAdd, models.AuditTargetUser, strconv.Itoa(userID), nil, sanction)
+	writeSanctions(w, http.StatusCreated, sanction)
+}
+
+// Lift a suspension, ban or mute before it expires (needs PermUserSanction)
+func (h *Handler) LiftSanction(w http.ResponseWriter, r *http.Request) {
+	userID, ok := idParam(r, "user_id")
+	if !ok {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
+	sanctionID, ok := idParam(r, "sanction_id")
+	if !ok {
+		http.Error(w, `{"error": "Sanction not found"}`, http.StatusNotFound)
+		return
+	}
+	if !h.checkOutranks(w, r, userID) {
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	lifted, err := h.sanctions.LiftSanction(r.Context(), userID, sanctionID, user.ID, time.Now().UTC())
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, `{"error": "Sanction not found"}`, http.StatusNotFound)
+		case errors.Is(err, store.ErrConflict):
+			http.Error(w, `{"error": "Sanction no longer applies"}`, http.StatusConflict)
+		default:
+			http.Error(w, `{"error": "Failed to lift sanction"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// The sanction as it was before being lifted
+	before := lifted
+	before.LiftedAt, before.LiftedBy = nil, 0
+	h.audit(r, models.AuditSanctionLift, models.AuditTargetUser, strconv.Itoa(userID), before, lifted)
+	writeSanctions(w, http.StatusOK, lifted)
+}
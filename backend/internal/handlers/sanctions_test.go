@@ -0,0 +1,62 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/models"
+)
+
+func TestSanctionsNeedHigherRank(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user("admin", models.RoleAdmin)
+	moderator := s.user("moderator", models.RoleGlobalModerator)
+	other := s.user("other", models.RoleGlobalModerator)
+	member := s.user("member", "")
+
+	mute := map[string]string{"kind": models.SanctionMute, "reason": "spam", "duration": "1h"}
+	sanctions := func(c *client) string { return fmt.Sprintf("/api/admin/users/%d/sanctions", c.userID) }
+
+	member.expect(http.MethodPost, sanctions(moderator), mute, http.StatusForbidden)
+	moderator.expect(http.MethodPost, sanctions(moderator), mute, http.StatusBadRequest)
+	moderator.expect(http.MethodPost, sanctions(admin), mute, http.StatusForbidden)
+	moderator.expect(http.MethodPost, sanctions(other), mute, http.StatusForbidden)
+
+	// A moderator mutes a member, but only someone above the moderator can lift a mute on one
+	var muted models.Sanction
+	moderator.decode(http.MethodPost, sanctions(member), mute, http.StatusCreated, &muted)
+	var onModerator models.Sanction
+	admin.decode(http.MethodPost, sanctions(other), mute, http.StatusCreated, &onModerator)
+
+	lift := func(c *client, sanction models.Sanction) string {
+		return fmt.Sprintf("%s/%d/lift", sanctions(c), sanction.ID)
+	}
+	moderator.expect(http.MethodPost, lift(other, onModerator), nil, http.StatusForbidden)
+	admin.expect(http.MethodPost, lift(other, onModerator), nil, http.StatusOK)
+	moderator.expect(http.MethodPost, lift(member, muted), nil, http.StatusOK)
+	moderator.expect(http.MethodPost, lift(member, muted), nil, http.StatusConflict)
+}
+
+func TestBannedUsersCannotStreamNotifications(t *testing.T) {
+	s := newTestServer(t)
+	admin := s.user("admin", models.RoleAdmin)
+	member := s.user("member", "")
+
+	ban := map[string]string{"kind": models.SanctionBan, "reason": "spam"}
+	admin.expect(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/sanctions", member.userID), ban, http.StatusCreated)
+
+	// Banning revokes the member's sessions, but the stream mustn't rely on that alone:
+	// give them an access token that outlived its session, as one issued while the ban was being placed would
+	token, err := auth.GenerateToken(models.User{ID: member.userID, Username: "member"}, "outlived")
+	if err != nil {
+		t.Fatalf("generate token: %v", err)
+	}
+	base, _ := url.Parse(member.base)
+	member.http.Jar.SetCookies(base, []*http.Cookie{{Name: "jwt", Value: token.Token}})
+	member.http.Timeout = 5 * time.Second // an accepted stream never ends
+	member.expect(http.MethodGet, "/api/stream?channel=notifications", nil, http.StatusForbidden)
+}
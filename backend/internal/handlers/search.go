@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/search"
+	"sample-go-app/internal/store"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Response envelope for a page of search results
+type searchResponse struct {
+	Results []models.SearchResult `json:"results"`
+	// Offset of the next page, null when there are no more results
+	NextOffset *int `json:"next_offset"`
+}
+
+// Parse a date filter given as YYYY-MM-DD or RFC 3339
+// A bare date used as an upper bound includes the whole day
+func parseSearchDate(value string, endOfDay bool) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfDay {
+		t = t.Add(24 * time.Hour)
+	}
+	return t, true
+}
+
+// Full-text search over posts and comments
+// Query parameters: q, type (posts / comments / all), topic, author, from, to, limit, offset
+func (h *Handler) Search(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+
+	query, err := search.Parse(values.Get("q"))
+	if err != nil {
+		http.Error(w, `{"error": "Search query is required"}`, http.StatusBadRequest)
+		return
+	}
+	params := store.SearchParams{Query: query}
+
+	if ref := values.Get("topic"); ref != "" {
+		topic, err := h.findTopic(r.Context(), ref)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				http.Error(w, `{"error": "Unknown topic"}`, http.StatusBadRequest)
+			} else {
+				http.Error(w, `{"error": "Failed to get topic"}`, http.StatusInternalServerError)
+			}
+			return
+		}
+		params.TopicID = topic.ID
+	}
+
+	switch values.Get("type") {
+	case "", "all":
+		params.Type = store.SearchAll
+	case "posts":
+		params.Type = store.SearchPosts
+	case "comments":
+		params.Type = store.SearchComments
+	default:
+		http.Error(w, `{"error": "Invalid search type"}`, http.StatusBadRequest)
+		return
+	}
+
+	if author := values.Get("author"); author != "" {
+		if params.AuthorID, err = strconv.Atoi(author); err != nil {
+			http.Error(w, `{"error": "Invalid author"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var fromOK, toOK bool
+	params.From, fromOK = parseSearchDate(values.Get("from"), false)
+	params.To, toOK = parseSearchDate(values.Get("to"), true)
+	if !fromOK || !toOK {
+		http.Error(w, `{"error": "Invalid date, use YYYY-MM-DD or RFC 3339"}`, http.StatusBadRequest)
+		return
+	}
+
+	params.Limit = defaultSearchLimit
+	if limit := values.Get("limit"); limit != "" {
+		if params.Limit, err = strconv.Atoi(limit); err != nil || params.Limit < 1 || params.Limit > maxSearchLimit {
+			http.Error(w, `{"error": "Invalid limit"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if offset := values.Get("offset"); offset != "" {
+		if params.Offset, err = strconv.Atoi(offset); err != nil || params.Offset < 0 {
+			http.Error(w, `{"error": "Invalid offset"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Fetch one extra result to find out whether there is another page
+	pageSize := params.Limit
+	params.Limit++
+	results, err := h.search.Search(r.Context(), params)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to search"}`, http.StatusInternalServerError)
+		return
+	}
+
+	response := searchResponse{Results: results}
+	if len(results) > pageSize {
+		response.Results = results[:pageSize]
+		next := params.Offset + pageSize
+		response.NextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		http.Error(w, `{"error": "Failed to encode search results"}`, http.StatusInternalServerError)
+		return
+	}
+}
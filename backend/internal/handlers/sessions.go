@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Get the denylist checked by auth.Verifier
+func (h *Handler) TokenDenylist() auth.Denylist {
+	return h.sessions
+}
+
+// Address of the client making the request, without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Start a new session for a user who just logged in and set its token cookies
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, user models.User) error {
+	sessionID, err := auth.NewSessionID()
+	if err != nil {
+		return err
+	}
+	refreshToken, err := auth.NewRefreshToken()
+	if err != nil {
+		return err
+	}
+	accessToken, err := auth.GenerateToken(user, sessionID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	session := models.Session{
+		ID:               sessionID,
+		UserID:           user.ID,
+		UserAgent:        r.UserAgent(),
+		IP:               clientIP(r),
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(auth.RefreshTokenTTL),
+		RefreshTokenHash: auth.HashToken(refreshToken),
+		AccessTokenID:    accessToken.ID,
+		AccessExpiresAt:  accessToken.ExpiresAt,
+	}
+	if err := h.sessions.CreateSession(r.Context(), session); err != nil {
+		return err
+	}
+
+	auth.SetTokenCookie(w, accessToken)
+	auth.SetRefreshCookie(w, refreshToken, session.ExpiresAt)
+	return nil
+}
+
+// Reject a refresh attempt, clearing the client's now useless cookies
+func refreshFailed(w http.ResponseWriter, message string) {
+	auth.ClearTokenCookie(w)
+	auth.ClearRefreshCookie(w)
+	http.Error(w, `{"error": "`+message+`"}`, http.StatusUnauthorized)
+}
+
+// Exchange a refresh token for a new access token and a new refresh token
+// The user is reloaded from the store, so changes such as an admin demotion are picked up
+func (h *Handler) Refresh(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(auth.RefreshCookieName)
+	if err != nil || cookie.Value == "" {
+		http.Error(w, `{"error": "Missing refresh token"}`, http.StatusUnauthorized)
+		return
+	}
+	presentedHash := auth.HashToken(cookie.Value)
+
+	session, err := h.sessions.GetSessionByTokenHash(r.Context(), presentedHash)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			refreshFailed(w, "Invalid refresh token")
+		} else {
+			http.Error(w, `{"error": "Failed to get session"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	now := time.Now().UTC()
+	if session.Revoked || !session.ExpiresAt.After(now) {
+		refreshFailed(w, "Session has ended")
+		return
+	}
+
+	// A refresh token that was already rotated away has been used twice, so it has probably
+	// been stolen; end the session so neither copy can be used again
+	if presentedHash != session.RefreshTokenHash {
+		if err := h.sessions.RevokeSession(r.Context(), session.UserID, session.ID, now); err != nil && !errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Failed to revoke session"}`, http.StatusInternalServerError)
+			return
+		}
+		refreshFailed(w, "Refresh token has already been used")
+		return
+	}
+
+	user, err := h.users.GetUserByID(r.Context(), session.UserID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			refreshFailed(w, "User not found")
+		} else {
+			http.Error(w, `{"error": "Failed to get user"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	user.Password = ""
+
+	// Sessions end when a user is suspended or banned, but don't rely on that alone
+	if !h.checkNotLockedOut(w, r, user.ID) {
+		return
+	}
+
+	refreshToken, err := auth.NewRefreshToken()
+	if err != nil {
+		http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
+		return
+	}
+	accessToken, err := auth.GenerateToken(user, session.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
+		return
+	}
+
+	session.RefreshTokenHash = auth.HashToken(refreshToken)
+	session.AccessTokenID = accessToken.ID
+	session.AccessExpiresAt = accessToken.ExpiresAt
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(auth.RefreshTokenTTL)
+	if err := h.sessions.RotateSession(r.Context(), session, presentedHash); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			// Another request rotated or revoked the session first
+			refreshFailed(w, "Session has ended")
+		} else {
+			http.Error(w, `{"error": "Failed to refresh session"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	auth.SetTokenCookie(w, accessToken)
+	auth.SetRefreshCookie(w, refreshToken, session.ExpiresAt)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Token refreshed"}`))
+}
+
+// List the current user's active sessions (i.e. logged in devices)
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.sessions.ListUserSessions(r.Context(), user.ID, time.Now().UTC())
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch sessions"}`, http.StatusInternalServerError)
+		return
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == user.SessionID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(sessions); err != nil {
+		http.Error(w, `{"error": "Failed to encode sessions"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+// Revoke one of the current user's sessions, logging that device out
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	sessionID := chi.URLParam(r, "session_id")
+
+	if err := h.sessions.RevokeSession(r.Context(), user.ID, sessionID, time.Now().UTC()); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Session not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to revoke session"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Revoking the current session is the same as logging out
+	if sessionID == user.SessionID {
+		auth.ClearTokenCookie(w)
+		auth.ClearRefreshCookie(w)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// Revoke every session of the current user, logging out all of their devices
+func (h *Handler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Error(w, `{"error": "Unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	revoked, err := h.sessions.RevokeUserSessions(r.Context(), user.ID, time.Now().UTC())
+	if err != nil {
+		http.Error(w, `{"error": "Failed to revoke sessions"}`, http.StatusInternalServerError)
+		return
+	}
+	auth.ClearTokenCookie(w)
+	auth.ClearRefreshCookie(w)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]int{"revoked": revoked}); err != nil {
+		http.Error(w, `{"error": "Failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+}
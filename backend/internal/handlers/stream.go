@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/events"
+	"sample-go-app/internal/store"
+
+	"github.com/go-chi/jwtauth/v5"
+)
+
+// Most channels a single stream can subscribe to
+const maxStreamChannels = 20
+
+// How long browsers wait before reconnecting a dropped stream, in milliseconds
+const streamRetry = 3000
+
+// Publish a change to a post on its channel, with the post as it now reads
+// Deleted posts can't be read, so only their ID is sent
+func (h *Handler) publishPost(r *http.Request, kind string, id int) {
+	if kind == events.PostDeleted {
+		h.events.Publish(events.PostChannel(id), kind, map[string]int{"id": id})
+		return
+	}
+	post, err := h.posts.GetPost(r.Context(), id)
+	if err != nil {
+		return
+	}
+	h.events.Publish(events.PostChannel(id), kind, post)
+}
+
+// Publish a change to a comment on the channel of its post, with the comment as it now reads (a tombstone once deleted)
+func (h *Handler) publishComment(r *http.Request, kind string, id int) {
+	comment, err := h.comments.GetComment(r.Context(), id)
+	if err != nil {
+		return
+	}
+	h.events.Publish(events.PostChannel(comment.PostID), kind, comment)
+}
+
+// Turn a requested channel into the bus channel it stands for, checking the current user may subscribe to it
+// Channels are "post:<id>", "topic:<id, slug or name>" and "notifications" (the current user's, needing a login)
+// Returns the HTTP status and error message to report if the channel can't be subscribed to
+func (h *Handler) streamChannel(r *http.Request, channel string) (string, int, string) {
+	kind, ref, _ := strings.Cut(channel, ":")
+	switch kind {
+	case "post":
+		id, err := strconv.Atoi(ref)
+		if err != nil || id <= 0 {
+			return "", http.StatusNotFound, "Post not found"
+		}
+		if _, err := h.posts.GetPost(r.Context(), id); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return "", http.StatusNotFound, "Post not found"
+			}
+			return "", http.StatusInternalServerError, "Failed to get post"
+		}
+		return events.PostChannel(id), 0, ""
+	case "topic":
+		topic, err := h.findTopic(r.Context(), ref)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return "", http.StatusNotFound, "Topic not found"
+			}
+			return "", http.StatusInternalServerError, "Failed to get topic"
+		}
+		return events.TopicChannel(topic.ID), 0, ""
+	case "notifications":
+		user, ok := auth.UserFromContext(r.Context())
+		if !ok {
+			return "", http.StatusUnauthorized, "Log in to receive notifications"
+		}
+		return events.UserChannel(user.ID), 0, ""
+	}
+	return "", http.StatusBadRequest, "Unknown channel"
+}
+
+// Stream real-time updates as Server-Sent Events
+// Subscribe with one or more ?channel= parameters: post:<id> (its comments and edits), topic:<id or slug> (its new posts)
+// and notifications (the current user's, which ends the stream when their access token expires so it gets refreshed)
+// Resumes after the Last-Event-ID header (or ?last_event_id=), sending a "reset" event if some events were lost
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	requested := r.URL.Query()["channel"]
+	if len(requested) == 0 {
+		http.Error(w, `{"error": "At least one channel is required"}`, http.StatusBadRequest)
+		return
+	}
+	if len(requested) > maxStreamChannels {
+		http.Error(w, fmt.Sprintf(`{"error": "At most %d channels can be streamed at once"}`, maxStreamChannels), http.StatusBadRequest)
+		return
+	}
+
+	channels := []string{}
+	private := false
+	for _, channel := range requested {
+		resolved, status, message := h.streamChannel(r, channel)
+		if status != 0 {
+			http.Error(w, `{"error": "`+message+`"}`, status)
+			return
+		}
+		channels = append(channels, resolved)
+		private = private || channel == "notifications"
+	}
+
+	// Private channels are only streamed to users who aren't locked out, and for as long as the token that authorized them
+	var expired <-chan time.Time
+	if private {
+		user, _ := auth.UserFromContext(r.Context())
+		if !h.checkNotLockedOut(w, r, user.ID) {
+			return
+		}
+		if token, _, err := jwtauth.FromContext(r.Context()); err == nil && token != nil && !token.Expiration().IsZero() {
+			timer := time.NewTimer(time.Until(token.Expiration()))
+			defer timer.Stop()
+			expired = timer.C
+		}
+	}
+
+	rc := http.NewResponseController(w)
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	sub, missed, complete := h.events.Subscribe(channels, lastEventID)
+	defer h.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // stop nginx holding events back
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range missed {
+		writeEvent(w, event)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.cfg.Stream.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-expired:
+			return
+		case event, ok := <-sub.Events():
+			// The bus dropped a subscriber that fell behind, it catches up from the replay buffer on reconnecting
+			if !ok {
+				return
+			}
+			writeEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// Write an event in the Server-Sent Events format
+// JSON never contains raw newlines, so the data fits on a single line
+func writeEvent(w http.ResponseWriter, event events.Event) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Body of a request to watch or mute a post, which watches it when empty
+type postSubscriptionRequest struct {
+	Muted bool `json:"muted"`
+}
+
+// Subscription settings to change, missing fields are left as they are
+type subscriptionSettingsRequest struct {
+	OnPost    *bool `json:"on_post"`
+	OnComment *bool `json:"on_comment"`
+}
+
+// Subscribe the author of a new post or comment to the post, if their subscription settings ask for it
+// Failures are logged rather than reported, since the post or comment itself was already created
+func (h *Handler) autoSubscribe(r *http.Request, userID, postID int, comment bool) {
+	settings, err := h.subscriptions.SubscriptionSettings(r.Context(), userID)
+	if err != nil {
+		log.Printf("Failed to get subscription settings of user %d: %v", userID, err)
+		return
+	}
+	if (comment && !settings.OnComment) || (!comment && !settings.OnPost) {
+		return
+	}
+	if err := h.subscriptions.AutoSubscribe(r.Context(), userID, postID, time.Now().UTC()); err != nil {
+		log.Printf("Failed to subscribe user %d to post %d: %v", userID, postID, err)
+	}
+}
+
+// Flag the topics the current user watches, leaving them all unflagged for anonymous requests
+func (h *Handler) fillTopicSubscriptions(ctx context.Context, topics []models.Topic) error {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok || len(topics) == 0 {
+		return nil
+	}
+
+	subscriptions, err := h.subscriptions.ListSubscriptions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	watched := map[int]bool{}
+	for _, subscription := range subscriptions {
+		if subscription.TargetType == models.SubscriptionTopic {
+			watched[subscription.TargetID] = true
+		}
+	}
+	for i := range topics {
+		topics[i].Subscribed = watched[topics[i].ID]
+	}
+	return nil
+}
+
+// Fill in how the current user follows a post, leaving it empty for anonymous requests
+func (h *Handler) fillPostSubscription(ctx context.Context, post *models.Post) error {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	subscription, err := h.subscriptions.GetSubscription(ctx, user.ID, models.SubscriptionPost, post.ID)
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		post.Subscription = ""
+	case err != nil:
+		return err
+	case subscription.Muted:
+		post.Subscription = models.PostMuted
+	default:
+		post.Subscription = models.PostWatching
+	}
+	return nil
+}
+
+// Write subscriptions (or subscription settings) as a JSON response
+func writeSubscriptions(w http.ResponseWriter, status int, subscriptions any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(subscriptions); err != nil {
+		http.Error(w, `{"error": "Failed to encode subscriptions"}`, http.StatusInternalServerError)
+	}
+}
+
+// Store a subscription of the current user and return it as it now reads
+func (h *Handler) subscribe(w http.ResponseWriter, r *http.Request, targetType string, targetID int, muted bool) {
+	user, _ := auth.UserFromContext(r.Context())
+	subscription := models.Subscription{
+		UserID:     user.ID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Muted:      muted,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := h.subscriptions.Subscribe(r.Context(), subscription); err != nil {
+		http.Error(w, `{"error": "Failed to subscribe"}`, http.StatusInternalServerError)
+		return
+	}
+
+	subscription, err := h.subscriptions.GetSubscription(r.Context(), user.ID, targetType, targetID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch subscription"}`, http.StatusInternalServerError)
+		return
+	}
+	writeSubscriptions(w, http.StatusOK, subscription)
+}
+
+// Remove a subscription of the current user
+func (h *Handler) unsubscribe(w http.ResponseWriter, r *http.Request, targetType string, targetID int) {
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.subscriptions.Unsubscribe(r.Context(), user.ID, targetType, targetID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Not subscribed"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to unsubscribe"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// List the topics and posts the current user watches or muted, newest first
+func (h *Handler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	subscriptions, err := h.subscriptions.ListSubscriptions(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch subscriptions"}`, http.StatusInternalServerError)
+		return
+	}
+	writeSubscriptions(w, http.StatusOK, subscriptions)
+}
+
+// Watch a topic, to be notified of new posts in it and its subtopics
+func (h *Handler) SubscribeTopic(w http.ResponseWriter, r *http.Request) {
+	topic, ok := h.topicParam(w, r)
+	if !ok {
+		return
+	}
+	h.subscribe(w, r, models.SubscriptionTopic, topic.ID, false)
+}
+
+// Stop watching a topic
+func (h *Handler) UnsubscribeTopic(w http.ResponseWriter, r *http.Request) {
+	topic, ok := h.topicParam(w, r)
+	if !ok {
+		return
+	}
+	h.unsubscribe(w, r, models.SubscriptionTopic, topic.ID)
+}
+
+// Watch a post, to be notified of every new comment on it, or mute it with {"muted": true}
+// Muted posts notify the user of nothing, not even replies to them or mentions of them
+func (h *Handler) SubscribePost(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var req postSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.posts.GetPost(r.Context(), postID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to get post"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	h.subscribe(w, r, models.SubscriptionPost, postID, req.Muted)
+}
+
+// Stop watching (or unmute) a post
+// Posts the user creates or comments on later are subscribed to again, if their settings say so
+func (h *Handler) UnsubscribePost(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+	h.unsubscribe(w, r, models.SubscriptionPost, postID)
+}
+
+// Get which posts the current user watches automatically
+func (h *Handler) GetSubscriptionSettings(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	settings, err := h.subscriptions.SubscriptionSettings(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch subscription settings"}`, http.StatusInternalServerError)
+		return
+	}
+	writeSubscriptions(w, http.StatusOK, settings)
+}
+
+// Choose whether the current user watches the posts they create ("on_post") and comment on ("on_comment")
+func (h *Handler) UpdateSubscriptionSettings(w http.ResponseWriter, r *http.Request) {
+	var req subscriptionSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid input"}`, http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	settings, err := h.subscriptions.SubscriptionSettings(r.Context(), user.ID)
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch subscription settings"}`, http.StatusInternalServerError)
+		return
+	}
+	if req.OnPost != nil {
+		settings.OnPost = *req.OnPost
+	}
+	if req.OnComment != nil {
+		settings.OnComment = *req.OnComment
+	}
+	if err := h.subscriptions.SetSubscriptionSettings(r.Context(), user.ID, settings); err != nil {
+		http.Error(w, `{"error": "Failed to update subscription settings"}`, http.StatusInternalServerError)
+		return
+	}
+	writeSubscriptions(w, http.StatusOK, settings)
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/events"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Write the error response for a failed thread change
+func writeThreadError(w http.ResponseWriter, err error, notFound, failure string) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		http.Error(w, `{"error": "`+notFound+`"}`, http.StatusNotFound)
+	case errors.Is(err, store.ErrConflict):
+		http.Error(w, `{"error": "Post has been merged into another post"}`, http.StatusConflict)
+	default:
+		http.Error(w, `{"error": "`+failure+`"}`, http.StatusInternalServerError)
+	}
+}
+
+// Move a post to another topic, given by topic_id or topic (a slug or name)
+// Topic moderators need the permission in both topics
+func (h *Handler) MovePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var destination models.Post
+	if err := json.NewDecoder(r.Body).Decode(&destination); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if destination.TopicID == 0 && destination.Topic == "" {
+		http.Error(w, `{"error": "Topic is required"}`, http.StatusBadRequest)
+		return
+	}
+	if !h.resolvePostTopic(w, r, &destination) {
+		return
+	}
+
+	resource, err := h.PostResource(r)
+	if err != nil {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+	if !h.authorize(w, r, models.PermThreadManage, auth.Resource{OwnerID: resource.OwnerID, TopicID: destination.TopicID}) {
+		return
+	}
+
+	before := h.postSnapshot(r, id)
+	if err := h.threads.MovePost(r.Context(), id, destination.TopicID); err != nil {
+		writeThreadError(w, err, "Post not found", "Failed to move post")
+		return
+	}
+	h.audit(r, models.AuditPostMove, models.AuditTargetPost, strconv.Itoa(id), before, h.postSnapshot(r, id))
+	h.publishPost(r, events.PostUpdated, id)
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// Body of a merge request
+type mergeRequest struct {
+	Into int `json:"into"`
+}
+
+// Merge a post into another one, moving its comments over and leaving it as a stub redirecting there
+// Topic moderators need the permission for both posts
+func (h *Handler) MergePost(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	var req mergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Into <= 0 {
+		http.Error(w, `{"error": "Post to merge into is required"}`, http.StatusBadRequest)
+		return
+	}
+	if req.Into == id {
+		http.Error(w, `{"error": "A post cannot be merged into itself"}`, http.StatusBadRequest)
+		return
+	}
+
+	ownerID, topicID, err := h.posts.GetPostOwner(r.Context(), req.Into)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post to merge into not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to get post"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	if !h.authorize(w, r, models.PermThreadManage, auth.Resource{OwnerID: ownerID, TopicID: topicID}) {
+		return
+	}
+
+	before := h.postSnapshot(r, id)
+	if err := h.threads.MergePost(r.Context(), id, req.Into); err != nil {
+		writeThreadError(w, err, "Post not found", "Failed to merge post")
+		return
+	}
+	h.audit(r, models.AuditPostMerge, models.AuditTargetPost, strconv.Itoa(id), before, h.postSnapshot(r, id))
+	// Viewers of the merged post follow it to the other one, which has gained its comments
+	h.publishPost(r, events.PostUpdated, id)
+	h.publishPost(r, events.PostUpdated, req.Into)
+
+	// Send a success response
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"success": true}`))
+}
+
+// Split a comment and its replies off into a new post with the given title, by the comment's author
+// The post goes in the topic given by topic_id or topic (a slug or name), by default that of the original post
+func (h *Handler) SplitComment(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
+
+	post := models.Post{}
+	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return
+	}
+	if post.Title == "" {
+		http.Error(w, `{"error": "Post title is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.comments.GetComment(r.Context(), commentID)
+	if err != nil || comment.PostID != postID {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
+	if post.TopicID == 0 && post.Topic == "" {
+		if _, post.TopicID, err = h.posts.GetPostOwner(r.Context(), postID); err != nil {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+			return
+		}
+	}
+	if !h.resolvePostTopic(w, r, &post) {
+		return
+	}
+	// Topic moderators need the permission in the new post's topic too
+	if !h.authorize(w, r, models.PermThreadManage, auth.Resource{OwnerID: comment.Author, TopicID: post.TopicID}) {
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	if err := h.threads.SplitComment(r.Context(), commentID, &post, user.ID, time.Now().UTC()); err != nil {
+		writeThreadError(w, err, "Comment not found", "Failed to split comment")
+		return
+	}
+	created := h.postSnapshot(r, post.ID)
+	h.audit(r, models.AuditCommentSplit, models.AuditTargetComment, strconv.Itoa(commentID), comment, created)
+	h.publishComment(r, events.CommentDeleted, commentID)
+	h.events.Publish(events.TopicChannel(post.TopicID), events.PostCreated, post)
+
+	// Return the new post as JSON
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		http.Error(w, `{"error": "Failed to encode post data"}`, http.StatusInternalServerError)
+	}
+}
@@ -1,148 +1,335 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
-	db "sample-go-app/internal/database"
 	"sample-go-app/internal/models"
+	"sample-go-app/internal/slug"
+	"sample-go-app/internal/store"
 
 	"github.com/go-chi/chi/v5"
-	_ "modernc.org/sqlite"
 )
 
-// Get all available topics
-func GetTopics(w http.ResponseWriter, r *http.Request) {
+// Limits on the optional topic fields
+const (
+	maxTopicDescription = 500
+	maxTopicIcon        = 64
+)
 
-	rows, err := db.DB.Query(`SELECT topic from topics`)
+// Find a topic by its slug, name or ID, as given in URLs and request bodies
+func (h *Handler) findTopic(ctx context.Context, ref string) (models.Topic, error) {
+	topic, err := h.topics.FindTopic(ctx, ref)
+	if errors.Is(err, store.ErrNotFound) {
+		if id, convErr := strconv.Atoi(ref); convErr == nil && id > 0 {
+			return h.topics.GetTopic(ctx, id)
+		}
+	}
+	return topic, err
+}
 
+// Get the topic referred to by the {topic} route parameter, writing a 404 if there is none
+func (h *Handler) topicParam(w http.ResponseWriter, r *http.Request) (models.Topic, bool) {
+	topic, err := h.findTopic(r.Context(), chi.URLParam(r, "topic"))
 	if err != nil {
-		http.Error(w, `{"error": "Failed to fetch topics"}`, http.StatusInternalServerError)
-		return
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Topic not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to get topic"}`, http.StatusInternalServerError)
+		}
+		return models.Topic{}, false
 	}
-	defer rows.Close()
+	return topic, true
+}
 
-	// Parse request body into array of topics
-	topics := []models.Topic{}
-	for rows.Next() {
-		var topic models.Topic
-		if err := rows.Scan(&topic.TopicName); err != nil {
-			http.Error(w, `{"error": "Failed to parse post data"}`, http.StatusInternalServerError)
-			return
+// Check the name, slug, description and icon of a topic, generating the slug from the name if missing
+func validateTopic(w http.ResponseWriter, topic *models.Topic) bool {
+	topic.TopicName = strings.TrimSpace(topic.TopicName)
+	if topic.TopicName == "" || topic.TopicName == "All Posts" {
+		http.Error(w, `{"error": "Invalid topic name"}`, http.StatusBadRequest)
+		return false
+	}
+	if topic.Slug == "" {
+		topic.Slug = slug.Make(topic.TopicName)
+	}
+	if !slug.Valid(topic.Slug) {
+		http.Error(w, `{"error": "Invalid topic slug, use lowercase letters, digits and single hyphens"}`, http.StatusBadRequest)
+		return false
+	}
+	if len(topic.Description) > maxTopicDescription {
+		http.Error(w, `{"error": "Topic description is too long"}`, http.StatusBadRequest)
+		return false
+	}
+	if len(topic.Icon) > maxTopicIcon {
+		http.Error(w, `{"error": "Topic icon is too long"}`, http.StatusBadRequest)
+		return false
+	}
+	if topic.Position < 0 {
+		http.Error(w, `{"error": "Invalid topic position"}`, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// Arrange topics, listed by position, into a tree of top-level topics and their subtopics
+func topicTree(topics []models.Topic) []models.Topic {
+	known := map[int]bool{}
+	for _, topic := range topics {
+		known[topic.ID] = true
+	}
+	// Topics whose parent is missing are shown at the top level rather than lost
+	children := map[int][]models.Topic{}
+	for _, topic := range topics {
+		parentID := topic.ParentID
+		if !known[parentID] {
+			parentID = 0
+		}
+		children[parentID] = append(children[parentID], topic)
+	}
+
+	var build func(parentID int) []models.Topic
+	build = func(parentID int) []models.Topic {
+		level := children[parentID]
+		for i := range level {
+			level[i].Children = build(level[i].ID)
+		}
+		return level
+	}
+
+	tree := build(0)
+	if tree == nil {
+		tree = []models.Topic{}
+	}
+	return tree
+}
+
+// Get the IDs of a topic and all of its subtopics, at any depth
+func topicSubtree(topics []models.Topic, id int) map[int]bool {
+	subtree := map[int]bool{id: true}
+	for grew := true; grew; {
+		grew = false
+		for _, topic := range topics {
+			if subtree[topic.ParentID] && !subtree[topic.ID] {
+				subtree[topic.ID] = true
+				grew = true
+			}
 		}
-		topics = append(topics, topic)
 	}
+	return subtree
+}
+
+// Check the parent of a topic exists and isn't the topic itself or one of its subtopics, writing a 400 if not
+func (h *Handler) checkTopicParent(w http.ResponseWriter, r *http.Request, topic models.Topic) bool {
+	if topic.ParentID == 0 {
+		return true
+	}
+	topics, err := h.topics.ListTopics(r.Context())
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch topics"}`, http.StatusInternalServerError)
+		return false
+	}
+	if !slices.ContainsFunc(topics, func(t models.Topic) bool { return t.ID == topic.ParentID }) {
+		http.Error(w, `{"error": "Unknown parent topic"}`, http.StatusBadRequest)
+		return false
+	}
+	if topic.ID != 0 && topicSubtree(topics, topic.ID)[topic.ParentID] {
+		http.Error(w, `{"error": "A topic cannot be moved under itself or one of its subtopics"}`, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
 
+// Write a topic as a JSON response
+func writeTopic(w http.ResponseWriter, status int, topic models.Topic) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(topics); err != nil {
-		http.Error(w, `{"error": "Failed to encode posts"}`, http.StatusInternalServerError)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(topic); err != nil {
+		http.Error(w, `{"error": "Failed to encode topic data"}`, http.StatusInternalServerError)
+	}
+}
+
+// Get all available topics as a tree, each level by position
+func (h *Handler) GetTopics(w http.ResponseWriter, r *http.Request) {
+	topics, err := h.topics.ListTopics(r.Context())
+	if err != nil {
+		http.Error(w, `{"error": "Failed to fetch topics"}`, http.StatusInternalServerError)
+		return
+	}
+	if err := h.fillTopicSubscriptions(r.Context(), topics); err != nil {
+		http.Error(w, `{"error": "Failed to fetch subscriptions"}`, http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(topicTree(topics)); err != nil {
+		http.Error(w, `{"error": "Failed to encode topics"}`, http.StatusInternalServerError)
+		return
+	}
 }
 
 // Add a new available topic
-func AddTopic(w http.ResponseWriter, r *http.Request) {
+// The slug is derived from the name unless given, topics without a position go after their siblings
+// and topics with a parent_id are created as subtopics
+func (h *Handler) AddTopic(w http.ResponseWriter, r *http.Request) {
 	// Parse the request body into a new topic
 	topic := &models.Topic{}
 	if err := json.NewDecoder(r.Body).Decode(topic); err != nil {
 		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
 		return
 	}
-
-	// Check for invalid topic names
-	if topic.TopicName == "All Posts" || topic.TopicName == "" {
-		http.Error(w, `{"error": "Invalid topic"}`, http.StatusInternalServerError)
+	topic.ID = 0
+	topic.Children = nil
+	topic.Subscribed = false
+	if !validateTopic(w, topic) || !h.checkTopicParent(w, r, *topic) {
 		return
 	}
 
-	// Insert new topic into DB
-	_, err := db.DB.Exec("INSERT INTO topics (topic) VALUES (?)", topic.TopicName)
-	if err != nil {
-		fmt.Print(err)
-		http.Error(w, `{"error": "Failed to create topic"}`, http.StatusInternalServerError)
+	// Insert new topic into the store
+	if err := h.topics.CreateTopic(r.Context(), topic); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			http.Error(w, `{"error": "Topic already exists"}`, http.StatusConflict)
+		} else {
+			http.Error(w, `{"error": "Failed to create topic"}`, http.StatusInternalServerError)
+		}
 		return
 	}
+	h.audit(r, models.AuditTopicCreate, models.AuditTargetTopic, strconv.Itoa(topic.ID), nil, topic)
 
 	// Return the created topic as JSON
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(topic); err != nil {
-		http.Error(w, `{"error": "Failed to encode post data"}`, http.StatusInternalServerError)
-	}
+	writeTopic(w, http.StatusCreated, *topic)
 }
 
-// Delete a topic, and all posts / comments associated with it
-func DeleteTopic(w http.ResponseWriter, r *http.Request) {
-	// Extract the topic name from the route parameter
-	topicName := chi.URLParam(r, "topic_name")
+// Fields of a topic that can be changed, missing fields are left as they are
+type topicUpdateRequest struct {
+	TopicName   *string `json:"topic_name"`
+	Slug        *string `json:"slug"`
+	Description *string `json:"description"`
+	Icon        *string `json:"icon"`
+	Position    *int    `json:"position"`
+	// 0 makes the topic a top-level one
+	ParentID *int `json:"parent_id"`
+}
 
-	// Start a transaction
-	tx, err := db.DB.Begin()
-	if err != nil {
-		http.Error(w, `{"error": "Failed to start transaction"}`, http.StatusInternalServerError)
+// Rename or otherwise change a topic
+// Posts and topic roles refer to the topic by ID, so they follow it; renaming regenerates the slug unless one is given
+func (h *Handler) UpdateTopic(w http.ResponseWriter, r *http.Request) {
+	before, ok := h.topicParam(w, r)
+	if !ok {
 		return
 	}
 
-	// Defer a rollback in case anything goes wrong
-	defer tx.Rollback()
-
-	// Step 1: Get all posts with the same topic
-	rows, err := tx.Query("SELECT id FROM posts WHERE topic = ?", topicName)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to fetch posts"}`, http.StatusInternalServerError)
+	var req topicUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
 
-	var postIDs []int
-	for rows.Next() {
-		var postID int
-		if err := rows.Scan(&postID); err != nil {
-			http.Error(w, `{"error": "Failed to read post ID"}`, http.StatusInternalServerError)
-			return
-		}
-		postIDs = append(postIDs, postID)
+	topic := before
+	if req.TopicName != nil && *req.TopicName != before.TopicName {
+		topic.TopicName = *req.TopicName
+		topic.Slug = ""
+	}
+	if req.Slug != nil {
+		topic.Slug = *req.Slug
+	}
+	if req.Description != nil {
+		topic.Description = *req.Description
+	}
+	if req.Icon != nil {
+		topic.Icon = *req.Icon
+	}
+	if req.Position != nil {
+		topic.Position = *req.Position
+	}
+	if req.ParentID != nil {
+		topic.ParentID = *req.ParentID
+	}
+	if !validateTopic(w, &topic) || !h.checkTopicParent(w, r, topic) {
+		return
 	}
 
-	// Step 2: Delete all comments associated with the posts
-	for _, postID := range postIDs {
-		_, err = tx.Exec("DELETE FROM comments WHERE post_id = ?", postID)
-		if err != nil {
-			http.Error(w, `{"error": "Failed to delete comments"}`, http.StatusInternalServerError)
-			return
+	if err := h.topics.UpdateTopic(r.Context(), topic); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, `{"error": "Topic not found"}`, http.StatusNotFound)
+		case errors.Is(err, store.ErrConflict):
+			http.Error(w, `{"error": "Topic name or slug already in use"}`, http.StatusConflict)
+		default:
+			http.Error(w, `{"error": "Failed to update topic"}`, http.StatusInternalServerError)
 		}
+		return
 	}
+	h.audit(r, models.AuditTopicUpdate, models.AuditTargetTopic, strconv.Itoa(topic.ID), before, topic)
 
-	// Step 3: Delete all posts with the topic
-	_, err = tx.Exec("DELETE FROM posts WHERE topic = ?", topicName)
-	if err != nil {
-		http.Error(w, `{"error": "Failed to delete posts"}`, http.StatusInternalServerError)
+	writeTopic(w, http.StatusOK, topic)
+}
+
+// Topics removed by DeleteTopic, as recorded in the audit log
+type topicDeletion struct {
+	Topics  []models.Topic `json:"topics"`
+	MovedTo *models.Topic  `json:"moved_to,omitempty"`
+}
+
+// Delete a topic
+// With ?move_to= (a slug, name or ID) its subtopics and posts are moved to that topic,
+// otherwise its subtopics are deleted too, along with all of their posts and comments
+func (h *Handler) DeleteTopic(w http.ResponseWriter, r *http.Request) {
+	topic, ok := h.topicParam(w, r)
+	if !ok {
 		return
 	}
 
-	// Step 4: Delete the topic itself
-	res, err := tx.Exec("DELETE FROM topics WHERE topic = ?", topicName)
+	topics, err := h.topics.ListTopics(r.Context())
 	if err != nil {
-		http.Error(w, `{"error": "Failed to delete topic"}`, http.StatusInternalServerError)
+		http.Error(w, `{"error": "Failed to fetch topics"}`, http.StatusInternalServerError)
 		return
 	}
+	subtree := topicSubtree(topics, topic.ID)
+	deletion := topicDeletion{}
 
-	// Check if the topic was deleted
-	affected, _ := res.RowsAffected()
-	if affected == 0 {
-		http.Error(w, `{"error": "Topic not found"}`, http.StatusNotFound)
-		return
+	if ref := r.URL.Query().Get("move_to"); ref != "" {
+		target, err := h.findTopic(r.Context(), ref)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				http.Error(w, `{"error": "Unknown topic to move to"}`, http.StatusBadRequest)
+			} else {
+				http.Error(w, `{"error": "Failed to get topic"}`, http.StatusInternalServerError)
+			}
+			return
+		}
+		if subtree[target.ID] {
+			http.Error(w, `{"error": "Cannot move into the deleted topic or one of its subtopics"}`, http.StatusBadRequest)
+			return
+		}
+		deletion.Topics = []models.Topic{topic}
+		deletion.MovedTo = &target
+	} else {
+		for _, t := range topics {
+			if subtree[t.ID] {
+				deletion.Topics = append(deletion.Topics, t)
+			}
+		}
 	}
 
-	// Commit the transaction
-	err = tx.Commit()
-	if err != nil {
-		http.Error(w, `{"error": "Failed to commit transaction"}`, http.StatusInternalServerError)
+	moveTo := 0
+	if deletion.MovedTo != nil {
+		moveTo = deletion.MovedTo.ID
+	}
+	if err := h.topics.DeleteTopic(r.Context(), topic.ID, moveTo); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Topic not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to delete topic"}`, http.StatusInternalServerError)
+		}
 		return
 	}
+	h.audit(r, models.AuditTopicDelete, models.AuditTargetTopic, strconv.Itoa(topic.ID), deletion, nil)
 
 	// Send a success response
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"sample-go-app/internal/models"
+)
+
+func TestTopicModeratorsModerateSubtopics(t *testing.T) {
+	s := newTestServer(t)
+	physics := s.topic("Physics", 0)
+	s.topic("Quantum Physics", physics.ID)
+	s.topic("Chemistry", 0)
+	alice := s.user("alice", "")
+	moderator := s.user("moderator", "")
+	grant := models.RoleGrant{UserID: moderator.userID, Role: models.RoleTopicModerator, TopicID: physics.ID}
+	if err := s.stores.Roles.GrantRole(context.Background(), &grant); err != nil {
+		t.Fatalf("grant topic moderator: %v", err)
+	}
+
+	for topic, status := range map[string]int{
+		"Physics":         http.StatusOK,
+		"Quantum Physics": http.StatusOK,
+		"Chemistry":       http.StatusForbidden,
+	} {
+		post := alice.post("Hello", topic)
+		if got, body := moderator.do(http.MethodDelete, fmt.Sprintf("/api/posts/%d", post.ID), nil); got != status {
+			t.Errorf("deleting a post in %s: got status %d, want %d: %s", topic, got, status, body)
+		}
+	}
+}
+
+func TestDeleteTopic(t *testing.T) {
+	s := newTestServer(t)
+	physics := s.topic("Physics", 0)
+	s.topic("Quantum Physics", physics.ID)
+	s.topic("Chemistry", 0)
+	alice := s.user("alice", "")
+	admin := s.user("admin", models.RoleAdmin)
+
+	moved := alice.post("Moved", "Physics")
+	deleted := alice.post("Deleted", "Quantum Physics")
+	alice.expect(http.MethodDelete, "/api/topics/physics?move_to=chemistry", nil, http.StatusForbidden)
+	admin.expect(http.MethodDelete, "/api/topics/physics?move_to=quantum-physics", nil, http.StatusBadRequest)
+
+	// Deleting a topic on its own moves its posts and subtopics
+	admin.expect(http.MethodDelete, "/api/topics/physics?move_to=chemistry", nil, http.StatusOK)
+	var post models.Post
+	s.anonymous().decode(http.MethodGet, fmt.Sprintf("/api/posts/%d", moved.ID), nil, http.StatusOK, &post)
+	if post.Topic != "Chemistry" {
+		t.Errorf("moved post's topic = %q, want Chemistry", post.Topic)
+	}
+	chemistry, err := s.stores.Topics.FindTopic(context.Background(), "chemistry")
+	if err != nil {
+		t.Fatalf("find chemistry: %v", err)
+	}
+	topic, err := s.stores.Topics.FindTopic(context.Background(), "quantum-physics")
+	if err != nil || topic.ParentID != chemistry.ID {
+		t.Errorf("subtopic after deleting its parent = %+v, %v", topic, err)
+	}
+
+	// Deleting a topic with its subtopics takes their posts along
+	admin.expect(http.MethodDelete, "/api/topics/chemistry", nil, http.StatusOK)
+	for _, id := range []int{moved.ID, deleted.ID} {
+		s.anonymous().expect(http.MethodGet, fmt.Sprintf("/api/posts/%d", id), nil, http.StatusNotFound)
+	}
+	s.anonymous().expect(http.MethodGet, "/api/topics/quantum-physics", nil, http.StatusNotFound)
+}
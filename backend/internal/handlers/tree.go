@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+const (
+	DefaultTreeDepth = 5
+	MaxTreeDepth     = 50
+)
+
+// Get the comments of a post as a nested tree, loaded with a single query
+// Supports ?root= (a comment ID, to load only the replies below it), ?max_depth= (deepest level
+// to load, the first level being 0), and ?limit=, ?cursor= and ?sort= (newest, oldest),
+// where limit caps the replies loaded per comment and cursor pages through the first level
+// A reply's own more_replies cursor continues its level via ?root=<reply id>&cursor=<more_replies>
+func (h *Handler) GetCommentTree(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+
+	params, ok := pageParams(w, r, store.CommentSorts)
+	if !ok {
+		return
+	}
+	tree := store.TreeParams{Page: params, MaxDepth: DefaultTreeDepth}
+	if raw := r.URL.Query().Get("max_depth"); raw != "" {
+		depth, err := strconv.Atoi(raw)
+		if err != nil || depth < 0 || depth > MaxTreeDepth {
+			http.Error(w, `{"error": "Invalid max_depth"}`, http.StatusBadRequest)
+			return
+		}
+		tree.MaxDepth = depth
+	}
+
+	// Make sure the post (and the root comment, if any) exist
+	if _, err := h.posts.GetPost(r.Context(), postID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to get post"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+	rootID := 0
+	if raw := r.URL.Query().Get("root"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+			return
+		}
+		root, err := h.comments.GetComment(r.Context(), id)
+		if err != nil || root.PostID != postID {
+			if err == nil || errors.Is(err, store.ErrNotFound) {
+				http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+			} else {
+				http.Error(w, `{"error": "Failed to get comment"}`, http.StatusInternalServerError)
+			}
+			return
+		}
+		rootID = id
+	}
+
+	nodes, err := h.comments.GetCommentTree(r.Context(), postID, rootID, tree)
+	if err != nil {
+		if !writePaginationError(w, err) {
+			http.Error(w, `{"error": "Failed to fetch comments"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Fill in the current user's votes on every loaded comment
+	comments := make([]models.Comment, len(nodes))
+	for i, node := range nodes {
+		comments[i] = node.Comment
+	}
+	if err := h.fillCommentVotes(r.Context(), comments); err != nil {
+		http.Error(w, `{"error": "Failed to fetch votes"}`, http.StatusInternalServerError)
+		return
+	}
+	for i := range nodes {
+		nodes[i].MyVote = comments[i].MyVote
+	}
+
+	items, next := buildCommentTree(nodes, rootID, tree)
+	page := pagination.Page[*models.CommentNode]{Items: items, NextCursor: next}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(page); err != nil {
+		http.Error(w, `{"error": "Failed to encode comments"}`, http.StatusInternalServerError)
+		return
+	}
+}
+
+// Nest the flat list returned by GetCommentTree below rootID
+// Returns the first level together with the cursor for the rest of it, if any
+func buildCommentTree(nodes []models.CommentNode, rootID int, tree store.TreeParams) ([]*models.CommentNode, *string) {
+	children := map[int][]*models.CommentNode{}
+	for i := range nodes {
+		children[nodes[i].ParentID] = append(children[nodes[i].ParentID], &nodes[i])
+	}
+
+	var attach func(parentID int) ([]*models.CommentNode, *string)
+	attach = func(parentID int) ([]*models.CommentNode, *string) {
+		level := append([]*models.CommentNode{}, children[parentID]...)
+
+		// The store returns one reply more than the limit when there are more to come
+		var more *string
+		if len(level) > tree.Page.Limit {
+			level = level[:tree.Page.Limit]
+			cursor := store.CommentCursor(tree.Page.Sort, level[len(level)-1].Comment).Encode()
+			more = &cursor
+		}
+
+		// Replies below max_depth were not loaded, so they are left as null rather than empty
+		for _, node := range level {
+			if node.Depth < tree.MaxDepth {
+				node.Replies, node.MoreReplies = attach(node.ID)
+			}
+		}
+		return level, more
+	}
+	return attach(rootID)
+}
@@ -1,22 +1,20 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
+
 	"sample-go-app/internal/auth"
-	db "sample-go-app/internal/database"
 	"sample-go-app/internal/models"
-	"strings"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
+	"sample-go-app/internal/store"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 // Create a new account
-func CreateAccount(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	// Parse the username and unhashed password into a new user
 	var newAccount models.User
 	if err := json.NewDecoder(r.Body).Decode(&newAccount); err != nil {
@@ -25,7 +23,7 @@ func CreateAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Hash the password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newAccount.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newAccount.Password), h.cfg.Auth.BcryptCost)
 	if err != nil {
 		http.Error(w, `{"error": "Failed to hash password"}`, http.StatusInternalServerError)
 		return
@@ -33,13 +31,13 @@ func CreateAccount(w http.ResponseWriter, r *http.Request) {
 
 	// Update the password with the hashed version
 	newAccount.Password = string(hashedPassword)
+	// New accounts are never admins, whatever the request says
+	newAccount.IsAdmin = 0
 
-	// Insert the new user into the database
-	_, err = db.DB.Exec("INSERT INTO users (username, password) VALUES (?, ?)", newAccount.Username, newAccount.Password)
-	if err != nil {
-		// Check for a UNIQUE constraint violation using error message
-		// This ensures no duplication of username
-		if strings.Contains(err.Error(), "UNIQUE constraint failed: users.username") {
+	// Insert the new user into the store
+	if err := h.users.CreateUser(r.Context(), &newAccount); err != nil {
+		// A conflict means the username is already taken
+		if errors.Is(err, store.ErrConflict) {
 			http.Error(w, `{"error": "This username is already taken. Please choose another one"}`, http.StatusConflict)
 			return
 		}
@@ -54,7 +52,7 @@ func CreateAccount(w http.ResponseWriter, r *http.Request) {
 }
 
 // Login user
-func Login(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	// Parse the username and unhashed password
 	var account models.User
 	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
@@ -62,12 +60,10 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get the user info currently stored in the database
-	row := db.DB.QueryRow("SELECT id, username, password, isAdmin FROM users WHERE username = ?", account.Username)
-	storedAccount := models.User{}
-	// Scan the result into the struct
-	if err := row.Scan(&storedAccount.ID, &storedAccount.Username, &storedAccount.Password, &storedAccount.IsAdmin); err != nil {
-		if err == sql.ErrNoRows {
+	// Get the user info currently stored
+	storedAccount, err := h.users.GetUserByUsername(r.Context(), account.Username)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
 		} else {
 			http.Error(w, `{"error": "Failed to get user"}`, http.StatusInternalServerError)
@@ -76,47 +72,62 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Compare the entered password with the hashed password in the database
-	err := bcrypt.CompareHashAndPassword([]byte(storedAccount.Password), []byte(account.Password))
+	err = bcrypt.CompareHashAndPassword([]byte(storedAccount.Password), []byte(account.Password))
 	if err != nil {
 		http.Error(w, `{"error": "Incorrect username / password"}`, http.StatusUnauthorized)
 		return
 	}
 
+	// Suspended users can't log in until the suspension ends, banned users ever
+	if !h.checkNotLockedOut(w, r, storedAccount.ID) {
+		return
+	}
+
 	// Clear any token currently in the cache
 	auth.ClearTokenCookie(w)
 
 	// Delete any sensitive info (password) before sending the user's details back
 	storedAccount.Password = ""
-	token, err := auth.GenerateToken(storedAccount)
-	if err != nil {
+
+	// Start a session, setting the access and refresh tokens as cookies
+	if err := h.startSession(w, r, storedAccount); err != nil {
 		http.Error(w, `{"error": "Failed to generate token"}`, http.StatusInternalServerError)
 		return
 	}
 
-	// set the JWT as a cookie
-	auth.SetTokenCookie(w, token)
-
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message": "Login successful"}`))
 }
 
 // Logout user
-func Logout(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	// End the session behind the refresh token, so neither token can be used again
+	if cookie, err := r.Cookie(auth.RefreshCookieName); err == nil && cookie.Value != "" {
+		session, err := h.sessions.GetSessionByTokenHash(r.Context(), auth.HashToken(cookie.Value))
+		if err == nil && !session.Revoked {
+			err = h.sessions.RevokeSession(r.Context(), session.UserID, session.ID, time.Now().UTC())
+		}
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "Failed to end session"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
 	// Clear any currently stored JWT cookies
 	auth.ClearTokenCookie(w)
+	auth.ClearRefreshCookie(w)
 	w.Write([]byte(`{"message": "Logged out successfully"}`))
 }
 
 // Protected route to validate users upon log in
-func Protected(w http.ResponseWriter, r *http.Request) {
-	// Get the token
-	_, claims, _ := jwtauth.FromContext(r.Context())
-	// Retrieve userData from the claims
-	userData, ok := claims["userData"].(map[string]interface{}) // Type assertion for nested data
+func (h *Handler) Protected(w http.ResponseWriter, r *http.Request) {
+	// Get the identity extracted from the token
+	user, ok := auth.UserFromContext(r.Context())
 	if !ok {
 		http.Error(w, `{"error": "Invalid user data"}`, http.StatusUnauthorized)
 		return
 	}
+	userData := user.Model()
 
 	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
@@ -128,14 +139,16 @@ func Protected(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func GetUsernameByID(w http.ResponseWriter, r *http.Request) {
-	userID := chi.URLParam(r, "user_id")
-
-	var username string
-	err := db.DB.QueryRow("SELECT username FROM users WHERE id = ?", userID).Scan(&username)
+func (h *Handler) GetUsernameByID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := idParam(r, "user_id")
+	if !ok {
+		http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+		return
+	}
 
+	user, err := h.users.GetUserByID(r.Context(), userID)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
 			return
 		}
@@ -144,7 +157,7 @@ func GetUsernameByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send the username as JSON response
-	response := map[string]string{"username": username}
+	response := map[string]string{"username": user.Username}
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, `{"error": "Failed to encode response"}`, http.StatusInternalServerError)
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"sample-go-app/internal/auth"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Models the body of a vote request
+type voteRequest struct {
+	Value int `json:"value"` // 1 for an upvote, -1 for a downvote
+}
+
+// Models the response to a vote request
+type voteResponse struct {
+	Score  int `json:"score"`
+	MyVote int `json:"my_vote"`
+}
+
+// Parse and validate the vote value from the request body
+func voteValue(w http.ResponseWriter, r *http.Request) (int, bool) {
+	var body voteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, `{"error": "Invalid request body"}`, http.StatusBadRequest)
+		return 0, false
+	}
+	if body.Value != 1 && body.Value != -1 {
+		http.Error(w, `{"error": "Vote value must be 1 or -1"}`, http.StatusBadRequest)
+		return 0, false
+	}
+	return body.Value, true
+}
+
+// Write the outcome of a vote, mapping a missing post / comment to 404
+func writeVoteResult(w http.ResponseWriter, score, myVote int, err error, notFound string) {
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, `{"error": "`+notFound+`"}`, http.StatusNotFound)
+		} else {
+			http.Error(w, `{"error": "Failed to record vote"}`, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(voteResponse{Score: score, MyVote: myVote}); err != nil {
+		http.Error(w, `{"error": "Failed to encode vote"}`, http.StatusInternalServerError)
+	}
+}
+
+// Upvote or downvote a post, replacing the current user's earlier vote
+func (h *Handler) VotePost(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+	value, ok := voteValue(w, r)
+	if !ok {
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+
+	score, err := h.votes.VotePost(r.Context(), postID, user.ID, value)
+	writeVoteResult(w, score, value, err, "Post not found")
+}
+
+// Remove the current user's vote on a post
+func (h *Handler) UnvotePost(w http.ResponseWriter, r *http.Request) {
+	postID, ok := idParam(r, "post_id")
+	if !ok {
+		http.Error(w, `{"error": "Post not found"}`, http.StatusNotFound)
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+
+	score, err := h.votes.UnvotePost(r.Context(), postID, user.ID)
+	writeVoteResult(w, score, 0, err, "Post not found")
+}
+
+// Upvote or downvote a comment, replacing the current user's earlier vote
+func (h *Handler) VoteComment(w http.ResponseWriter, r *http.Request) {
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
+	value, ok := voteValue(w, r)
+	if !ok {
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+
+	score, err := h.votes.VoteComment(r.Context(), commentID, user.ID, value)
+	writeVoteResult(w, score, value, err, "Comment not found")
+}
+
+// Remove the current user's vote on a comment
+func (h *Handler) UnvoteComment(w http.ResponseWriter, r *http.Request) {
+	commentID, ok := idParam(r, "comment_id")
+	if !ok {
+		http.Error(w, `{"error": "Comment not found"}`, http.StatusNotFound)
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+
+	score, err := h.votes.UnvoteComment(r.Context(), commentID, user.ID)
+	writeVoteResult(w, score, 0, err, "Comment not found")
+}
+
+// Fill in the current user's vote on each post, leaving 0 for anonymous requests
+func (h *Handler) fillPostVotes(ctx context.Context, posts []models.Post) error {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok || len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	votes, err := h.votes.PostVotesBy(ctx, user.ID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range posts {
+		posts[i].MyVote = votes[posts[i].ID]
+	}
+	return nil
+}
+
+// Fill in the current user's vote on each comment, leaving 0 for anonymous requests
+func (h *Handler) fillCommentVotes(ctx context.Context, comments []models.Comment) error {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok || len(comments) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(comments))
+	for i, comment := range comments {
+		ids[i] = comment.ID
+	}
+	votes, err := h.votes.CommentVotesBy(ctx, user.ID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range comments {
+		comments[i].MyVote = votes[comments[i].ID]
+	}
+	return nil
+}
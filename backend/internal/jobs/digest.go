@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"sample-go-app/internal/config"
+	"sample-go-app/internal/mail"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+// Time between two digests of each frequency
+var digestPeriods = map[string]time.Duration{
+	models.DigestDaily:  24 * time.Hour,
+	models.DigestWeekly: 7 * 24 * time.Hour,
+}
+
+// Most posts and replies listed in a digest
+const digestItems = 20
+
+// Unread notifications looked through for the replies listed in a digest
+const digestNotifications = 100
+
+// Notification types listed as replies in digests
+var digestReplyTypes = []string{models.NotificationPostReply, models.NotificationCommentReply}
+
+// Queue the digests that are due every cfg.DigestInterval, until ctx is cancelled
+func RunDigests(ctx context.Context, stores store.Stores, cfg config.MailConfig) {
+	if cfg.DigestInterval <= 0 {
+		log.Println("Digest job disabled, no digest emails are sent")
+		return
+	}
+
+	ticker := time.NewTicker(cfg.DigestInterval)
+	defer ticker.Stop()
+	for {
+		SendDigestsOnce(ctx, stores, cfg.BaseURL, time.Now().UTC())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Queue a digest for every user whose daily or weekly digest is due at now, summarizing the new posts
+// in the topics they watch and their unread replies since the last one
+// Users with nothing new get no email, but still wait a full period before the next digest
+func SendDigestsOnce(ctx context.Context, stores store.Stores, baseURL string, now time.Time) {
+	recipients, err := stores.Emails.DigestRecipients(ctx)
+	if err != nil {
+		log.Printf("Failed to find digest recipients: %v", err)
+		return
+	}
+
+	sent := 0
+	for _, recipient := range recipients {
+		period, ok := digestPeriods[recipient.Digest]
+		if !ok || now.Before(recipient.Since.Add(period)) {
+			continue
+		}
+
+		posts, err := stores.Subscriptions.WatchedTopicPosts(ctx, recipient.UserID, recipient.Since, digestItems)
+		if err != nil {
+			log.Printf("Failed to get the digest posts of user %d: %v", recipient.UserID, err)
+			continue
+		}
+		unread, err := stores.Notifications.ListNotifications(ctx, recipient.UserID, true,
+			pagination.Params{Sort: store.SortNewest, Limit: digestNotifications})
+		if err != nil {
+			log.Printf("Failed to get the digest replies of user %d: %v", recipient.UserID, err)
+			continue
+		}
+		replies := []models.Notification{}
+		for _, notification := range unread {
+			if len(replies) < digestItems && slices.Contains(digestReplyTypes, notification.Type) &&
+				notification.CreatedAt.After(recipient.Since) {
+				replies = append(replies, notification)
+			}
+		}
+
+		if len(posts) > 0 || len(replies) > 0 {
+			data := mail.DigestData{
+				Username:  recipient.Username,
+				Frequency: recipient.Digest,
+				Posts:     posts,
+				Replies:   replies,
+				BaseURL:   baseURL,
+			}
+			if err := mail.Queue(ctx, stores.Emails, mail.TemplateDigest, recipient.Email, data); err != nil {
+				log.Printf("Failed to queue the digest of user %d: %v", recipient.UserID, err)
+				continue
+			}
+			sent++
+		}
+		if err := stores.Emails.MarkDigestSent(ctx, recipient.UserID, now); err != nil {
+			log.Printf("Failed to record the digest of user %d: %v", recipient.UserID, err)
+		}
+	}
+	if sent > 0 {
+		log.Printf("Queued %d digest emails", sent)
+	}
+}
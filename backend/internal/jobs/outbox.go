@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"sample-go-app/internal/config"
+	"sample-go-app/internal/mail"
+	"sample-go-app/internal/store"
+)
+
+// Emails sent per run of the outbox job, the rest waiting for the next run
+const outboxBatch = 50
+
+// Longest wait before retrying a failed email, however many times it failed
+const maxRetryDelay = 24 * time.Hour
+
+// Send the emails waiting in the outbox every cfg.OutboxInterval, until ctx is cancelled
+func RunOutbox(ctx context.Context, outbox store.EmailStore, mailer mail.Mailer, cfg config.MailConfig) {
+	if cfg.OutboxInterval <= 0 {
+		log.Println("Outbox job disabled, emails are queued but never sent")
+		return
+	}
+
+	ticker := time.NewTicker(cfg.OutboxInterval)
+	defer ticker.Stop()
+	for {
+		SendOutboxOnce(ctx, outbox, mailer, cfg)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Try sending every email that is due, scheduling a retry for those that fail
+// Emails are given up on after cfg.MaxAttempts, waiting cfg.RetryBackoff after the first failure and twice as long after each next one
+func SendOutboxOnce(ctx context.Context, outbox store.EmailStore, mailer mail.Mailer, cfg config.MailConfig) {
+	emails, err := outbox.DueEmails(ctx, time.Now().UTC(), outboxBatch)
+	if err != nil {
+		log.Printf("Failed to read the outbox: %v", err)
+		return
+	}
+
+	for _, email := range emails {
+		err := mailer.Send(ctx, mail.Message{To: email.To, Subject: email.Subject, Text: email.Text, HTML: email.HTML})
+		now := time.Now().UTC()
+		if err == nil {
+			if err := outbox.MarkEmailSent(ctx, email.ID, now); err != nil {
+				log.Printf("Failed to mark email %d sent: %v", email.ID, err)
+			}
+			continue
+		}
+
+		attempts := email.Attempts + 1
+		var retryAt time.Time
+		if attempts < cfg.MaxAttempts {
+			retryAt = now.Add(retryDelay(cfg.RetryBackoff, attempts))
+			log.Printf("Failed to send email %d to %s (attempt %d of %d), retrying at %s: %v",
+				email.ID, email.To, attempts, cfg.MaxAttempts, retryAt.Format(time.RFC3339), err)
+		} else {
+			log.Printf("Giving up on email %d to %s after %d attempts: %v", email.ID, email.To, attempts, err)
+		}
+		if err := outbox.MarkEmailFailed(ctx, email.ID, err.Error(), now, retryAt); err != nil {
+			log.Printf("Failed to record failure of email %d: %v", email.ID, err)
+		}
+	}
+}
+
+// Get how long to wait after an email failed for the given number of times
+func retryDelay(backoff time.Duration, attempts int) time.Duration {
+	delay := backoff
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
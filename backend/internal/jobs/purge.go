@@ -0,0 +1,45 @@
+// Package jobs runs background maintenance tasks alongside the HTTP server.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"sample-go-app/internal/config"
+	"sample-go-app/internal/store"
+)
+
+// Permanently remove content deleted more than cfg.Retention ago, every cfg.Interval,
+// until ctx is cancelled
+func RunPurge(ctx context.Context, purger store.PurgeStore, cfg config.PurgeConfig) {
+	if cfg.Interval <= 0 {
+		log.Println("Purge job disabled, deleted content is kept forever")
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		// Run once straight away, so a restart doesn't delay the purge by a full interval
+		PurgeOnce(ctx, purger, cfg.Retention)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Remove content deleted more than retention ago, logging what was removed
+func PurgeOnce(ctx context.Context, purger store.PurgeStore, retention time.Duration) {
+	posts, comments, err := purger.PurgeDeleted(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		log.Printf("Failed to purge deleted content: %v", err)
+		return
+	}
+	if posts > 0 || comments > 0 {
+		log.Printf("Purged %d deleted posts and %d deleted comments", posts, comments)
+	}
+}
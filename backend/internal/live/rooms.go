@@ -0,0 +1,168 @@
+// Package live tracks who is viewing each thread and relays their typing indicators.
+// Unlike the events bus, nothing here is stored or replayed: it only concerns the users connected right now.
+package live
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Kinds of message sent to viewers
+const (
+	MessagePresence = "presence" // data is every user viewing the thread
+	MessageTyping   = "typing"   // data is a TypingStatus
+)
+
+// Messages queued for a viewer that isn't keeping up, beyond which it is dropped
+const clientBuffer = 32
+
+// A user typing again within this long of their last indicator isn't announced again
+const typingInterval = 2 * time.Second
+
+// Models a user viewing a thread
+type Viewer struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// Models a user starting or stopping to type a reply
+type TypingStatus struct {
+	User   Viewer `json:"user"`
+	Typing bool   `json:"typing"`
+}
+
+// Models a message sent to viewers, with a JSON encoded payload
+type Message struct {
+	Type string          `json:"type"`
+	ID   string          `json:"id,omitempty"`
+	Data json.RawMessage `json:"data"`
+}
+
+// One connection viewing a thread
+type Client struct {
+	Viewer Viewer
+	send   chan Message
+
+	typing     bool
+	lastTyping time.Time
+}
+
+// Get the messages for the client, the channel being closed if it fell too far behind
+func (c *Client) Messages() <-chan Message {
+	return c.send
+}
+
+// The viewers of every thread, keyed by post ID
+type Rooms struct {
+	mu    sync.Mutex
+	rooms map[int]map[*Client]bool
+}
+
+// Create an empty set of rooms
+func NewRooms() *Rooms {
+	return &Rooms{rooms: map[int]map[*Client]bool{}}
+}
+
+// Add a viewer to a thread, telling everyone viewing it (the new viewer included) who is there
+func (r *Rooms) Join(postID int, viewer Viewer) *Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	client := &Client{Viewer: viewer, send: make(chan Message, clientBuffer)}
+	if r.rooms[postID] == nil {
+		r.rooms[postID] = map[*Client]bool{}
+	}
+	r.rooms[postID][client] = true
+	r.presenceLocked(postID)
+	return client
+}
+
+// Remove a viewer from a thread, telling those left if they stopped typing or the user is no longer there
+func (r *Rooms) Leave(postID int, client *Client) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.rooms[postID][client] {
+		return
+	}
+	r.removeLocked(postID, client)
+	if client.typing {
+		r.broadcastLocked(postID, nil, MessageTyping, TypingStatus{User: client.Viewer})
+	}
+	r.presenceLocked(postID)
+}
+
+// Tell the other viewers of a thread that a client started or stopped typing
+// Repeated indicators are only passed on every typingInterval, so clients can simply resend theirs as the user types
+func (r *Rooms) Typing(postID int, client *Client, typing bool, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.rooms[postID][client] {
+		return
+	}
+	if typing == client.typing && (!typing || now.Sub(client.lastTyping) < typingInterval) {
+		return
+	}
+	client.typing = typing
+	if typing {
+		client.lastTyping = now
+	}
+	r.broadcastLocked(postID, client, MessageTyping, TypingStatus{User: client.Viewer, Typing: typing})
+}
+
+// Send a message to every viewer of a thread but one (nil to include everyone)
+// Viewers whose queue is full are dropped rather than waited for (callers must hold the lock)
+func (r *Rooms) broadcastLocked(postID int, except *Client, kind string, data any) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to encode %s message: %v", kind, err)
+		return
+	}
+	message := Message{Type: kind, Data: encoded}
+
+	var dropped []*Client
+	for client := range r.rooms[postID] {
+		if client == except {
+			continue
+		}
+		select {
+		case client.send <- message:
+		default:
+			dropped = append(dropped, client)
+		}
+	}
+	if len(dropped) > 0 {
+		for _, client := range dropped {
+			r.removeLocked(postID, client)
+		}
+		r.presenceLocked(postID)
+	}
+}
+
+// Tell every viewer of a thread who is viewing it, each user once however many connections they have
+// (callers must hold the lock)
+func (r *Rooms) presenceLocked(postID int) {
+	viewers := []Viewer{}
+	seen := map[int]bool{}
+	for client := range r.rooms[postID] {
+		if !seen[client.Viewer.ID] {
+			seen[client.Viewer.ID] = true
+			viewers = append(viewers, client.Viewer)
+		}
+	}
+	sort.Slice(viewers, func(i, j int) bool { return viewers[i].Username < viewers[j].Username })
+	r.broadcastLocked(postID, nil, MessagePresence, viewers)
+}
+
+// Take a client out of a thread and close its queue (callers must hold the lock)
+func (r *Rooms) removeLocked(postID int, client *Client) {
+	delete(r.rooms[postID], client)
+	if len(r.rooms[postID]) == 0 {
+		delete(r.rooms, postID)
+	}
+	close(client.send)
+}
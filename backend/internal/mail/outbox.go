@@ -0,0 +1,24 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Render an email and queue it in the outbox, from which the outbox job delivers it
+func Queue(ctx context.Context, outbox store.EmailStore, name, to string, data any) error {
+	message, err := Render(name, to, data)
+	if err != nil {
+		return err
+	}
+	return outbox.EnqueueEmail(ctx, &models.Email{
+		To:        message.To,
+		Subject:   message.Subject,
+		Text:      message.Text,
+		HTML:      message.HTML,
+		CreatedAt: time.Now().UTC(),
+	})
+}
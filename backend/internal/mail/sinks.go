@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Writes emails to the server log instead of sending them, for development
+type LogMailer struct{}
+
+func (m *LogMailer) Send(ctx context.Context, message Message) error {
+	log.Printf("Email to %s: %s\n%s", message.To, message.Subject, message.Text)
+	return nil
+}
+
+// Writes each email to its own .eml file instead of sending it, for development and tests
+// The files can be opened with any mail client
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// Create a file mailer writing to dir, creating the directory if needed
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, message Message) error {
+	now := time.Now().UTC()
+	data, err := Compose(m.from, message, now)
+	if err != nil {
+		return err
+	}
+	// Named by time so the files list in the order they were sent
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := now.Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+	return os.WriteFile(filepath.Join(m.dir, name), data, 0o600)
+}
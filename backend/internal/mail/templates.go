@@ -0,0 +1,94 @@
+package mail
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"sample-go-app/internal/models"
+)
+
+// Every email is defined by three templates: "<name>.subject" and "<name>.text" in templates/<name>.txt,
+// and "<name>.html" in templates/<name>.html, which wraps itself in the "header" and "footer" of layout.html
+//
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFiles, "templates/*.txt"))
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFiles, "templates/*.html"))
+)
+
+// Names of the emails
+const (
+	TemplateVerifyEmail  = "verify_email"
+	TemplateNotification = "notification"
+	TemplateDigest       = "digest"
+)
+
+// Data of the verify_email template
+type VerifyEmailData struct {
+	Username string
+	Email    string
+	// Frontend page confirming the address, carrying the token
+	Link    string
+	Expires time.Time
+}
+
+// Data of the notification template
+type NotificationData struct {
+	Username     string
+	Notification models.Notification
+	Summary      string // from NotificationSummary
+	BaseURL      string
+}
+
+// Data of the digest template
+type DigestData struct {
+	Username  string
+	Frequency string // models.DigestDaily or models.DigestWeekly
+	// New posts in the topics the user watches, and unread replies to them, since the last digest
+	Posts   []models.Post
+	Replies []models.Notification
+	BaseURL string
+}
+
+// Render an email to the given address
+func Render(name, to string, data any) (Message, error) {
+	var subject, text, html strings.Builder
+	if err := textTemplates.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := textTemplates.ExecuteTemplate(&text, name+".text", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s text: %w", name, err)
+	}
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s html: %w", name, err)
+	}
+	return Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// Describe a notification in a sentence, e.g. `alice replied to your post "Hello"`
+func NotificationSummary(n models.Notification) string {
+	switch n.Type {
+	case models.NotificationPostReply:
+		return fmt.Sprintf("%s replied to your post %q", n.ActorUsername, n.PostTitle)
+	case models.NotificationCommentReply:
+		return fmt.Sprintf("%s replied to your comment on %q", n.ActorUsername, n.PostTitle)
+	case models.NotificationMention:
+		return fmt.Sprintf("%s mentioned you in %q", n.ActorUsername, n.PostTitle)
+	case models.NotificationNewPost:
+		return fmt.Sprintf("%s posted %q in a topic you watch", n.ActorUsername, n.PostTitle)
+	case models.NotificationNewComment:
+		return fmt.Sprintf("%s commented on %q", n.ActorUsername, n.PostTitle)
+	}
+	return fmt.Sprintf("New activity on %q", n.PostTitle)
+}
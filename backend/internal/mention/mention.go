@@ -0,0 +1,30 @@
+// Package mention finds @username mentions in post and comment content.
+package mention
+
+import "regexp"
+
+// Most distinct users a single post or comment can mention, so one message can't notify everyone
+const MaxMentions = 10
+
+// An @ at the start of the text or after a non-word character (so e-mail addresses don't count),
+// followed by letters, digits, '_', '.' or '-' not ending in '.' or '-' (trailing punctuation isn't part of the name)
+var pattern = regexp.MustCompile(`(?:^|[^\w@])@(\w(?:[\w.-]*\w)?)`)
+
+// Get the usernames mentioned in content, in order of first mention and without repeats
+// At most MaxMentions are returned
+func Mentions(content string) []string {
+	var usernames []string
+	seen := map[string]bool{}
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		username := match[1]
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		usernames = append(usernames, username)
+		if len(usernames) == MaxMentions {
+			break
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditPostUpdate      = "post.update"
+	AuditPostDelete      = "post.delete"
+	AuditPostRestore     = "post.restore"
+	AuditPostRollback    = "post.rollback"
+	AuditPostMove        = "post.move"
+	AuditPostMerge       = "post.merge"
+	AuditCommentSplit    = "comment.split"
+	AuditCommentUpdate   = "comment.update"
+	AuditCommentDelete   = "comment.delete"
+	AuditCommentRestore  = "comment.restore"
+	AuditCommentRollback = "comment.rollback"
+	AuditTopicCreate     = "topic.create"
+	AuditTopicUpdate     = "topic.update"
+	AuditTopicDelete     = "topic.delete"
+	AuditReportResolve   = "report.resolve"
+	AuditRoleGrant       = "role.grant"
+	AuditRoleRevoke      = "role.revoke"
+	AuditSanctionAdd     = "sanction.add"
+	AuditSanctionLift    = "sanction.lift"
+)
+
+// Kinds of things an audited action can target
+const (
+	AuditTargetPost    = "post"
+	AuditTargetComment = "comment"
+	AuditTargetTopic   = "topic"
+	AuditTargetReport  = "report"
+	AuditTargetUser    = "user"
+)
+
+// Models one privileged action in the append-only audit log
+type AuditEntry struct {
+	ID            int    `json:"id"`
+	ActorID       int    `json:"actor_id"`
+	ActorUsername string `json:"actor_username"`
+	Action        string `json:"action"`
+	TargetType    string `json:"target_type"`
+	// ID of the target, or the name of a topic
+	TargetID string `json:"target_id"`
+	// JSON snapshots of the target around the action, null where there is nothing to show
+	Before json.RawMessage `json:"before"`
+	After  json.RawMessage `json:"after"`
+	// Metadata of the request that performed the action
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	RequestID string    `json:"request_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
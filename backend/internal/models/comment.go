@@ -11,4 +11,42 @@ type Comment struct {
 	Username  string    `json:"username"`
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
+	// Net votes, and the current user's vote (1, -1 or 0 when not voted / logged out)
+	Score  int `json:"score"`
+	MyVote int `json:"my_vote"`
+	// When the comment was last edited (null if never), and how many times
+	EditedAt      *time.Time `json:"edited_at"`
+	RevisionCount int        `json:"revision_count"`
+	// Set on tombstones: deleted or hidden comments kept in place so their replies stay visible
+	Deleted   bool       `json:"deleted"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Shown in place of the content and username of deleted comments,
+// and of comments hidden by a moderator respectively
+const (
+	DeletedPlaceholder = "[deleted]"
+	RemovedPlaceholder = "[removed]"
+)
+
+// Turn the comment into a tombstone, replacing its content and author with the placeholder
+func (c *Comment) Tombstone(deletedAt time.Time, placeholder string) {
+	c.Deleted = true
+	c.DeletedAt = &deletedAt
+	c.Content = placeholder
+	c.Author = 0
+	c.Username = placeholder
+}
+
+// Models a comment within a thread, together with its replies
+type CommentNode struct {
+	Comment
+	// Levels below the root of the requested tree (0 for the first level)
+	Depth int `json:"depth"`
+	// Number of direct replies, whether or not they are included
+	ReplyCount int `json:"reply_count"`
+	// Included replies; null when the tree was cut off at max_depth before this comment
+	Replies []*CommentNode `json:"replies"`
+	// Cursor for the replies left out by the per-level limit, null when all are included
+	MoreReplies *string `json:"more_replies"`
 }
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// How often users get a digest email
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Every digest frequency
+var DigestFrequencies = []string{DigestOff, DigestDaily, DigestWeekly}
+
+// Models a user's email address and what the forum emails them
+type EmailSettings struct {
+	// Verified address, empty if the user has none
+	Email      string     `json:"email"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	// Address waiting to be verified, and when its verification was sent
+	PendingEmail string     `json:"pending_email,omitempty"`
+	PendingSince *time.Time `json:"pending_since,omitempty"`
+	Digest       string     `json:"digest"`
+	// Whether notifications are emailed as they happen
+	Notifications bool `json:"notifications"`
+}
+
+// Email settings of users who never changed them
+var DefaultEmailSettings = EmailSettings{Digest: DigestWeekly}
+
+// Models a new address waiting to be verified, proven by a token sent to it
+type EmailVerification struct {
+	TokenHash string
+	UserID    int
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Models an email in the outbox
+type Email struct {
+	ID      int    `json:"id"`
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+	// Failed attempts at sending it so far, and why the last one failed
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	CreatedAt time.Time `json:"created_at"`
+	// When it is (next) due to be sent, null once sent or given up on
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at"`
+	FailedAt      *time.Time `json:"failed_at"`
+}
+
+// Models a user whose digest is due
+type DigestRecipient struct {
+	UserID   int
+	Username string
+	Email    string
+	Digest   string
+	// When the last digest was sent (or the address verified), the digest covering what happened since
+	Since time.Time
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Kinds of notification a user can receive
+const (
+	NotificationPostReply    = "post_reply"    // a top-level comment on the user's post
+	NotificationCommentReply = "comment_reply" // a reply to the user's comment
+	NotificationMention      = "mention"       // the user was @mentioned in a post or comment
+	NotificationNewPost      = "new_post"      // a post in a topic the user watches
+	NotificationNewComment   = "new_comment"   // a comment on a post the user watches
+)
+
+// Every kind of notification, each of which users can turn off
+var NotificationTypes = []string{NotificationPostReply, NotificationCommentReply, NotificationMention,
+	NotificationNewPost, NotificationNewComment}
+
+// Models an in-app notification telling a user someone replied to or mentioned them
+type Notification struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	Type   string `json:"type"`
+	// User whose post or comment caused the notification
+	ActorID       int    `json:"actor_id"`
+	ActorUsername string `json:"actor_username"`
+	PostID        int    `json:"post_id"`
+	PostTitle     string `json:"post_title"`
+	// The comment replying to or mentioning the user, or posted on a watched post; 0 for notifications about a post
+	CommentID int       `json:"comment_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// When the user read the notification, null while unread
+	ReadAt *time.Time `json:"read_at"`
+}
@@ -6,9 +6,26 @@ import "time"
 type Post struct {
 	ID        int       `json:"id"`
 	Title     string    `json:"title"`
-	Topic     string    `json:"topic"`
+	Topic     string    `json:"topic"` // name of the topic, accepted in place of topic_id in requests
+	TopicID   int       `json:"topic_id"`
 	Content   string    `json:"content"`
 	Author    int       `json:"author"`
 	Username  string    `json:"username"`
 	CreatedAt time.Time `json:"created_at"`
+	// Maintained by the store as comments are added / removed
+	CommentCount   int       `json:"comment_count"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	// Net votes, and the current user's vote (1, -1 or 0 when not voted / logged out)
+	Score   int     `json:"score"`
+	MyVote  int     `json:"my_vote"`
+	HotRank float64 `json:"-"`
+	// When the post was last edited (null if never), and how many times
+	EditedAt      *time.Time `json:"edited_at"`
+	RevisionCount int        `json:"revision_count"`
+	// Post this one was merged into (its comments having moved there), 0 if not merged
+	// Merged posts are left as redirect stubs, out of post lists and search
+	MergedInto int `json:"merged_into,omitempty"`
+	// How the current user follows the post (PostWatching or PostMuted), empty if they don't
+	// Only filled in for the post's own details
+	Subscription string `json:"subscription,omitempty"`
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Kinds of things that can be reported
+const (
+	ReportPost    = "post"
+	ReportComment = "comment"
+	ReportUser    = "user"
+)
+
+// Reason categories a report must pick from
+var ReportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "misinformation", "off_topic", "other"}
+
+// Report statuses
+const (
+	ReportOpen      = "open"
+	ReportResolved  = "resolved"
+	ReportDismissed = "dismissed"
+)
+
+// Actions an admin can resolve a report with
+const (
+	ActionDismiss = "dismiss" // no action, the report was unfounded
+	ActionHide    = "hide"    // hide the post / comment until restored
+	ActionDelete  = "delete"  // soft delete the post / comment
+	ActionWarn    = "warn"    // warn the author
+	ActionSuspend = "suspend" // suspend the author for a while
+)
+
+// Models a user's report against a post, comment or user
+type Report struct {
+	ID         int    `json:"id"`
+	TargetType string `json:"target_type"`
+	TargetID   int    `json:"target_id"`
+	// Author of the reported content, or the reported user
+	TargetUserID     int       `json:"target_user_id"`
+	ReporterID       int       `json:"reporter_id"`
+	ReporterUsername string    `json:"reporter_username"`
+	Reason           string    `json:"reason"`
+	Details          string    `json:"details"`
+	Status           string    `json:"status"`
+	CreatedAt        time.Time `json:"created_at"`
+	// Set once an admin resolves the report
+	ResolvedAt *time.Time `json:"resolved_at"`
+	ResolvedBy int        `json:"resolved_by,omitempty"`
+	Action     string     `json:"action,omitempty"`
+	Note       string     `json:"note,omitempty"`
+}
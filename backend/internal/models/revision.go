@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Models one version of the editable fields of a post or comment
+type Revision struct {
+	// Numbered from 1 for the original version
+	Revision int `json:"revision"`
+	// Title and topic are only set for posts
+	Title   string `json:"title,omitempty"`
+	Topic   string `json:"topic,omitempty"` // current name of the topic
+	TopicID int    `json:"topic_id,omitempty"`
+	Content string `json:"content"`
+	// Who wrote this version (the original author or an editor) and when
+	Author    int       `json:"author"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	// Whether this is the version currently shown
+	Current bool `json:"current"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import "time"
+
+// Roles a user can hold
+const (
+	RoleAdmin           = "admin"
+	RoleGlobalModerator = "global_moderator"
+	RoleTopicModerator  = "topic_moderator" // only granted for a topic
+	RoleMember          = "member"          // the global role of users without one
+	RoleReadOnly        = "read_only"
+)
+
+// Every role, from most to least privileged
+var Roles = []string{RoleAdmin, RoleGlobalModerator, RoleTopicModerator, RoleMember, RoleReadOnly}
+
+// Rank of each role, a user only being able to sanction users they outrank
+// Topic moderators rank with global moderators, so moderators can't sanction one another
+var RoleRanks = map[string]int{
+	RoleAdmin:           3,
+	RoleGlobalModerator: 2,
+	RoleTopicModerator:  2,
+	RoleMember:          1,
+	RoleReadOnly:        0,
+}
+
+// Permissions checked by the policy
+const (
+	PermPostCreate      = "post.create"
+	PermPostEdit        = "post.edit"
+	PermPostDelete      = "post.delete"
+	PermCommentCreate   = "comment.create"
+	PermCommentEdit     = "comment.edit"
+	PermCommentDelete   = "comment.delete"
+	PermVote            = "vote"
+	PermReportCreate    = "report.create"
+	PermContentRestore  = "content.restore"
+	PermContentRollback = "content.rollback"
+	PermThreadManage    = "thread.manage" // moving, merging and splitting threads
+	PermReportManage    = "report.manage"
+	PermUserSanction    = "user.sanction"
+	PermTopicManage     = "topic.manage"
+	PermAuditView       = "audit.view"
+	PermRoleManage      = "role.manage"
+)
+
+// Models a permission held by a role
+type RolePermission struct {
+	Permission string `json:"permission"`
+	// Whether the permission only covers the user's own posts / comments
+	OwnOnly bool `json:"own_only"`
+}
+
+// Models a role and everything it allows
+type Role struct {
+	Name        string           `json:"name"`
+	Permissions []RolePermission `json:"permissions"`
+}
+
+// Models a role held by a user, either globally or for a single topic
+type RoleGrant struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	Role   string `json:"role"`
+	// Topic the role is limited to, 0 / empty for the user's global role
+	TopicID   int       `json:"topic_id,omitempty"`
+	Topic     string    `json:"topic,omitempty"`
+	GrantedBy int       `json:"granted_by"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// Permissions every role starts with, written to the role_permissions table by the roles migration
+// Changing this needs a new migration to update existing databases
+var DefaultRolePermissions = map[string][]RolePermission{
+	RoleAdmin: {
+		{Permission: PermPostCreate}, {Permission: PermPostEdit}, {Permission: PermPostDelete},
+		{Permission: PermCommentCreate}, {Permission: PermCommentEdit}, {Permission: PermCommentDelete},
+		{Permission: PermVote}, {Permission: PermReportCreate},
+		{Permission: PermContentRestore}, {Permission: PermContentRollback}, {Permission: PermThreadManage},
+		{Permission: PermReportManage}, {Permission: PermUserSanction},
+		{Permission: PermTopicManage}, {Permission: PermAuditView}, {Permission: PermRoleManage},
+	},
+	RoleGlobalModerator: {
+		{Permission: PermPostCreate}, {Permission: PermPostEdit}, {Permission: PermPostDelete},
+		{Permission: PermCommentCreate}, {Permission: PermCommentEdit}, {Permission: PermCommentDelete},
+		{Permission: PermVote}, {Permission: PermReportCreate},
+		{Permission: PermContentRestore}, {Permission: PermContentRollback}, {Permission: PermThreadManage},
+		{Permission: PermReportManage}, {Permission: PermUserSanction},
+	},
+	// Added on top of the user's global role, within the topic
+	RoleTopicModerator: {
+		{Permission: PermPostEdit}, {Permission: PermPostDelete},
+		{Permission: PermCommentEdit}, {Permission: PermCommentDelete},
+		{Permission: PermContentRestore}, {Permission: PermContentRollback}, {Permission: PermThreadManage},
+	},
+	RoleMember: {
+		{Permission: PermPostCreate}, {Permission: PermPostEdit, OwnOnly: true}, {Permission: PermPostDelete, OwnOnly: true},
+		{Permission: PermCommentCreate}, {Permission: PermCommentEdit, OwnOnly: true}, {Permission: PermCommentDelete, OwnOnly: true},
+		{Permission: PermVote}, {Permission: PermReportCreate},
+	},
+	RoleReadOnly: {},
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Kinds of sanctions against a user
+const (
+	SanctionWarning    = "warning"
+	SanctionSuspension = "suspension" // no logging in or acting until it expires
+	SanctionBan        = "ban"        // a suspension that never expires
+	SanctionMute       = "mute"       // reading only, with or without an expiry
+)
+
+// Models a warning or restriction placed on a user by an admin
+type Sanction struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	Kind   string `json:"kind"`
+	Reason string `json:"reason"`
+	// Report that led to the sanction, 0 if none
+	ReportID  int       `json:"report_id,omitempty"`
+	IssuedBy  int       `json:"issued_by"`
+	CreatedAt time.Time `json:"created_at"`
+	// When the sanction stops applying, null if never
+	ExpiresAt *time.Time `json:"expires_at"`
+	// When and by whom the sanction was lifted early, null if it wasn't
+	LiftedAt *time.Time `json:"lifted_at"`
+	LiftedBy int        `json:"lifted_by,omitempty"`
+}
+
+// Whether the sanction still applies at now, i.e. it was neither lifted nor has expired
+func (s *Sanction) ActiveAt(now time.Time) bool {
+	return s.LiftedAt == nil && (s.ExpiresAt == nil || s.ExpiresAt.After(now))
+}
+
+// Whether the sanction locks the user out entirely
+func (s *Sanction) LocksOut() bool {
+	return s.Kind == SanctionSuspension || s.Kind == SanctionBan
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// models a single search hit, either a post or a comment
+type SearchResult struct {
+	Type      string    `json:"type"` // "post" or "comment"
+	PostID    int       `json:"post_id"`
+	CommentID int       `json:"comment_id,omitempty"`
+	Title     string    `json:"title"` // title of the post (the parent post for comments)
+	Topic     string    `json:"topic"`
+	Author    int       `json:"author"`
+	Username  string    `json:"username"`
+	Snippet   string    `json:"snippet"` // matching excerpt as escaped HTML, matches wrapped in <mark></mark>
+	Score     float64   `json:"score"`   // relevance, higher is better
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Models a logged in device, identified by its refresh token
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     int       `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Whether this is the session making the request
+	Current bool `json:"current"`
+
+	// SHA-256 hashes of the current refresh token and the one it replaced
+	RefreshTokenHash  string `json:"-"`
+	PreviousTokenHash string `json:"-"`
+	// ID (jti) and expiry of the latest access token issued for the session
+	AccessTokenID   string    `json:"-"`
+	AccessExpiresAt time.Time `json:"-"`
+	Revoked         bool      `json:"-"`
+}
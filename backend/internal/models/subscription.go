@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Kinds of thing users can subscribe to
+const (
+	SubscriptionTopic = "topic"
+	SubscriptionPost  = "post"
+)
+
+// Models a user watching a topic (its new posts, subtopics included) or a post (its new comments),
+// or muting a post so nothing about it notifies them
+type Subscription struct {
+	UserID     int    `json:"user_id"`
+	TargetType string `json:"target_type"`
+	TargetID   int    `json:"target_id"`
+	// Name of the topic or title of the post
+	TargetName string `json:"target_name"`
+	// Only posts can be muted
+	Muted     bool      `json:"muted"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Models which posts a user is subscribed to automatically
+type SubscriptionSettings struct {
+	OnPost    bool `json:"on_post"`    // the posts they create
+	OnComment bool `json:"on_comment"` // the posts they comment on
+}
+
+// Settings of users who never changed them
+var DefaultSubscriptionSettings = SubscriptionSettings{OnPost: true, OnComment: true}
+
+// How the current user follows a post, as reported with the post
+const (
+	PostWatching = "watching"
+	PostMuted    = "muted"
+)
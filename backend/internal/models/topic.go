@@ -2,5 +2,19 @@ package models
 
 // models a topic
 type Topic struct {
+	ID        int    `json:"id"`
 	TopicName string `json:"topic_name"`
+	// URL-safe identifier, derived from the name unless chosen explicitly
+	Slug        string `json:"slug"`
+	Description string `json:"description"`
+	// Icon shown next to the topic (e.g. an emoji or icon name), chosen by the frontend
+	Icon string `json:"icon"`
+	// Topics are listed by ascending position, among their siblings in the tree
+	Position int `json:"position"`
+	// Topic this one is a subtopic of, 0 for top-level topics
+	ParentID int `json:"parent_id"`
+	// Subtopics, only filled in when topics are listed as a tree
+	Children []Topic `json:"children,omitempty"`
+	// Whether the current user watches the topic, only filled in when topics are listed
+	Subscribed bool `json:"subscribed,omitempty"`
 }
@@ -0,0 +1,171 @@
+// Package pagination implements opaque keyset cursors shared by every list endpoint.
+//
+// A cursor records the sort order it was issued for, the sort key of the last item
+// on the page and that item's ID, which breaks ties between equal sort keys.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// Position after the last item of a page
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// Cursor for an item sorted by a timestamp
+func TimeCursor(sort string, t time.Time, id int) Cursor {
+	return Cursor{Sort: sort, Value: t.UTC().Format(time.RFC3339Nano), ID: id}
+}
+
+// Cursor for an item sorted by an integer
+func IntCursor(sort string, n int, id int) Cursor {
+	return Cursor{Sort: sort, Value: strconv.Itoa(n), ID: id}
+}
+
+// Cursor for an item sorted by a floating point rank
+func FloatCursor(sort string, f float64, id int) Cursor {
+	return Cursor{Sort: sort, Value: strconv.FormatFloat(f, 'g', -1, 64), ID: id}
+}
+
+// Sort key of a TimeCursor
+func (c Cursor) Time() (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, c.Value)
+	if err != nil {
+		return time.Time{}, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// Sort key of an IntCursor
+func (c Cursor) Int() (int, error) {
+	n, err := strconv.Atoi(c.Value)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return n, nil
+}
+
+// Sort key of a FloatCursor
+func (c Cursor) Float() (float64, error) {
+	f, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return f, nil
+}
+
+// Encode the cursor into the opaque string handed to clients
+func (c Cursor) Encode() string {
+	encoded, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// Decode a cursor produced by Encode
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Sort == "" {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// Models the paging request of a list endpoint
+type Params struct {
+	Sort  string
+	Limit int
+	// Return only items after this cursor, nil for the first page
+	After *Cursor
+}
+
+// Read ?limit=, ?cursor= and ?sort= from the request
+// sorts lists the sort orders the endpoint supports, the first one is the default
+func FromRequest(r *http.Request, sorts ...string) (Params, error) {
+	values := r.URL.Query()
+	params := Params{Sort: sorts[0], Limit: DefaultLimit}
+
+	if sort := values.Get("sort"); sort != "" {
+		if !contains(sorts, sort) {
+			return Params{}, ErrInvalidSort
+		}
+		params.Sort = sort
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			return Params{}, ErrInvalidLimit
+		}
+		params.Limit = n
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		c, err := DecodeCursor(cursor)
+		// A cursor only makes sense for the sort order it was issued for
+		if err != nil || c.Sort != params.Sort {
+			return Params{}, ErrInvalidCursor
+		}
+		params.After = &c
+	}
+
+	return params, nil
+}
+
+func contains(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Params asking the store for one extra item, used by NewPage to detect a following page
+func (p Params) Probe() Params {
+	p.Limit++
+	return p
+}
+
+// Response envelope for one page of a list endpoint
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// Cursor for the following page, null on the last page
+	NextCursor *string `json:"next_cursor"`
+}
+
+// Build a page from items fetched with params.Probe()
+// cursorOf produces the cursor positioned after a given item
+func NewPage[T any](items []T, params Params, cursorOf func(T) Cursor) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) <= params.Limit {
+		return Page[T]{Items: items}
+	}
+
+	items = items[:params.Limit]
+	next := cursorOf(items[len(items)-1]).Encode()
+	return Page[T]{Items: items, NextCursor: &next}
+}
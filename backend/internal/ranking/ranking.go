@@ -0,0 +1,31 @@
+// Package ranking computes the cached sort keys derived from votes.
+package ranking
+
+import (
+	"math"
+	"time"
+)
+
+// Reference point for hot ranks, any fixed date works as long as it never changes
+var hotEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Seconds of age that are worth a tenfold increase in score
+const hotDecaySeconds = 45000
+
+// Time-decayed rank used by the "hot" sort
+// Newer posts rank higher, and every order of magnitude of net votes is worth
+// about half a day of age, so old posts need ever more votes to stay on top
+func HotRank(score int, createdAt time.Time) float64 {
+	order := math.Log10(math.Max(math.Abs(float64(score)), 1))
+
+	sign := 0.0
+	if score > 0 {
+		sign = 1
+	} else if score < 0 {
+		sign = -1
+	}
+
+	seconds := createdAt.Sub(hotEpoch).Seconds()
+	// Round so the value survives a round trip through the database and cursors unchanged
+	return math.Round((sign*order+seconds/hotDecaySeconds)*1e7) / 1e7
+}
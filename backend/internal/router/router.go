@@ -1,6 +1,8 @@
 package router
 
 import (
+	"sample-go-app/internal/config"
+	"sample-go-app/internal/handlers"
 	"sample-go-app/internal/routes"
 
 	"github.com/go-chi/chi/v5"
@@ -8,25 +10,26 @@ import (
 	"github.com/go-chi/cors"
 )
 
-func Setup() chi.Router {
+func Setup(h *handlers.Handler, cfg config.ServerConfig) chi.Router {
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID) // Tag each request with an ID, shown in the logs and recorded in the audit log
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
 	// CORS middleware configuration
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:3000"},          // Frontend origins
-		AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE"}, // HTTP methods
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},  // Headers allowed in requests
-		AllowCredentials: true,                                       // Allow cookies and credentials
-		MaxAge:           300,                                        // Cache preflight requests for 5 minutes
+		AllowedOrigins:   cfg.AllowedOrigins,                                         // Frontend origins
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},          // HTTP methods
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "Last-Event-ID"}, // Headers allowed in requests (Last-Event-ID resumes event streams)
+		AllowCredentials: true,                                                       // Allow cookies and credentials
+		MaxAge:           300,                                                        // Cache preflight requests for 5 minutes
 	}))
 
-	setUpRoutes(r)
+	setUpRoutes(r, h)
 	return r
 }
 
-func setUpRoutes(r chi.Router) {
-	r.Group(routes.UnprotectedRoutes())
-	r.Group(routes.ProtectedRoutes())
+func setUpRoutes(r chi.Router, h *handlers.Handler) {
+	r.Group(routes.UnprotectedRoutes(h))
+	r.Group(routes.ProtectedRoutes(h))
 }
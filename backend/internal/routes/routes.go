@@ -3,65 +3,168 @@ package routes
 import (
 	"sample-go-app/internal/auth"
 	"sample-go-app/internal/handlers"
+	"sample-go-app/internal/models"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
 )
 
-func UnprotectedRoutes() func(r chi.Router) {
+func UnprotectedRoutes(h *handlers.Handler) func(r chi.Router) {
 	return func(r chi.Router) {
-		r.Get("/api/topics", handlers.GetTopics)
-		r.Get("/api/topics/{topic}", handlers.GetPostsByTopic)
-		r.Get("/api/posts", handlers.GetAllPosts)
-		r.Get("/api/posts/{post_id}", handlers.GetPostDetails)
-		r.Get("/api/posts/{post_id}/comments", handlers.GetPostComments)
-		r.Get("/api/posts/{post_id}/comments/{comment_id}", handlers.GetComment)
-		r.Get("/api/posts/{post_id}/comments/{comment_id}/subcomments", handlers.GetSubComments)
-
-		r.Post("/api/create_account", handlers.CreateAccount)
-		r.Post("/api/login", handlers.Login)
-		r.Get("/api/logout", handlers.Logout)
-
-		r.Get("/api/users/{user_id}", handlers.GetUsernameByID)
+		// Identify logged in users when possible, without requiring a login
+		r.Use(auth.Verifier(h.TokenDenylist()))
+		r.Use(auth.OptionalIdentityMiddleware())
+
+		r.Get("/api/topics", h.GetTopics)
+		r.Get("/api/topics/{topic}", h.GetPostsByTopic)
+		r.Get("/api/posts", h.GetAllPosts)
+		r.Get("/api/posts/{post_id}", h.GetPostDetails)
+		r.Get("/api/posts/{post_id}/comments", h.GetPostComments)
+		r.Get("/api/posts/{post_id}/comments/tree", h.GetCommentTree)
+		r.Get("/api/posts/{post_id}/comments/{comment_id}", h.GetComment)
+		r.Get("/api/posts/{post_id}/comments/{comment_id}/subcomments", h.GetSubComments)
+		r.Get("/api/posts/{post_id}/revisions", h.GetPostRevisions)
+		r.Get("/api/posts/{post_id}/revisions/diff", h.DiffPostRevisions)
+		r.Get("/api/posts/{post_id}/comments/{comment_id}/revisions", h.GetCommentRevisions)
+		r.Get("/api/posts/{post_id}/comments/{comment_id}/revisions/diff", h.DiffCommentRevisions)
+		r.Get("/api/search", h.Search)
+		r.Get("/api/stream", h.Stream) // checks private channels itself
+
+		r.Post("/api/create_account", h.CreateAccount)
+		r.Post("/api/login", h.Login)
+		r.Post("/api/refresh", h.Refresh)
+		r.Get("/api/logout", h.Logout)
+		r.Post("/api/account/email/verify", h.VerifyEmail) // the token stands in for a login
+
+		r.Get("/api/users/{user_id}", h.GetUsernameByID)
+
+		r.Get("/.well-known/jwks.json", h.JWKS)
 	}
 }
 
-func ProtectedRoutes() func(r chi.Router) {
+func ProtectedRoutes(h *handlers.Handler) func(r chi.Router) {
 	return func(r chi.Router) {
 		// Add JWT authentication middleware
-		r.Use(jwtauth.Verifier(auth.TokenAuth))      // Verify the JWT token
-		r.Use(jwtauth.Authenticator(auth.TokenAuth)) // Enforce authentication
+		r.Use(auth.Verifier(h.TokenDenylist()))       // Verify the JWT token and check it hasn't been revoked
+		r.Use(jwtauth.Authenticator(auth.TokenAuth))  // Enforce authentication
+		r.Use(auth.IdentityMiddleware())              // Extract the current user into the context
+		r.Use(auth.SanctionMiddleware(h.Sanctions())) // Refuse suspended and banned users, flag muted ones
+
+		r.Get("/api/protected", h.Protected)
+
+		r.Get("/api/posts/{post_id}/live", h.LiveThread) // WebSocket
+
+		r.Get("/api/sessions", h.ListSessions)
+		r.Delete("/api/sessions", h.RevokeAllSessions)
+		r.Delete("/api/sessions/{session_id}", h.RevokeSession)
+
+		r.Get("/api/notifications", h.ListNotifications)
+		r.Get("/api/notifications/unread_count", h.GetUnreadNotificationCount)
+		r.Post("/api/notifications/read_all", h.MarkAllNotificationsRead)
+		r.Post("/api/notifications/{notification_id}/read", h.MarkNotificationRead)
+		r.Get("/api/notifications/preferences", h.GetNotificationPreferences)
+		r.Put("/api/notifications/preferences", h.UpdateNotificationPreferences)
+
+		r.Get("/api/subscriptions", h.ListSubscriptions)
+		r.Get("/api/subscriptions/settings", h.GetSubscriptionSettings)
+		r.Put("/api/subscriptions/settings", h.UpdateSubscriptionSettings)
+		r.Put("/api/topics/{topic}/subscription", h.SubscribeTopic)
+		r.Delete("/api/topics/{topic}/subscription", h.UnsubscribeTopic)
+		r.Put("/api/posts/{post_id}/subscription", h.SubscribePost)
+		r.Delete("/api/posts/{post_id}/subscription", h.UnsubscribePost)
 
-		r.Get("/api/protected", handlers.Protected)
+		r.Get("/api/account/email", h.GetEmailSettings)
+		r.Put("/api/account/email", h.SetEmail)
+		r.Delete("/api/account/email", h.RemoveEmail)
+		r.Put("/api/account/email/settings", h.UpdateEmailSettings)
 
-		r.Post("/api/posts", handlers.AddPost)
+		policy := h.Policy()
 
-		// Admin / Owners for post-based action
+		r.Post("/api/posts", h.AddPost) // checks the topic of the new post itself
+
+		// Owners (own-only permissions) or moderators of the post's topic
+		r.With(auth.RoleMiddleware(policy, models.PermPostEdit, h.PostResource)).Patch("/api/posts/{post_id}", h.UpdatePost)
+		r.With(auth.RoleMiddleware(policy, models.PermPostDelete, h.PostResource)).Delete("/api/posts/{post_id}", h.DeletePost)
+
+		// Actions open to members, which read-only users lose
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RoleMiddleware(policy, models.PermVote, h.PostResource))
+
+			r.Post("/api/posts/{post_id}/vote", h.VotePost)
+			r.Delete("/api/posts/{post_id}/vote", h.UnvotePost)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RoleMiddleware(policy, models.PermCommentCreate, h.PostResource))
+
+			r.Post("/api/posts/{post_id}/comments", h.AddPostComment)             // add new comment to the post
+			r.Post("/api/posts/{post_id}/comments/{comment_id}", h.AddSubComment) //add new subcomment
+		})
 		r.Group(func(r chi.Router) {
-			r.Use(auth.RoleMiddleware(handlers.GetPostOwnerID))
+			r.Use(auth.RoleMiddleware(policy, models.PermVote, h.CommentResource))
 
-			r.Patch("/api/posts/{post_id}", handlers.UpdatePost)
-			r.Delete("/api/posts/{post_id}", handlers.DeletePost)
+			r.Post("/api/posts/{post_id}/comments/{comment_id}/vote", h.VoteComment)
+			r.Delete("/api/posts/{post_id}/comments/{comment_id}/vote", h.UnvoteComment)
 		})
+		r.With(auth.PermissionMiddleware(policy, models.PermReportCreate)).Post("/api/reports", h.CreateReport)
 
-		r.Post("/api/posts/{post_id}/comments", handlers.AddPostComment)             // add new comment to the post
-		r.Post("/api/posts/{post_id}/comments/{comment_id}", handlers.AddSubComment) //add new subcomment
+		// Owners (own-only permissions) or moderators of the comment's topic
+		r.With(auth.RoleMiddleware(policy, models.PermCommentEdit, h.CommentResource)).Patch("/api/posts/{post_id}/comments/{comment_id}", h.UpdateComment)
+		r.With(auth.RoleMiddleware(policy, models.PermCommentDelete, h.CommentResource)).Delete("/api/posts/{post_id}/comments/{comment_id}", h.DeleteComment)
 
-		// Admin / Owners for comment-based actions
+		// Moderation of a topic's content
 		r.Group(func(r chi.Router) {
-			// Middleware for role-based access
-			r.Use(auth.RoleMiddleware(handlers.GetCommentOwnerID))
+			r.Use(auth.RoleMiddleware(policy, models.PermContentRestore, h.PostResource))
 
-			r.Patch("/api/posts/{post_id}/comments/{comment_id}", handlers.UpdateComment)
-			r.Delete("/api/posts/{post_id}/comments/{comment_id}", handlers.DeleteComment)
+			r.Post("/api/posts/{post_id}/restore", h.RestorePost)
 		})
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RoleMiddleware(policy, models.PermContentRestore, h.CommentResource))
+
+			r.Post("/api/posts/{post_id}/comments/{comment_id}/restore", h.RestoreComment)
+		})
+		r.With(auth.RoleMiddleware(policy, models.PermContentRollback, h.PostResource)).Post("/api/posts/{post_id}/revisions/{revision}/rollback", h.RollbackPost)
+		r.With(auth.RoleMiddleware(policy, models.PermContentRollback, h.CommentResource)).Post("/api/posts/{post_id}/comments/{comment_id}/revisions/{revision}/rollback", h.RollbackComment)
+
+		// Reorganising threads, the handlers check the destination topic / post as well
+		r.With(auth.RoleMiddleware(policy, models.PermThreadManage, h.PostResource)).Post("/api/posts/{post_id}/move", h.MovePost)
+		r.With(auth.RoleMiddleware(policy, models.PermThreadManage, h.PostResource)).Post("/api/posts/{post_id}/merge", h.MergePost)
+		r.With(auth.RoleMiddleware(policy, models.PermThreadManage, h.CommentResource)).Post("/api/posts/{post_id}/comments/{comment_id}/split", h.SplitComment)
+
+		// Forum-wide administration
+		r.Group(func(r chi.Router) {
+			r.Use(auth.PermissionMiddleware(policy, models.PermTopicManage))
+
+			r.Post("/api/topics", h.AddTopic)
+			r.Patch("/api/topics/{topic}", h.UpdateTopic)
+			r.Delete("/api/topics/{topic}", h.DeleteTopic)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(auth.PermissionMiddleware(policy, models.PermReportManage))
+
+			r.Get("/api/admin/reports", h.ListReports)
+			r.Get("/api/admin/reports/{report_id}", h.GetReport)
+			r.Post("/api/admin/reports/{report_id}/resolve", h.ResolveReport)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(auth.PermissionMiddleware(policy, models.PermUserSanction))
 
-		// Admin-only action
+			r.Get("/api/admin/users/{user_id}/sanctions", h.ListUserSanctions)
+			r.Post("/api/admin/users/{user_id}/sanctions", h.SanctionUser)
+			r.Post("/api/admin/users/{user_id}/sanctions/{sanction_id}/lift", h.LiftSanction)
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(auth.PermissionMiddleware(policy, models.PermAuditView))
+
+			r.Get("/api/admin/audit", h.ListAuditLog)
+			r.Get("/api/admin/audit/export", h.ExportAuditLog)
+		})
 		r.Group(func(r chi.Router) {
-			r.Use(auth.AdminMiddleware())
+			r.Use(auth.PermissionMiddleware(policy, models.PermRoleManage))
 
-			r.Post("/api/topics", handlers.AddTopic)
-			r.Delete("/api/topics/{topic_name}", handlers.DeleteTopic)
+			r.Get("/api/admin/roles", h.ListRoles)
+			r.Get("/api/admin/users/{user_id}/roles", h.GetUserRoles)
+			r.Post("/api/admin/users/{user_id}/roles", h.GrantRole)
+			r.Delete("/api/admin/users/{user_id}/roles/{grant_id}", h.RevokeRole)
 		})
 	}
 }
@@ -0,0 +1,165 @@
+// Package search parses user-entered search text into a structured query
+// that storage backends can translate into their own full-text syntax.
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var ErrEmptyQuery = errors.New("search query has no searchable words")
+
+// Models one required part of a query: a single word, or a quoted phrase of several words
+type Term struct {
+	Words []string
+	// Match any word starting with the last word (e.g. algo*)
+	Prefix bool
+}
+
+// Whether the term is a multi-word phrase
+func (t Term) Phrase() bool {
+	return len(t.Words) > 1
+}
+
+// Models a parsed search; every term must match
+type Query struct {
+	Terms []Term
+}
+
+// Parse search text such as `"binary search" tree algo*`
+// Quoted text is matched as a phrase, a trailing * turns a word into a prefix match,
+// and all other punctuation is ignored so user input can never produce invalid syntax
+func Parse(raw string) (Query, error) {
+	query := Query{}
+
+	rest := raw
+	for rest != "" {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		var chunk string
+		if rest[0] == '"' {
+			// Phrase: everything up to the closing quote (or the end of the input)
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				chunk, rest = rest[1:], ""
+			} else {
+				chunk, rest = rest[1:end+1], rest[end+2:]
+			}
+			addTerm(&query, chunk, true)
+			continue
+		}
+
+		// Bare word: everything up to the next space or quote
+		end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+		if end < 0 {
+			chunk, rest = rest, ""
+		} else {
+			chunk, rest = rest[:end], rest[end:]
+		}
+		addTerm(&query, chunk, false)
+	}
+
+	if len(query.Terms) == 0 {
+		return Query{}, ErrEmptyQuery
+	}
+	return query, nil
+}
+
+// Split chunk into words and add them to the query, as one phrase or as separate terms
+func addTerm(query *Query, chunk string, phrase bool) {
+	prefix := strings.HasSuffix(strings.TrimSpace(chunk), "*")
+	words := Words(chunk)
+	if len(words) == 0 {
+		return
+	}
+
+	if phrase {
+		query.Terms = append(query.Terms, Term{Words: words, Prefix: prefix})
+		return
+	}
+	// Punctuation inside a bare word (e.g. "c++/java") splits it into separate words
+	for i, word := range words {
+		query.Terms = append(query.Terms, Term{Words: []string{word}, Prefix: prefix && i == len(words)-1})
+	}
+}
+
+// Lower-cased runs of letters and digits in text
+func Words(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Render the query in SQLite FTS5 syntax, every word is quoted so it is never read as an operator
+func (q Query) FTS5() string {
+	parts := make([]string, 0, len(q.Terms))
+	for _, term := range q.Terms {
+		part := `"` + strings.Join(term.Words, " ") + `"`
+		if term.Prefix {
+			part += "*"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Render the query in PostgreSQL to_tsquery syntax
+func (q Query) TSQuery() string {
+	parts := make([]string, 0, len(q.Terms))
+	for _, term := range q.Terms {
+		words := make([]string, len(term.Words))
+		copy(words, term.Words)
+		if term.Prefix {
+			words[len(words)-1] += ":*"
+		}
+		part := strings.Join(words, " <-> ")
+		if term.Phrase() {
+			part = "(" + part + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " & ")
+}
+
+// Whether text matches every term, used by backends without a full-text index
+// Returns the number of matched terms as a crude relevance score
+func (q Query) Matches(text string) (bool, int) {
+	words := Words(text)
+	score := 0
+	for _, term := range q.Terms {
+		hits := countPhrase(words, term)
+		if hits == 0 {
+			return false, 0
+		}
+		score += hits
+	}
+	return true, score
+}
+
+// Count the occurrences of a term's words, in order, within words
+func countPhrase(words []string, term Term) int {
+	hits := 0
+	last := len(term.Words) - 1
+	for i := 0; i+last < len(words); i++ {
+		matched := true
+		for j, want := range term.Words {
+			got := words[i+j]
+			if j == last && term.Prefix {
+				matched = strings.HasPrefix(got, want)
+			} else {
+				matched = got == want
+			}
+			if !matched {
+				break
+			}
+		}
+		if matched {
+			hits++
+		}
+	}
+	return hits
+}
@@ -0,0 +1,40 @@
+// Package slug turns display names into URL-safe identifiers.
+package slug
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Longest slug Make produces, in bytes
+const MaxLength = 64
+
+var valid = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Derive a slug from a name: lowercase ASCII letters and digits, with every other run of characters
+// turned into a single hyphen (e.g. "Computer Science" becomes "computer-science")
+// Names without any ASCII letter or digit give an empty slug
+func Make(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if b.Len() >= MaxLength {
+			break
+		}
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			hyphen = true
+			continue
+		}
+		if hyphen && b.Len() > 0 && b.Len() < MaxLength-1 {
+			b.WriteByte('-')
+		}
+		hyphen = false
+		b.WriteRune(r)
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// Whether s is a well-formed slug
+func Valid(s string) bool {
+	return len(s) <= MaxLength && valid.MatchString(s)
+}
@@ -0,0 +1,41 @@
+package memory
+
+import (
+	"context"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+func (s *Store) AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = s.newID()
+	stored := *entry
+	stored.ActorUsername = ""
+	s.auditLog = append(s.auditLog, stored)
+	return nil
+}
+
+func (s *Store) ListAuditEntries(ctx context.Context, filter store.AuditFilter, page pagination.Params) ([]models.AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []models.AuditEntry{}
+	for _, entry := range s.auditLog {
+		if (filter.ActorID == 0 || entry.ActorID == filter.ActorID) &&
+			(filter.Action == "" || entry.Action == filter.Action) &&
+			(filter.TargetType == "" || entry.TargetType == filter.TargetType) &&
+			(filter.TargetID == "" || entry.TargetID == filter.TargetID) &&
+			(filter.From.IsZero() || !entry.CreatedAt.Before(filter.From)) &&
+			(filter.To.IsZero() || entry.CreatedAt.Before(filter.To)) {
+			entry.ActorUsername = s.usernameOf(entry.ActorID)
+			entries = append(entries, entry)
+		}
+	}
+	return paginate(entries, page, page.Sort != store.SortOldest, timeCursor, func(e models.AuditEntry) sortKey {
+		return sortKey{value: e.CreatedAt.UnixNano(), id: e.ID}
+	})
+}
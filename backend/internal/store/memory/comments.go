@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+// Get a page of the comments matching keep, leaving out those of deleted and hidden posts
+func (s *Store) listComments(keep func(models.Comment) bool, page pagination.Params) ([]models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := []models.Comment{}
+	for _, comment := range s.comments {
+		if !keep(comment) || !s.postLive(comment.PostID) {
+			continue
+		}
+		comments = append(comments, s.commentView(comment))
+	}
+	return paginateComments(comments, page)
+}
+
+func (s *Store) ListPostComments(ctx context.Context, postID int, page pagination.Params) ([]models.Comment, error) {
+	return s.listComments(func(c models.Comment) bool { return c.PostID == postID && c.ParentID == 0 }, page)
+}
+
+func (s *Store) ListSubComments(ctx context.Context, commentID int, page pagination.Params) ([]models.Comment, error) {
+	return s.listComments(func(c models.Comment) bool { return c.ParentID == commentID }, page)
+}
+
+func (s *Store) GetComment(ctx context.Context, id int) (models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.commentReadable(id) {
+		return models.Comment{}, store.ErrNotFound
+	}
+	return s.commentView(s.comments[id]), nil
+}
+
+func (s *Store) CreateComment(ctx context.Context, comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Only live posts and comments can be replied to, merged posts having handed their thread over
+	if !s.postLive(comment.PostID) || s.posts[comment.PostID].MergedInto != 0 {
+		return store.ErrNotFound
+	}
+	if comment.ParentID != 0 && (!s.commentLive(comment.ParentID) || s.comments[comment.ParentID].PostID != comment.PostID) {
+		return store.ErrNotFound
+	}
+	post := s.posts[comment.PostID]
+
+	comment.ID = s.newID()
+	stored := *comment
+	stored.Username = ""
+	s.comments[comment.ID] = stored
+
+	// Keep the post's sort keys up to date
+	post.CommentCount++
+	post.LastActivityAt = comment.CreatedAt
+	s.posts[post.ID] = post
+	return nil
+}
+
+func (s *Store) UpdateCommentContent(ctx context.Context, id int, content string, editedBy int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.commentLive(id) || !s.commentReadable(id) {
+		return store.ErrNotFound
+	}
+	comment := s.comments[id]
+	original := models.Revision{Content: comment.Content, Author: comment.Author, CreatedAt: comment.CreatedAt}
+	edit := models.Revision{Content: content, Author: editedBy, CreatedAt: at}
+	s.commentRevisions[id] = appendRevision(s.commentRevisions[id], original, edit)
+
+	comment.Content = content
+	comment.EditedAt = &at
+	comment.RevisionCount++
+	s.comments[id] = comment
+	return nil
+}
+
+func (s *Store) DeleteComment(ctx context.Context, id, deletedBy int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.commentLive(id) || !s.commentReadable(id) {
+		return store.ErrNotFound
+	}
+	s.deletedComments[id] = deletion{at: at, by: deletedBy}
+	s.adjustCommentCountLocked(s.comments[id].PostID, -1)
+	return nil
+}
+
+func (s *Store) RestoreComment(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, deleted := s.deletedComments[id]
+	_, hidden := s.hiddenComments[id]
+	if !deleted && !hidden {
+		return store.ErrNotFound
+	}
+	delete(s.deletedComments, id)
+	delete(s.hiddenComments, id)
+	s.adjustCommentCountLocked(s.comments[id].PostID, 1)
+	return nil
+}
+
+// Add delta to a post's comment count (callers must hold the write lock)
+func (s *Store) adjustCommentCountLocked(postID, delta int) {
+	if post, ok := s.posts[postID]; ok {
+		post.CommentCount += delta
+		s.posts[post.ID] = post
+	}
+}
+
+func (s *Store) GetCommentOwner(ctx context.Context, id int) (int, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments[id]
+	if !ok {
+		return -1, 0, store.ErrNotFound
+	}
+	return comment.Author, s.posts[comment.PostID].TopicID, nil
+}
@@ -0,0 +1,245 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// A user's verified address
+type verifiedEmail struct {
+	address    string
+	verifiedAt time.Time
+}
+
+func (s *Store) EmailSettings(ctx context.Context, userID int) (models.EmailSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.users[userID]; !ok {
+		return models.EmailSettings{}, store.ErrNotFound
+	}
+	settings := models.DefaultEmailSettings
+	if stored, ok := s.emailSettings[userID]; ok {
+		settings.Digest = stored.Digest
+		settings.Notifications = stored.Notifications
+	}
+	if email, ok := s.userEmails[userID]; ok {
+		settings.Email = email.address
+		settings.VerifiedAt = &email.verifiedAt
+	}
+	for _, verification := range s.emailVerifications {
+		if verification.UserID == userID {
+			settings.PendingEmail = verification.Email
+			settings.PendingSince = &verification.CreatedAt
+		}
+	}
+	return settings, nil
+}
+
+func (s *Store) SetEmailSettings(ctx context.Context, userID int, settings models.EmailSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.emailSettings[userID] = models.EmailSettings{Digest: settings.Digest, Notifications: settings.Notifications}
+	return nil
+}
+
+func (s *Store) EmailInUse(ctx context.Context, email string, exceptUserID int) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for userID, verified := range s.userEmails {
+		if verified.address == email && userID != exceptUserID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *Store) AddEmailVerification(ctx context.Context, verification models.EmailVerification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleteEmailVerificationsLocked(verification.UserID)
+	s.emailVerifications[verification.TokenHash] = verification
+
+	cutoff := verification.CreatedAt.Add(-store.EmailVerificationLogRetention)
+	sent := []time.Time{}
+	for _, at := range s.verificationsSent[verification.UserID] {
+		if !at.Before(cutoff) {
+			sent = append(sent, at)
+		}
+	}
+	s.verificationsSent[verification.UserID] = append(sent, verification.CreatedAt)
+	return nil
+}
+
+func (s *Store) CountEmailVerifications(ctx context.Context, userID int, since time.Time) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, at := range s.verificationsSent[userID] {
+		if !at.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) VerifyEmail(ctx context.Context, tokenHash string, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	verification, ok := s.emailVerifications[tokenHash]
+	if !ok || !verification.ExpiresAt.After(at) {
+		return 0, store.ErrNotFound
+	}
+	for userID, verified := range s.userEmails {
+		if verified.address == verification.Email && userID != verification.UserID {
+			return 0, store.ErrConflict
+		}
+	}
+
+	s.userEmails[verification.UserID] = verifiedEmail{address: verification.Email, verifiedAt: at}
+	s.deleteEmailVerificationsLocked(verification.UserID)
+	for hash, other := range s.emailVerifications {
+		if !other.ExpiresAt.After(at) {
+			delete(s.emailVerifications, hash)
+		}
+	}
+	return verification.UserID, nil
+}
+
+func (s *Store) RemoveEmail(ctx context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, verified := s.userEmails[userID]
+	pending := s.deleteEmailVerificationsLocked(userID)
+	if !verified && !pending {
+		return store.ErrNotFound
+	}
+	delete(s.userEmails, userID)
+	return nil
+}
+
+// Delete a user's pending addresses, reporting whether there were any (callers must hold the write lock)
+func (s *Store) deleteEmailVerificationsLocked(userID int) bool {
+	deleted := false
+	for hash, verification := range s.emailVerifications {
+		if verification.UserID == userID {
+			delete(s.emailVerifications, hash)
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+func (s *Store) EnqueueEmail(ctx context.Context, email *models.Email) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email.ID = s.newID()
+	nextAttempt := email.CreatedAt
+	email.NextAttemptAt = &nextAttempt
+	s.outbox[email.ID] = *email
+	return nil
+}
+
+func (s *Store) DueEmails(ctx context.Context, now time.Time, limit int) ([]models.Email, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	emails := []models.Email{}
+	for _, email := range s.outbox {
+		if email.NextAttemptAt != nil && !email.NextAttemptAt.After(now) {
+			emails = append(emails, email)
+		}
+	}
+	sort.Slice(emails, func(i, j int) bool {
+		if !emails[i].NextAttemptAt.Equal(*emails[j].NextAttemptAt) {
+			return emails[i].NextAttemptAt.Before(*emails[j].NextAttemptAt)
+		}
+		return emails[i].ID < emails[j].ID
+	})
+	if len(emails) > limit {
+		emails = emails[:limit]
+	}
+	return emails, nil
+}
+
+func (s *Store) MarkEmailSent(ctx context.Context, id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email, ok := s.outbox[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	email.SentAt = &at
+	email.NextAttemptAt = nil
+	s.outbox[id] = email
+	return nil
+}
+
+func (s *Store) MarkEmailFailed(ctx context.Context, id int, reason string, at, retryAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email, ok := s.outbox[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+	email.Attempts++
+	email.LastError = reason
+	if retryAt.IsZero() {
+		email.NextAttemptAt = nil
+		email.FailedAt = &at
+	} else {
+		email.NextAttemptAt = &retryAt
+	}
+	s.outbox[id] = email
+	return nil
+}
+
+func (s *Store) DigestRecipients(ctx context.Context) ([]models.DigestRecipient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	recipients := []models.DigestRecipient{}
+	for userID, email := range s.userEmails {
+		digest := models.DefaultEmailSettings.Digest
+		if settings, ok := s.emailSettings[userID]; ok {
+			digest = settings.Digest
+		}
+		if digest == models.DigestOff {
+			continue
+		}
+		since := email.verifiedAt
+		if sent, ok := s.digestsSent[userID]; ok {
+			since = sent
+		}
+		recipients = append(recipients, models.DigestRecipient{
+			UserID:   userID,
+			Username: s.usernameOf(userID),
+			Email:    email.address,
+			Digest:   digest,
+			Since:    since,
+		})
+	}
+	sort.Slice(recipients, func(i, j int) bool { return recipients[i].UserID < recipients[j].UserID })
+	return recipients, nil
+}
+
+func (s *Store) MarkDigestSent(ctx context.Context, userID int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.digestsSent[userID] = at
+	return nil
+}
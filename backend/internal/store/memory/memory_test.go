@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"sample-go-app/internal/store"
+	"sample-go-app/internal/store/memory"
+	"sample-go-app/internal/store/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Stores {
+		return memory.New().Stores()
+	})
+}
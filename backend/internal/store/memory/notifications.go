@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+func (s *Store) AddNotifications(ctx context.Context, notifications []models.Notification) ([]models.Notification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := []models.Notification{}
+	for _, notification := range notifications {
+		if enabled, ok := s.notificationPreferences[notification.UserID][notification.Type]; ok && !enabled {
+			continue
+		}
+		notification.ID = s.newID()
+		added = append(added, notification)
+
+		notification.ActorUsername = ""
+		notification.PostTitle = ""
+		notification.ReadAt = nil
+		s.notifications[notification.ID] = notification
+	}
+	return added, nil
+}
+
+func (s *Store) ListNotifications(ctx context.Context, userID int, unreadOnly bool, page pagination.Params) ([]models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	notifications := []models.Notification{}
+	for _, notification := range s.notifications {
+		if notification.UserID != userID || (unreadOnly && notification.ReadAt != nil) {
+			continue
+		}
+		notification.ActorUsername = s.usernameOf(notification.ActorID)
+		notification.PostTitle = s.posts[notification.PostID].Title
+		notifications = append(notifications, notification)
+	}
+	return paginate(notifications, page, page.Sort != store.SortOldest, timeCursor, func(n models.Notification) sortKey {
+		return sortKey{value: n.CreatedAt.UnixNano(), id: n.ID}
+	})
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, notification := range s.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Store) MarkNotificationRead(ctx context.Context, userID, id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notification, ok := s.notifications[id]
+	if !ok || notification.UserID != userID {
+		return store.ErrNotFound
+	}
+	if notification.ReadAt == nil {
+		notification.ReadAt = &at
+		s.notifications[id] = notification
+	}
+	return nil
+}
+
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, userID int, at time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	marked := 0
+	for id, notification := range s.notifications {
+		if notification.UserID == userID && notification.ReadAt == nil {
+			notification.ReadAt = &at
+			s.notifications[id] = notification
+			marked++
+		}
+	}
+	return marked, nil
+}
+
+func (s *Store) NotificationPreferences(ctx context.Context, userID int) (map[string]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	preferences := map[string]bool{}
+	for _, kind := range models.NotificationTypes {
+		preferences[kind] = true
+	}
+	for kind, enabled := range s.notificationPreferences[userID] {
+		preferences[kind] = enabled
+	}
+	return preferences, nil
+}
+
+func (s *Store) SetNotificationPreferences(ctx context.Context, userID int, preferences map[string]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.notificationPreferences[userID] == nil {
+		s.notificationPreferences[userID] = map[string]bool{}
+	}
+	for kind, enabled := range preferences {
+		s.notificationPreferences[userID][kind] = enabled
+	}
+	return nil
+}
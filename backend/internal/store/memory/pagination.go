@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+// Type of value a sort order's cursor carries
+type cursorKind int
+
+const (
+	timeCursor cursorKind = iota
+	intCursor
+	floatCursor
+)
+
+// Sort key of an item, with timestamps expressed in nanoseconds
+// Float ranks are kept separately so large integers never lose precision
+type sortKey struct {
+	value int64
+	rank  float64
+	id    int
+}
+
+// Whether a comes before b in the given direction
+func (a sortKey) before(b sortKey, desc bool) bool {
+	if a.value != b.value {
+		return (a.value < b.value) != desc
+	}
+	if a.rank != b.rank {
+		return (a.rank < b.rank) != desc
+	}
+	// Equal keys are the same item, which comes before neither itself nor the cursor it was paged by
+	return a.id != b.id && (a.id < b.id) != desc
+}
+
+// Sort key of a cursor, mirroring keyOf for the cursor's sort order
+func cursorKey(c *pagination.Cursor, kind cursorKind) (sortKey, error) {
+	switch kind {
+	case intCursor:
+		n, err := c.Int()
+		return sortKey{value: int64(n), id: c.ID}, err
+	case floatCursor:
+		f, err := c.Float()
+		return sortKey{rank: f, id: c.ID}, err
+	}
+	t, err := c.Time()
+	return sortKey{value: t.UnixNano(), id: c.ID}, err
+}
+
+// Sort items and cut out the requested page
+func paginate[T any](items []T, page pagination.Params, desc bool, kind cursorKind, keyOf func(T) sortKey) ([]T, error) {
+	sort.Slice(items, func(i, j int) bool { return keyOf(items[i]).before(keyOf(items[j]), desc) })
+
+	if page.After != nil {
+		after, err := cursorKey(page.After, kind)
+		if err != nil {
+			return nil, err
+		}
+		start := sort.Search(len(items), func(i int) bool { return after.before(keyOf(items[i]), desc) })
+		items = items[start:]
+	}
+
+	if len(items) > page.Limit {
+		items = items[:page.Limit]
+	}
+	return items, nil
+}
+
+// Page through posts in the given sort order
+func paginatePosts(posts []models.Post, page pagination.Params) ([]models.Post, error) {
+	switch {
+	case page.Sort == store.SortOldest:
+		return paginate(posts, page, false, timeCursor, func(p models.Post) sortKey { return sortKey{value: p.CreatedAt.UnixNano(), id: p.ID} })
+	case page.Sort == store.SortMostCommented:
+		return paginate(posts, page, true, intCursor, func(p models.Post) sortKey { return sortKey{value: int64(p.CommentCount), id: p.ID} })
+	case page.Sort == store.SortActivity:
+		return paginate(posts, page, true, timeCursor, func(p models.Post) sortKey { return sortKey{value: p.LastActivityAt.UnixNano(), id: p.ID} })
+	case page.Sort == store.SortHot:
+		return paginate(posts, page, true, floatCursor, func(p models.Post) sortKey { return sortKey{rank: p.HotRank, id: p.ID} })
+	case store.IsTopSort(page.Sort):
+		// Windowed top sorts only consider recent posts
+		if window := store.TopWindows[page.Sort]; window > 0 {
+			since := time.Now().UTC().Add(-window)
+			recent := []models.Post{}
+			for _, post := range posts {
+				if !post.CreatedAt.Before(since) {
+					recent = append(recent, post)
+				}
+			}
+			posts = recent
+		}
+		return paginate(posts, page, true, intCursor, func(p models.Post) sortKey { return sortKey{value: int64(p.Score), id: p.ID} })
+	}
+	return paginate(posts, page, true, timeCursor, func(p models.Post) sortKey { return sortKey{value: p.CreatedAt.UnixNano(), id: p.ID} })
+}
+
+// Page through comments in the given sort order
+func paginateComments(comments []models.Comment, page pagination.Params) ([]models.Comment, error) {
+	desc := page.Sort != store.SortOldest
+	return paginate(comments, page, desc, timeCursor, func(c models.Comment) sortKey { return sortKey{value: c.CreatedAt.UnixNano(), id: c.ID} })
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/ranking"
+	"sample-go-app/internal/store"
+)
+
+// Get a page of the list view of posts matching keep (content is not loaded, like the SQL store)
+func (s *Store) listPosts(keep func(models.Post) bool, page pagination.Params) ([]models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	posts := []models.Post{}
+	for _, post := range s.posts {
+		if _, deleted := s.deletedPosts[post.ID]; deleted || post.MergedInto != 0 || !keep(post) {
+			continue
+		}
+		post.Content = ""
+		post.Username = s.usernameOf(post.Author)
+		post.Topic = s.topicNameOf(post.TopicID)
+		posts = append(posts, post)
+	}
+	return paginatePosts(posts, page)
+}
+
+func (s *Store) ListPosts(ctx context.Context, page pagination.Params) ([]models.Post, error) {
+	return s.listPosts(func(models.Post) bool { return true }, page)
+}
+
+func (s *Store) ListPostsByTopic(ctx context.Context, topicID int, subtopics bool, page pagination.Params) ([]models.Post, error) {
+	topicIDs := map[int]bool{topicID: true}
+	if subtopics {
+		s.mu.RLock()
+		topicIDs = s.topicSubtreeLocked(topicID)
+		s.mu.RUnlock()
+	}
+	return s.listPosts(func(p models.Post) bool { return topicIDs[p.TopicID] }, page)
+}
+
+func (s *Store) GetPost(ctx context.Context, id int) (models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.postLive(id) {
+		return models.Post{}, store.ErrNotFound
+	}
+	post := s.posts[id]
+	post.Username = s.usernameOf(post.Author)
+	post.Topic = s.topicNameOf(post.TopicID)
+	return post, nil
+}
+
+func (s *Store) CreatePost(ctx context.Context, post *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post.ID = s.newID()
+	post.CommentCount = 0
+	post.LastActivityAt = post.CreatedAt
+	post.Score = 0
+	post.HotRank = ranking.HotRank(0, post.CreatedAt)
+	post.Topic = s.topicNameOf(post.TopicID)
+	stored := *post
+	stored.Username = ""
+	stored.Topic = ""
+	s.posts[post.ID] = stored
+	return nil
+}
+
+func (s *Store) UpdatePost(ctx context.Context, post models.Post, editedBy int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.postLive(post.ID) || s.posts[post.ID].MergedInto != 0 {
+		return store.ErrNotFound
+	}
+	stored := s.posts[post.ID]
+	original := models.Revision{Title: stored.Title, TopicID: stored.TopicID, Content: stored.Content, Author: stored.Author, CreatedAt: stored.CreatedAt}
+	edit := models.Revision{Title: post.Title, TopicID: post.TopicID, Content: post.Content, Author: editedBy, CreatedAt: at}
+	s.postRevisions[post.ID] = appendRevision(s.postRevisions[post.ID], original, edit)
+
+	stored.Title = post.Title
+	stored.TopicID = post.TopicID
+	stored.Content = post.Content
+	stored.EditedAt = &at
+	stored.RevisionCount++
+	s.posts[post.ID] = stored
+	return nil
+}
+
+func (s *Store) DeletePost(ctx context.Context, id, deletedBy int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.postLive(id) || s.posts[id].MergedInto != 0 {
+		return store.ErrNotFound
+	}
+	s.deletedPosts[id] = deletion{at: at, by: deletedBy}
+	return nil
+}
+
+func (s *Store) RestorePost(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, deleted := s.deletedPosts[id]
+	_, hidden := s.hiddenPosts[id]
+	if !deleted && !hidden {
+		return store.ErrNotFound
+	}
+	delete(s.deletedPosts, id)
+	delete(s.hiddenPosts, id)
+	return nil
+}
+
+// Permanently delete a post and its comments, with their votes, notifications and reports (callers must hold the write lock)
+// Posts merged into it become standalone posts again
+func (s *Store) deletePostLocked(id int) {
+	for postID, post := range s.posts {
+		if post.MergedInto == id {
+			post.MergedInto = 0
+			s.posts[postID] = post
+		}
+	}
+	for notificationID, notification := range s.notifications {
+		if notification.PostID == id {
+			delete(s.notifications, notificationID)
+		}
+	}
+	s.deleteReportsLocked(models.ReportPost, id)
+	for commentID, comment := range s.comments {
+		if comment.PostID == id {
+			s.deleteReportsLocked(models.ReportComment, commentID)
+			delete(s.comments, commentID)
+			delete(s.commentVotes, commentID)
+			delete(s.deletedComments, commentID)
+			delete(s.hiddenComments, commentID)
+			delete(s.commentRevisions, commentID)
+		}
+	}
+	delete(s.posts, id)
+	delete(s.postVotes, id)
+	delete(s.deletedPosts, id)
+	delete(s.hiddenPosts, id)
+	delete(s.postRevisions, id)
+	for userID := range s.subscriptions {
+		delete(s.subscriptions[userID], subscriptionKey{models.SubscriptionPost, id})
+	}
+}
+
+// Delete the reports about a post or comment, keeping the sanctions handed out for them (callers must hold the lock)
+func (s *Store) deleteReportsLocked(targetType string, targetID int) {
+	for reportID, report := range s.reports {
+		if report.TargetType != targetType || report.TargetID != targetID {
+			continue
+		}
+		for sanctionID, sanction := range s.sanctions {
+			if sanction.ReportID == reportID {
+				sanction.ReportID = 0
+				s.sanctions[sanctionID] = sanction
+			}
+		}
+		delete(s.reports, reportID)
+	}
+}
+
+func (s *Store) GetPostOwner(ctx context.Context, id int) (int, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	post, ok := s.posts[id]
+	if !ok {
+		return -1, 0, store.ErrNotFound
+	}
+	return post.Author, post.TopicID, nil
+}
@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"sample-go-app/internal/models"
+)
+
+func (s *Store) PurgeDeleted(ctx context.Context, before time.Time) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	posts := 0
+	for id, deleted := range s.deletedPosts {
+		if deleted.at.Before(before) {
+			s.deletePostLocked(id)
+			posts++
+		}
+	}
+
+	// Remove expired comment tombstones from the leaves up, like the SQL store
+	comments := 0
+	for {
+		replied := map[int]bool{}
+		for _, comment := range s.comments {
+			replied[comment.ParentID] = true
+		}
+
+		purged := 0
+		for id, deleted := range s.deletedComments {
+			if deleted.at.Before(before) && !replied[id] {
+				for notificationID, notification := range s.notifications {
+					if notification.CommentID == id {
+						delete(s.notifications, notificationID)
+					}
+				}
+				s.deleteReportsLocked(models.ReportComment, id)
+				delete(s.comments, id)
+				delete(s.commentVotes, id)
+				delete(s.deletedComments, id)
+				delete(s.commentRevisions, id)
+				purged++
+			}
+		}
+		if purged == 0 {
+			return posts, comments, nil
+		}
+		comments += purged
+	}
+}
@@ -0,0 +1,171 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+// Find the user behind a report's target, failing with ErrNotFound if readers can't see it (callers must hold a lock)
+func (s *Store) reportTargetUser(targetType string, targetID int) (int, error) {
+	switch targetType {
+	case models.ReportPost:
+		if s.postLive(targetID) {
+			return s.posts[targetID].Author, nil
+		}
+	case models.ReportComment:
+		if s.commentLive(targetID) && s.postLive(s.comments[targetID].PostID) {
+			return s.comments[targetID].Author, nil
+		}
+	case models.ReportUser:
+		if _, ok := s.users[targetID]; ok {
+			return targetID, nil
+		}
+	}
+	return 0, store.ErrNotFound
+}
+
+func (s *Store) CreateReport(ctx context.Context, report *models.Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	targetUserID, err := s.reportTargetUser(report.TargetType, report.TargetID)
+	if err != nil {
+		return err
+	}
+	// Mirror the unique index on open reports
+	for _, existing := range s.reports {
+		if existing.Status == models.ReportOpen && existing.TargetType == report.TargetType &&
+			existing.TargetID == report.TargetID && existing.ReporterID == report.ReporterID {
+			return store.ErrConflict
+		}
+	}
+
+	report.ID = s.newID()
+	report.TargetUserID = targetUserID
+	report.Status = models.ReportOpen
+	stored := *report
+	stored.ReporterUsername = ""
+	s.reports[report.ID] = stored
+	return nil
+}
+
+// Get a stored report the way reads return it (callers must hold a lock)
+func (s *Store) reportView(report models.Report) models.Report {
+	report.ReporterUsername = s.usernameOf(report.ReporterID)
+	return report
+}
+
+func (s *Store) GetReport(ctx context.Context, id int) (models.Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report, ok := s.reports[id]
+	if !ok {
+		return models.Report{}, store.ErrNotFound
+	}
+	return s.reportView(report), nil
+}
+
+func (s *Store) ListReports(ctx context.Context, filter store.ReportFilter, page pagination.Params) ([]models.Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	reports := []models.Report{}
+	for _, report := range s.reports {
+		if (filter.Status == "" || report.Status == filter.Status) &&
+			(filter.TargetType == "" || report.TargetType == filter.TargetType) &&
+			(filter.Reason == "" || report.Reason == filter.Reason) &&
+			(filter.TargetUserID == 0 || report.TargetUserID == filter.TargetUserID) {
+			reports = append(reports, s.reportView(report))
+		}
+	}
+	return paginate(reports, page, page.Sort == store.SortNewest, timeCursor, func(r models.Report) sortKey {
+		return sortKey{value: r.CreatedAt.UnixNano(), id: r.ID}
+	})
+}
+
+// Check that the post / comment a report targets can be hidden or deleted (callers must hold a lock)
+func (s *Store) checkModeratable(report models.Report) error {
+	switch report.TargetType {
+	case models.ReportPost:
+		if !s.postLive(report.TargetID) {
+			return store.ErrNotFound
+		}
+	case models.ReportComment:
+		if !s.commentLive(report.TargetID) {
+			return store.ErrNotFound
+		}
+	default:
+		return fmt.Errorf("cannot moderate a %s report", report.TargetType)
+	}
+	return nil
+}
+
+func (s *Store) ResolveReport(ctx context.Context, id int, resolution store.Resolution) (models.Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report, ok := s.reports[id]
+	if !ok {
+		return models.Report{}, store.ErrNotFound
+	}
+	if report.Status != models.ReportOpen {
+		return models.Report{}, store.ErrConflict
+	}
+
+	// Step 1: Apply the action, checking everything up front since there is no transaction to roll back
+	reason := resolution.Note
+	if reason == "" {
+		reason = report.Reason
+	}
+	sanction := models.Sanction{UserID: report.TargetUserID, Reason: reason, ReportID: report.ID,
+		IssuedBy: resolution.ResolvedBy, CreatedAt: resolution.At}
+	switch resolution.Action {
+	case models.ActionHide, models.ActionDelete:
+		if err := s.checkModeratable(report); err != nil {
+			return models.Report{}, err
+		}
+		if report.TargetType == models.ReportComment {
+			s.adjustCommentCountLocked(s.comments[report.TargetID].PostID, -1)
+		}
+		switch {
+		case resolution.Action == models.ActionDelete && report.TargetType == models.ReportPost:
+			s.deletedPosts[report.TargetID] = deletion{at: resolution.At, by: resolution.ResolvedBy}
+		case resolution.Action == models.ActionDelete:
+			s.deletedComments[report.TargetID] = deletion{at: resolution.At, by: resolution.ResolvedBy}
+		case report.TargetType == models.ReportPost:
+			s.hiddenPosts[report.TargetID] = resolution.At
+		default:
+			s.hiddenComments[report.TargetID] = resolution.At
+		}
+	case models.ActionWarn:
+		sanction.Kind = models.SanctionWarning
+		s.addSanctionLocked(&sanction)
+	case models.ActionSuspend:
+		sanction.Kind = models.SanctionSuspension
+		sanction.ExpiresAt = &resolution.SuspendUntil
+		s.addSanctionLocked(&sanction)
+	}
+
+	// Step 2: Close every open report against the same target
+	status := models.ReportResolved
+	if resolution.Action == models.ActionDismiss {
+		status = models.ReportDismissed
+	}
+	for reportID, other := range s.reports {
+		if other.Status == models.ReportOpen && other.TargetType == report.TargetType && other.TargetID == report.TargetID {
+			resolvedAt := resolution.At
+			other.Status = status
+			other.ResolvedAt = &resolvedAt
+			other.ResolvedBy = resolution.ResolvedBy
+			other.Action = resolution.Action
+			other.Note = resolution.Note
+			s.reports[reportID] = other
+		}
+	}
+	return s.reportView(s.reports[id]), nil
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"context"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Add an edit to the history of a post or comment, starting it with the original version
+// on the first edit, and return the new history
+func appendRevision(history []models.Revision, original, edit models.Revision) []models.Revision {
+	if len(history) == 0 {
+		original.Revision = 1
+		history = []models.Revision{original}
+	}
+	edit.Revision = len(history) + 1
+	return append(history, edit)
+}
+
+// Copy a history for returning, with usernames and topic names filled in and the last version marked current
+// (callers must hold a lock)
+func (s *Store) revisionsView(history []models.Revision, original models.Revision) []models.Revision {
+	if len(history) == 0 {
+		original.Revision = 1
+		history = []models.Revision{original}
+	}
+
+	revisions := make([]models.Revision, len(history))
+	for i, revision := range history {
+		revision.Username = s.usernameOf(revision.Author)
+		revision.Topic = s.topicNameOf(revision.TopicID)
+		revision.Current = i == len(history)-1
+		revisions[i] = revision
+	}
+	return revisions
+}
+
+func (s *Store) ListPostRevisions(ctx context.Context, postID int) ([]models.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.postLive(postID) {
+		return nil, store.ErrNotFound
+	}
+	post := s.posts[postID]
+	original := models.Revision{Title: post.Title, TopicID: post.TopicID, Content: post.Content, Author: post.Author, CreatedAt: post.CreatedAt}
+	return s.revisionsView(s.postRevisions[postID], original), nil
+}
+
+func (s *Store) ListCommentRevisions(ctx context.Context, commentID int) ([]models.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.commentLive(commentID) || !s.commentReadable(commentID) {
+		return nil, store.ErrNotFound
+	}
+	comment := s.comments[commentID]
+	original := models.Revision{Content: comment.Content, Author: comment.Author, CreatedAt: comment.CreatedAt}
+	return s.revisionsView(s.commentRevisions[commentID], original), nil
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+func (s *Store) RolePermissions(ctx context.Context) (map[string][]models.RolePermission, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	permissions := map[string][]models.RolePermission{}
+	for role, granted := range s.rolePermissions {
+		permissions[role] = append([]models.RolePermission{}, granted...)
+	}
+	return permissions, nil
+}
+
+func (s *Store) UserRoles(ctx context.Context, userID int) ([]models.RoleGrant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	grants := []models.RoleGrant{}
+	for _, grant := range s.userRoles {
+		if grant.UserID == userID {
+			grant.Topic = s.topicNameOf(grant.TopicID)
+			grants = append(grants, grant)
+		}
+	}
+	// Global role first, like the SQL store
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].TopicID != grants[j].TopicID {
+			return s.topics[grants[i].TopicID].Position < s.topics[grants[j].TopicID].Position
+		}
+		return grants[i].Role < grants[j].Role
+	})
+	return grants, nil
+}
+
+func (s *Store) TopicAncestors(ctx context.Context, topicID int) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := []int{}
+	// Walk up from the topic, stopping at the top or at a topic that doesn't exist
+	for id := topicID; id != 0; id = s.topics[id].ParentID {
+		if _, ok := s.topics[id]; !ok {
+			break
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Keep the user's IsAdmin flag in step with their global role (callers must hold the write lock)
+func (s *Store) syncIsAdminLocked(userID int, role string) {
+	user := s.users[userID]
+	user.IsAdmin = 0
+	if role == models.RoleAdmin {
+		user.IsAdmin = 1
+	}
+	s.users[userID] = user
+}
+
+func (s *Store) GrantRole(ctx context.Context, grant *models.RoleGrant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[grant.UserID]; !ok {
+		return store.ErrNotFound
+	}
+	if grant.TopicID != 0 {
+		topic, ok := s.topics[grant.TopicID]
+		if !ok {
+			return store.ErrNotFound
+		}
+		grant.Topic = topic.TopicName
+	}
+
+	for id, existing := range s.userRoles {
+		if existing.UserID != grant.UserID || existing.TopicID != grant.TopicID {
+			continue
+		}
+		if grant.TopicID == 0 {
+			// A user holds a single global role, so the new one replaces the old
+			delete(s.userRoles, id)
+		} else if existing.Role == grant.Role {
+			return store.ErrConflict
+		}
+	}
+	if grant.TopicID == 0 {
+		s.syncIsAdminLocked(grant.UserID, grant.Role)
+	}
+
+	grant.ID = s.newID()
+	stored := *grant
+	stored.Topic = ""
+	s.userRoles[grant.ID] = stored
+	return nil
+}
+
+func (s *Store) RevokeRole(ctx context.Context, grantID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	grant, ok := s.userRoles[grantID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	delete(s.userRoles, grantID)
+	if grant.TopicID == 0 {
+		s.syncIsAdminLocked(grant.UserID, models.RoleMember)
+	}
+	return nil
+}
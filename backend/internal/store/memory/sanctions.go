@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Record a sanction, logging the user out everywhere if it locks them out; the caller must hold s.mu
+func (s *Store) addSanctionLocked(sanction *models.Sanction) {
+	sanction.ID = s.newID()
+	s.sanctions[sanction.ID] = *sanction
+	if sanction.LocksOut() {
+		for _, session := range s.sessions {
+			if session.UserID == sanction.UserID && !session.Revoked {
+				s.revokeSessionLocked(session, sanction.CreatedAt)
+			}
+		}
+	}
+}
+
+// Get the sanctions of a user matching keep, newest first; the caller must hold s.mu
+func (s *Store) userSanctionsLocked(userID int, keep func(models.Sanction) bool) []models.Sanction {
+	sanctions := []models.Sanction{}
+	for _, sanction := range s.sanctions {
+		if sanction.UserID == userID && keep(sanction) {
+			sanctions = append(sanctions, sanction)
+		}
+	}
+	sort.Slice(sanctions, func(i, j int) bool {
+		if !sanctions[i].CreatedAt.Equal(sanctions[j].CreatedAt) {
+			return sanctions[i].CreatedAt.After(sanctions[j].CreatedAt)
+		}
+		return sanctions[i].ID > sanctions[j].ID
+	})
+	return sanctions
+}
+
+func (s *Store) AddSanction(ctx context.Context, sanction *models.Sanction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[sanction.UserID]; !ok {
+		return store.ErrNotFound
+	}
+	s.addSanctionLocked(sanction)
+	return nil
+}
+
+func (s *Store) ListSanctions(ctx context.Context, userID int) ([]models.Sanction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userSanctionsLocked(userID, func(models.Sanction) bool { return true }), nil
+}
+
+func (s *Store) ActiveSanctions(ctx context.Context, userID int, now time.Time) ([]models.Sanction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.userSanctionsLocked(userID, func(sanction models.Sanction) bool {
+		return sanction.Kind != models.SanctionWarning && sanction.ActiveAt(now)
+	}), nil
+}
+
+func (s *Store) LiftSanction(ctx context.Context, userID, id, liftedBy int, at time.Time) (models.Sanction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sanction, ok := s.sanctions[id]
+	if !ok || sanction.UserID != userID {
+		return models.Sanction{}, store.ErrNotFound
+	}
+	if sanction.Kind == models.SanctionWarning || !sanction.ActiveAt(at) {
+		return models.Sanction{}, store.ErrConflict
+	}
+
+	sanction.LiftedAt = &at
+	sanction.LiftedBy = liftedBy
+	s.sanctions[id] = sanction
+	return sanction, nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"html"
+	"sort"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Whether a row passes the non-text search filters
+func matchesFilters(params store.SearchParams, topicID, author int, createdAt time.Time) bool {
+	if params.TopicID != 0 && topicID != params.TopicID {
+		return false
+	}
+	if params.AuthorID != 0 && author != params.AuthorID {
+		return false
+	}
+	if !params.From.IsZero() && createdAt.Before(params.From) {
+		return false
+	}
+	if !params.To.IsZero() && !createdAt.Before(params.To) {
+		return false
+	}
+	return true
+}
+
+// Search by scanning every row; the snippet is the start of the matched text, without highlighting
+func (s *Store) Search(ctx context.Context, params store.SearchParams) ([]models.SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	results := []models.SearchResult{}
+	if params.Type != store.SearchComments {
+		for _, post := range s.posts {
+			if !s.postLive(post.ID) || post.MergedInto != 0 {
+				continue
+			}
+			ok, score := params.Query.Matches(post.Title + " " + post.Content)
+			if !ok || !matchesFilters(params, post.TopicID, post.Author, post.CreatedAt) {
+				continue
+			}
+			results = append(results, models.SearchResult{
+				Type: "post", PostID: post.ID, Title: post.Title, Topic: s.topicNameOf(post.TopicID),
+				Author: post.Author, Username: s.usernameOf(post.Author),
+				Snippet: html.EscapeString(excerpt(post.Content)), Score: float64(score), CreatedAt: post.CreatedAt,
+			})
+		}
+	}
+	if params.Type != store.SearchPosts {
+		for _, comment := range s.comments {
+			if !s.commentLive(comment.ID) || !s.postLive(comment.PostID) {
+				continue
+			}
+			post := s.posts[comment.PostID]
+			ok, score := params.Query.Matches(comment.Content)
+			if !ok || !matchesFilters(params, post.TopicID, comment.Author, comment.CreatedAt) {
+				continue
+			}
+			results = append(results, models.SearchResult{
+				Type: "comment", PostID: post.ID, CommentID: comment.ID, Title: post.Title, Topic: s.topicNameOf(post.TopicID),
+				Author: comment.Author, Username: s.usernameOf(comment.Author),
+				Snippet: html.EscapeString(excerpt(comment.Content)), Score: float64(score), CreatedAt: comment.CreatedAt,
+			})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].CreatedAt.After(results[j].CreatedAt)
+	})
+
+	// Apply paging
+	if params.Offset >= len(results) {
+		return []models.SearchResult{}, nil
+	}
+	results = results[params.Offset:]
+	if params.Limit > 0 && len(results) > params.Limit {
+		results = results[:params.Limit]
+	}
+	return results, nil
+}
+
+// Shorten text to a snippet-sized excerpt
+func excerpt(text string) string {
+	runes := []rune(text)
+	if len(runes) <= 160 {
+		return text
+	}
+	return string(runes[:160]) + "…"
+}
@@ -0,0 +1,126 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Add an access token to the denylist until it expires (callers must hold the write lock)
+func (s *Store) denyTokenLocked(tokenID string, expiresAt, now time.Time) {
+	for id, expiry := range s.revokedTokens {
+		if expiry.Before(now) {
+			delete(s.revokedTokens, id)
+		}
+	}
+	if expiresAt.After(now) {
+		s.revokedTokens[tokenID] = expiresAt
+	}
+}
+
+func (s *Store) CreateSession(ctx context.Context, session models.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[session.ID]; ok {
+		return store.ErrConflict
+	}
+	session.Current = false
+	s.sessions[session.ID] = session
+	return nil
+}
+
+func (s *Store) GetSessionByTokenHash(ctx context.Context, tokenHash string) (models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, session := range s.sessions {
+		if session.RefreshTokenHash == tokenHash || (session.PreviousTokenHash != "" && session.PreviousTokenHash == tokenHash) {
+			return session, nil
+		}
+	}
+	return models.Session{}, store.ErrNotFound
+}
+
+func (s *Store) RotateSession(ctx context.Context, session models.Session, previousHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[session.ID]
+	if !ok || stored.Revoked || stored.RefreshTokenHash != previousHash {
+		return store.ErrNotFound
+	}
+	s.denyTokenLocked(stored.AccessTokenID, stored.AccessExpiresAt, session.LastUsedAt)
+
+	stored.RefreshTokenHash = session.RefreshTokenHash
+	stored.PreviousTokenHash = previousHash
+	stored.AccessTokenID = session.AccessTokenID
+	stored.AccessExpiresAt = session.AccessExpiresAt
+	stored.LastUsedAt = session.LastUsedAt
+	stored.ExpiresAt = session.ExpiresAt
+	s.sessions[session.ID] = stored
+	return nil
+}
+
+func (s *Store) ListUserSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := []models.Session{}
+	for _, session := range s.sessions {
+		if session.UserID == userID && !session.Revoked && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	return sessions, nil
+}
+
+// Revoke a live session and deny its latest access token (callers must hold the write lock)
+func (s *Store) revokeSessionLocked(session models.Session, now time.Time) {
+	session.Revoked = true
+	s.sessions[session.ID] = session
+	s.denyTokenLocked(session.AccessTokenID, session.AccessExpiresAt, now)
+}
+
+func (s *Store) RevokeSession(ctx context.Context, userID int, sessionID string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[sessionID]
+	if !ok || session.UserID != userID || session.Revoked {
+		return store.ErrNotFound
+	}
+	s.revokeSessionLocked(session, now)
+	return nil
+}
+
+func (s *Store) RevokeUserSessions(ctx context.Context, userID int, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revoked := 0
+	for _, session := range s.sessions {
+		if session.UserID == userID && !session.Revoked {
+			s.revokeSessionLocked(session, now)
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (s *Store) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, revoked := s.revokedTokens[tokenID]
+	return revoked, nil
+}
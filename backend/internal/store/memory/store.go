@@ -0,0 +1,183 @@
+// Package memory implements the store interfaces in memory, for use in tests.
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// In-memory implementation of every store interface
+// All data lives behind a single mutex, so cascading deletes stay consistent
+type Store struct {
+	mu       sync.RWMutex
+	users    map[int]models.User
+	posts    map[int]models.Post
+	comments map[int]models.Comment
+	topics   map[int]models.Topic
+	// Votes keyed by post / comment ID, then by user ID
+	postVotes    map[int]map[int]int
+	commentVotes map[int]map[int]int
+	// When and by whom soft deleted posts / comments were deleted, keyed by ID
+	deletedPosts    map[int]deletion
+	deletedComments map[int]deletion
+	// When posts / comments were hidden by a moderator, keyed by ID
+	hiddenPosts    map[int]time.Time
+	hiddenComments map[int]time.Time
+	// Every version of edited posts / comments, oldest first and including the current one
+	postRevisions    map[int][]models.Revision
+	commentRevisions map[int][]models.Revision
+	reports          map[int]models.Report
+	sanctions        map[int]models.Sanction
+	auditLog         []models.AuditEntry
+	// Permissions of each role, and the roles granted to users keyed by grant ID
+	rolePermissions map[string][]models.RolePermission
+	userRoles       map[int]models.RoleGrant
+	notifications   map[int]models.Notification
+	// Notification types each user turned on or off, keyed by user ID
+	notificationPreferences map[int]map[string]bool
+	// Subscriptions and subscription settings keyed by user ID
+	subscriptions        map[int]map[subscriptionKey]models.Subscription
+	subscriptionSettings map[int]models.SubscriptionSettings
+	// Verified addresses and email settings keyed by user ID, and pending addresses keyed by token hash
+	userEmails         map[int]verifiedEmail
+	emailSettings      map[int]models.EmailSettings
+	emailVerifications map[string]models.EmailVerification
+	// When each user's recent verifications were created, oldest first
+	verificationsSent map[int][]time.Time
+	// When each user's last digest was sent
+	digestsSent map[int]time.Time
+	outbox      map[int]models.Email
+	sessions    map[string]models.Session
+	// Expiry of each denied access token, keyed by token ID
+	revokedTokens map[string]time.Time
+	nextID        int
+}
+
+// Create an empty store
+func New() *Store {
+	return &Store{
+		users:    map[int]models.User{},
+		posts:    map[int]models.Post{},
+		comments: map[int]models.Comment{},
+		topics:   map[int]models.Topic{},
+
+		postVotes:    map[int]map[int]int{},
+		commentVotes: map[int]map[int]int{},
+
+		deletedPosts:    map[int]deletion{},
+		deletedComments: map[int]deletion{},
+		hiddenPosts:     map[int]time.Time{},
+		hiddenComments:  map[int]time.Time{},
+
+		postRevisions:    map[int][]models.Revision{},
+		commentRevisions: map[int][]models.Revision{},
+
+		reports:   map[int]models.Report{},
+		sanctions: map[int]models.Sanction{},
+
+		rolePermissions: models.DefaultRolePermissions,
+		userRoles:       map[int]models.RoleGrant{},
+
+		notifications:           map[int]models.Notification{},
+		notificationPreferences: map[int]map[string]bool{},
+
+		subscriptions:        map[int]map[subscriptionKey]models.Subscription{},
+		subscriptionSettings: map[int]models.SubscriptionSettings{},
+
+		userEmails:         map[int]verifiedEmail{},
+		emailSettings:      map[int]models.EmailSettings{},
+		emailVerifications: map[string]models.EmailVerification{},
+		verificationsSent:  map[int][]time.Time{},
+		digestsSent:        map[int]time.Time{},
+		outbox:             map[int]models.Email{},
+
+		sessions:      map[string]models.Session{},
+		revokedTokens: map[string]time.Time{},
+	}
+}
+
+// Get the store interfaces backed by this store
+func (s *Store) Stores() store.Stores {
+	return store.Stores{
+		Posts:         s,
+		Comments:      s,
+		Topics:        s,
+		Users:         s,
+		Search:        s,
+		Votes:         s,
+		Sessions:      s,
+		Purge:         s,
+		Revisions:     s,
+		Reports:       s,
+		Sanctions:     s,
+		Audit:         s,
+		Roles:         s,
+		Threads:       s,
+		Notifications: s,
+		Subscriptions: s,
+		Emails:        s,
+	}
+}
+
+// Records a soft delete
+type deletion struct {
+	at time.Time
+	by int
+}
+
+// Allocate a new ID, unique across every table (callers must hold the write lock)
+func (s *Store) newID() int {
+	s.nextID++
+	return s.nextID
+}
+
+// Look up a username the way the SQL store's LEFT JOIN does (callers must hold a lock)
+func (s *Store) usernameOf(userID int) string {
+	if user, ok := s.users[userID]; ok {
+		return user.Username
+	}
+	return "Unknown"
+}
+
+// Look up a topic name the way the SQL store's LEFT JOIN does (callers must hold a lock)
+// Posts refer to topics by ID only, so renames show up everywhere straight away
+func (s *Store) topicNameOf(topicID int) string {
+	return s.topics[topicID].TopicName
+}
+
+// Whether a post exists and is neither deleted nor hidden (callers must hold a lock)
+func (s *Store) postLive(id int) bool {
+	_, ok := s.posts[id]
+	_, deleted := s.deletedPosts[id]
+	_, hidden := s.hiddenPosts[id]
+	return ok && !deleted && !hidden
+}
+
+// Whether a comment exists and is neither deleted nor hidden (callers must hold a lock)
+func (s *Store) commentLive(id int) bool {
+	_, ok := s.comments[id]
+	_, deleted := s.deletedComments[id]
+	_, hidden := s.hiddenComments[id]
+	return ok && !deleted && !hidden
+}
+
+// Whether a comment exists and its post is neither deleted nor hidden, the comment itself possibly being a tombstone
+// (callers must hold a lock)
+func (s *Store) commentReadable(id int) bool {
+	comment, ok := s.comments[id]
+	return ok && s.postLive(comment.PostID)
+}
+
+// Get a stored comment the way reads return it, as a tombstone if deleted or hidden (callers must hold a lock)
+func (s *Store) commentView(comment models.Comment) models.Comment {
+	comment.Username = s.usernameOf(comment.Author)
+	if deleted, ok := s.deletedComments[comment.ID]; ok {
+		comment.Tombstone(deleted.at, models.DeletedPlaceholder)
+	} else if hiddenAt, ok := s.hiddenComments[comment.ID]; ok {
+		comment.Tombstone(hiddenAt, models.RemovedPlaceholder)
+	}
+	return comment
+}
@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Identifies what a subscription is to, among a user's subscriptions
+type subscriptionKey struct {
+	targetType string
+	targetID   int
+}
+
+// Get the name of the topic or title of the post a subscription is to (callers must hold the lock)
+func (s *Store) subscriptionTargetName(subscription models.Subscription) string {
+	if subscription.TargetType == models.SubscriptionTopic {
+		return s.topics[subscription.TargetID].TopicName
+	}
+	return s.posts[subscription.TargetID].Title
+}
+
+func (s *Store) ListSubscriptions(ctx context.Context, userID int) ([]models.Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscriptions := []models.Subscription{}
+	for _, subscription := range s.subscriptions[userID] {
+		subscription.TargetName = s.subscriptionTargetName(subscription)
+		subscriptions = append(subscriptions, subscription)
+	}
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		if a.TargetType != b.TargetType {
+			return a.TargetType < b.TargetType
+		}
+		return a.TargetID > b.TargetID
+	})
+	return subscriptions, nil
+}
+
+func (s *Store) GetSubscription(ctx context.Context, userID int, targetType string, targetID int) (models.Subscription, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscription, ok := s.subscriptions[userID][subscriptionKey{targetType, targetID}]
+	if !ok {
+		return models.Subscription{}, store.ErrNotFound
+	}
+	subscription.TargetName = s.subscriptionTargetName(subscription)
+	return subscription, nil
+}
+
+func (s *Store) Subscribe(ctx context.Context, subscription models.Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := subscriptionKey{subscription.TargetType, subscription.TargetID}
+	if existing, ok := s.subscriptions[subscription.UserID][key]; ok {
+		subscription.CreatedAt = existing.CreatedAt
+	}
+	if s.subscriptions[subscription.UserID] == nil {
+		s.subscriptions[subscription.UserID] = map[subscriptionKey]models.Subscription{}
+	}
+	subscription.TargetName = ""
+	s.subscriptions[subscription.UserID][key] = subscription
+	return nil
+}
+
+func (s *Store) AutoSubscribe(ctx context.Context, userID, postID int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := subscriptionKey{models.SubscriptionPost, postID}
+	if _, ok := s.subscriptions[userID][key]; ok {
+		return nil
+	}
+	if s.subscriptions[userID] == nil {
+		s.subscriptions[userID] = map[subscriptionKey]models.Subscription{}
+	}
+	s.subscriptions[userID][key] = models.Subscription{UserID: userID, TargetType: models.SubscriptionPost, TargetID: postID, CreatedAt: at}
+	return nil
+}
+
+func (s *Store) Unsubscribe(ctx context.Context, userID int, targetType string, targetID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := subscriptionKey{targetType, targetID}
+	if _, ok := s.subscriptions[userID][key]; !ok {
+		return store.ErrNotFound
+	}
+	delete(s.subscriptions[userID], key)
+	return nil
+}
+
+func (s *Store) PostSubscribers(ctx context.Context, postID int) ([]int, []int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	watching, muted := []int{}, []int{}
+	for userID, subscriptions := range s.subscriptions {
+		if subscription, ok := subscriptions[subscriptionKey{models.SubscriptionPost, postID}]; ok {
+			if subscription.Muted {
+				muted = append(muted, userID)
+			} else {
+				watching = append(watching, userID)
+			}
+		}
+	}
+	sort.Ints(watching)
+	sort.Ints(muted)
+	return watching, muted, nil
+}
+
+func (s *Store) TopicSubscribers(ctx context.Context, topicID int) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := []int{}
+	for userID, subscriptions := range s.subscriptions {
+		// Walk up from the topic, stopping at the top or at a parent that no longer exists
+		for id := topicID; id != 0; id = s.topics[id].ParentID {
+			if _, ok := subscriptions[subscriptionKey{models.SubscriptionTopic, id}]; ok {
+				users = append(users, userID)
+				break
+			}
+		}
+	}
+	sort.Ints(users)
+	return users, nil
+}
+
+func (s *Store) WatchedTopicPosts(ctx context.Context, userID int, since time.Time, limit int) ([]models.Post, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	watched := map[int]bool{}
+	for key := range s.subscriptions[userID] {
+		if key.targetType == models.SubscriptionTopic {
+			for topicID := range s.topicSubtreeLocked(key.targetID) {
+				watched[topicID] = true
+			}
+		}
+	}
+
+	posts := []models.Post{}
+	for _, post := range s.posts {
+		if !watched[post.TopicID] || !s.postLive(post.ID) || post.MergedInto != 0 || post.Author == userID || !post.CreatedAt.After(since) {
+			continue
+		}
+		post.Content = ""
+		post.Username = s.usernameOf(post.Author)
+		post.Topic = s.topicNameOf(post.TopicID)
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		if !posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].CreatedAt.After(posts[j].CreatedAt)
+		}
+		return posts[i].ID > posts[j].ID
+	})
+	if len(posts) > limit {
+		posts = posts[:limit]
+	}
+	return posts, nil
+}
+
+func (s *Store) SubscriptionSettings(ctx context.Context, userID int) (models.SubscriptionSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings, ok := s.subscriptionSettings[userID]
+	if !ok {
+		return models.DefaultSubscriptionSettings, nil
+	}
+	return settings, nil
+}
+
+func (s *Store) SetSubscriptionSettings(ctx context.Context, userID int, settings models.SubscriptionSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscriptionSettings[userID] = settings
+	return nil
+}
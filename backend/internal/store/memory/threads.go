@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/ranking"
+	"sample-go-app/internal/store"
+)
+
+// Check a post is visible and hasn't been merged, so its thread can be reorganised (callers must hold a lock)
+func (s *Store) checkThreadPostLocked(id int) error {
+	if !s.postLive(id) {
+		return store.ErrNotFound
+	}
+	if s.posts[id].MergedInto != 0 {
+		return store.ErrConflict
+	}
+	return nil
+}
+
+// Recompute the comment count and last activity of a post after comments moved in or out (callers must hold the write lock)
+func (s *Store) recountCommentsLocked(postID int) {
+	post := s.posts[postID]
+	post.CommentCount = 0
+	post.LastActivityAt = post.CreatedAt
+	for _, comment := range s.comments {
+		if comment.PostID != postID {
+			continue
+		}
+		if s.commentLive(comment.ID) {
+			post.CommentCount++
+		}
+		if comment.CreatedAt.After(post.LastActivityAt) {
+			post.LastActivityAt = comment.CreatedAt
+		}
+	}
+	s.posts[postID] = post
+}
+
+func (s *Store) MovePost(ctx context.Context, id, topicID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkThreadPostLocked(id); err != nil {
+		return err
+	}
+	post := s.posts[id]
+	post.TopicID = topicID
+	s.posts[id] = post
+	return nil
+}
+
+func (s *Store) MergePost(ctx context.Context, id, into int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, postID := range []int{id, into} {
+		if err := s.checkThreadPostLocked(postID); err != nil {
+			return err
+		}
+	}
+
+	for commentID, comment := range s.comments {
+		if comment.PostID == id {
+			comment.PostID = into
+			s.comments[commentID] = comment
+		}
+	}
+	// Stubs of posts merged earlier follow along, so redirects never chain
+	for postID, post := range s.posts {
+		if postID == id || post.MergedInto == id {
+			post.MergedInto = into
+			s.posts[postID] = post
+		}
+	}
+
+	s.recountCommentsLocked(id)
+	s.recountCommentsLocked(into)
+	return nil
+}
+
+func (s *Store) SplitComment(ctx context.Context, commentID int, post *models.Post, splitBy int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.commentLive(commentID) {
+		return store.ErrNotFound
+	}
+	comment := s.comments[commentID]
+	if err := s.checkThreadPostLocked(comment.PostID); err != nil {
+		return err
+	}
+
+	post.ID = s.newID()
+	post.Author = comment.Author
+	post.Content = comment.Content
+	post.CreatedAt = comment.CreatedAt
+	post.Score = 0
+	post.HotRank = ranking.HotRank(0, post.CreatedAt)
+	post.Topic = s.topicNameOf(post.TopicID)
+	stored := *post
+	stored.Username = ""
+	stored.Topic = ""
+	s.posts[post.ID] = stored
+
+	// Move the replies over, the direct ones becoming top-level comments of the new post
+	subtree := map[int]bool{commentID: true}
+	for grew := true; grew; {
+		grew = false
+		for _, reply := range s.comments {
+			if subtree[reply.ParentID] && !subtree[reply.ID] {
+				subtree[reply.ID] = true
+				grew = true
+			}
+		}
+	}
+	for replyID := range subtree {
+		if replyID == commentID {
+			continue
+		}
+		reply := s.comments[replyID]
+		reply.PostID = post.ID
+		if reply.ParentID == commentID {
+			reply.ParentID = 0
+		}
+		s.comments[replyID] = reply
+	}
+	// The comment itself now lives on as the post
+	s.deletedComments[commentID] = deletion{at: at, by: splitBy}
+
+	s.recountCommentsLocked(comment.PostID)
+	s.recountCommentsLocked(post.ID)
+	post.CommentCount = s.posts[post.ID].CommentCount
+	post.LastActivityAt = s.posts[post.ID].LastActivityAt
+	return nil
+}
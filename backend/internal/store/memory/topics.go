@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+func (s *Store) ListTopics(ctx context.Context) ([]models.Topic, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	topics := []models.Topic{}
+	for _, topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	sort.Slice(topics, func(i, j int) bool {
+		if topics[i].Position != topics[j].Position {
+			return topics[i].Position < topics[j].Position
+		}
+		return topics[i].ID < topics[j].ID
+	})
+	return topics, nil
+}
+
+func (s *Store) GetTopic(ctx context.Context, id int) (models.Topic, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	topic, ok := s.topics[id]
+	if !ok {
+		return models.Topic{}, store.ErrNotFound
+	}
+	return topic, nil
+}
+
+func (s *Store) FindTopic(ctx context.Context, slugOrName string) (models.Topic, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found *models.Topic
+	for _, topic := range s.topics {
+		if topic.Slug == slugOrName {
+			return topic, nil
+		}
+		if topic.TopicName == slugOrName {
+			found = &topic
+		}
+	}
+	if found == nil {
+		return models.Topic{}, store.ErrNotFound
+	}
+	return *found, nil
+}
+
+// Whether another topic already uses the name or slug (callers must hold a lock)
+func (s *Store) topicTakenLocked(topic models.Topic) bool {
+	for id, existing := range s.topics {
+		if id != topic.ID && (existing.TopicName == topic.TopicName || existing.Slug == topic.Slug) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) CreateTopic(ctx context.Context, topic *models.Topic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.topicTakenLocked(*topic) {
+		return store.ErrConflict
+	}
+	if topic.Position == 0 {
+		topic.Position = 1
+		for _, existing := range s.topics {
+			if existing.ParentID == topic.ParentID {
+				topic.Position = max(topic.Position, existing.Position+1)
+			}
+		}
+	}
+	topic.Children = nil
+	topic.ID = s.newID()
+	s.topics[topic.ID] = *topic
+	return nil
+}
+
+func (s *Store) UpdateTopic(ctx context.Context, topic models.Topic) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.topics[topic.ID]; !ok {
+		return store.ErrNotFound
+	}
+	if s.topicTakenLocked(topic) {
+		return store.ErrConflict
+	}
+	topic.Children = nil
+	s.topics[topic.ID] = topic
+	return nil
+}
+
+// Get the IDs of a topic and all of its subtopics, at any depth (callers must hold a lock)
+func (s *Store) topicSubtreeLocked(id int) map[int]bool {
+	subtree := map[int]bool{id: true}
+	for grew := true; grew; {
+		grew = false
+		for _, topic := range s.topics {
+			if subtree[topic.ParentID] && !subtree[topic.ID] {
+				subtree[topic.ID] = true
+				grew = true
+			}
+		}
+	}
+	return subtree
+}
+
+func (s *Store) DeleteTopic(ctx context.Context, id, moveTo int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.topics[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	deleted := map[int]bool{id: true}
+	if moveTo != 0 {
+		if _, ok := s.topics[moveTo]; !ok {
+			return store.ErrNotFound
+		}
+		for topicID, topic := range s.topics {
+			if topic.ParentID == id {
+				topic.ParentID = moveTo
+				s.topics[topicID] = topic
+			}
+		}
+		for postID, post := range s.posts {
+			if post.TopicID == id {
+				post.TopicID = moveTo
+				s.posts[postID] = post
+			}
+		}
+	} else {
+		deleted = s.topicSubtreeLocked(id)
+		for postID, post := range s.posts {
+			if deleted[post.TopicID] {
+				s.deletePostLocked(postID)
+			}
+		}
+	}
+
+	for grantID, grant := range s.userRoles {
+		if deleted[grant.TopicID] {
+			delete(s.userRoles, grantID)
+		}
+	}
+	// Forget the topics in revisions of posts that moved elsewhere, like the SQL store
+	for postID, history := range s.postRevisions {
+		for i := range history {
+			if deleted[history[i].TopicID] {
+				history[i].TopicID = 0
+			}
+		}
+		s.postRevisions[postID] = history
+	}
+	for topicID := range deleted {
+		for userID := range s.subscriptions {
+			delete(s.subscriptions[userID], subscriptionKey{models.SubscriptionTopic, topicID})
+		}
+		delete(s.topics, topicID)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+func (s *Store) GetCommentTree(ctx context.Context, postID, rootID int, params store.TreeParams) ([]models.CommentNode, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Group the post's comments by parent, as returned by reads
+	children := map[int][]models.Comment{}
+	for _, comment := range s.comments {
+		if comment.PostID == postID {
+			children[comment.ParentID] = append(children[comment.ParentID], s.commentView(comment))
+		}
+	}
+
+	// Walk the thread level by level, like the recursive query of the SQL store
+	nodes := []models.CommentNode{}
+	parents := []int{rootID}
+	for depth := 0; depth <= params.MaxDepth && len(parents) > 0; depth++ {
+		var next []int
+		for _, parentID := range parents {
+			page := pagination.Params{Sort: params.Page.Sort, Limit: params.Page.Limit + 1}
+			if depth == 0 {
+				page.After = params.Page.After
+			}
+			level, err := paginateComments(append([]models.Comment(nil), children[parentID]...), page)
+			if err != nil {
+				return nil, err
+			}
+			for _, comment := range level {
+				nodes = append(nodes, models.CommentNode{Comment: comment, Depth: depth, ReplyCount: len(children[comment.ID])})
+				next = append(next, comment.ID)
+			}
+		}
+		parents = next
+	}
+	return nodes, nil
+}
@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Username == user.Username {
+			return store.ErrConflict
+		}
+	}
+	user.ID = s.newID()
+	s.users[user.ID] = *user
+	return nil
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id int) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return models.User{}, store.ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return models.User{}, store.ErrNotFound
+}
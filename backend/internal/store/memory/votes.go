@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+
+	"sample-go-app/internal/ranking"
+	"sample-go-app/internal/store"
+)
+
+// Sum a set of votes keyed by user ID
+func sumVotes(votes map[int]int) int {
+	score := 0
+	for _, value := range votes {
+		score += value
+	}
+	return score
+}
+
+// Recompute a post's score and hot rank (callers must hold the write lock)
+func (s *Store) refreshPostScoreLocked(postID int) int {
+	post := s.posts[postID]
+	post.Score = sumVotes(s.postVotes[postID])
+	post.HotRank = ranking.HotRank(post.Score, post.CreatedAt)
+	s.posts[postID] = post
+	return post.Score
+}
+
+// Recompute a comment's score (callers must hold the write lock)
+func (s *Store) refreshCommentScoreLocked(commentID int) int {
+	comment := s.comments[commentID]
+	comment.Score = sumVotes(s.commentVotes[commentID])
+	s.comments[commentID] = comment
+	return comment.Score
+}
+
+func (s *Store) VotePost(ctx context.Context, postID, userID, value int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.postLive(postID) {
+		return 0, store.ErrNotFound
+	}
+	if s.postVotes[postID] == nil {
+		s.postVotes[postID] = map[int]int{}
+	}
+	s.postVotes[postID][userID] = value
+	return s.refreshPostScoreLocked(postID), nil
+}
+
+func (s *Store) UnvotePost(ctx context.Context, postID, userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.postLive(postID) {
+		return 0, store.ErrNotFound
+	}
+	delete(s.postVotes[postID], userID)
+	return s.refreshPostScoreLocked(postID), nil
+}
+
+func (s *Store) VoteComment(ctx context.Context, commentID, userID, value int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.commentLive(commentID) || !s.commentReadable(commentID) {
+		return 0, store.ErrNotFound
+	}
+	if s.commentVotes[commentID] == nil {
+		s.commentVotes[commentID] = map[int]int{}
+	}
+	s.commentVotes[commentID][userID] = value
+	return s.refreshCommentScoreLocked(commentID), nil
+}
+
+func (s *Store) UnvoteComment(ctx context.Context, commentID, userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.commentLive(commentID) || !s.commentReadable(commentID) {
+		return 0, store.ErrNotFound
+	}
+	delete(s.commentVotes[commentID], userID)
+	return s.refreshCommentScoreLocked(commentID), nil
+}
+
+func (s *Store) PostVotesBy(ctx context.Context, userID int, postIDs []int) (map[int]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	votes := map[int]int{}
+	for _, id := range postIDs {
+		if value, ok := s.postVotes[id][userID]; ok {
+			votes[id] = value
+		}
+	}
+	return votes, nil
+}
+
+func (s *Store) CommentVotesBy(ctx context.Context, userID int, commentIDs []int) (map[int]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	votes := map[int]int{}
+	for _, id := range commentIDs {
+		if value, ok := s.commentVotes[id][userID]; ok {
+			votes[id] = value
+		}
+	}
+	return votes, nil
+}
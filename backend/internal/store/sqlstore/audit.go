@@ -0,0 +1,102 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+const auditColumns = `a.id, a.actor_id, COALESCE(u.username, 'Unknown'), a.action, a.target_type, a.target_id,
+	a.before_state, a.after_state, a.ip, a.user_agent, a.method, a.path, a.request_id, a.created_at`
+
+// Sort columns for the audit log (table alias a)
+func auditSortSpec(sort string) sortSpec {
+	if sort == store.SortOldest {
+		return sortSpec{column: "a.created_at"}
+	}
+	return sortSpec{column: "a.created_at", desc: true}
+}
+
+// Store a snapshot as text (which Postgres casts to JSONB), or NULL if there is none
+func jsonParam(snapshot json.RawMessage) any {
+	if len(snapshot) == 0 {
+		return nil
+	}
+	return string(snapshot)
+}
+
+func (s *Store) AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	id, err := s.conn().insert(ctx, `INSERT INTO audit_log (actor_id, action, target_type, target_id, before_state, after_state,
+			ip, user_agent, method, path, request_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, entry.ActorID, entry.Action, entry.TargetType, entry.TargetID,
+		jsonParam(entry.Before), jsonParam(entry.After), entry.IP, entry.UserAgent, entry.Method, entry.Path, entry.RequestID, entry.CreatedAt)
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+	return nil
+}
+
+func (s *Store) ListAuditEntries(ctx context.Context, filter store.AuditFilter, page pagination.Params) ([]models.AuditEntry, error) {
+	condition := "1 = 1"
+	var args []any
+	if filter.ActorID != 0 {
+		condition += " AND a.actor_id = ?"
+		args = append(args, filter.ActorID)
+	}
+	if filter.Action != "" {
+		condition += " AND a.action = ?"
+		args = append(args, filter.Action)
+	}
+	if filter.TargetType != "" {
+		condition += " AND a.target_type = ?"
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		condition += " AND a.target_id = ?"
+		args = append(args, filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		condition += " AND a.created_at >= ?"
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		condition += " AND a.created_at < ?"
+		args = append(args, filter.To.UTC())
+	}
+
+	where, tail, pageArgs, err := pageClause(auditSortSpec(page.Sort), "a.id", "a.created_at", page)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.conn().query(ctx, "SELECT "+auditColumns+`
+		FROM audit_log a
+		LEFT JOIN users u ON a.actor_id = u.id
+		WHERE `+condition+where+tail, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&entry.ID, &entry.ActorID, &entry.ActorUsername, &entry.Action, &entry.TargetType, &entry.TargetID,
+			&before, &after, &entry.IP, &entry.UserAgent, &entry.Method, &entry.Path, &entry.RequestID, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
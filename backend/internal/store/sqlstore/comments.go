@@ -0,0 +1,190 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+)
+
+// Columns selected for comments
+const commentColumns = `c.id, c.post_id, COALESCE(c.parent_id, 0), c.user_id, COALESCE(u.username, 'Unknown') AS username, c.content, c.created_at,
+	c.score, c.deleted_at, c.hidden_at, c.edited_at, c.revision_count`
+
+// Scan the commentColumns of a row, followed by any extra columns
+// Deleted and hidden comments are turned into tombstones
+func scanComment(row interface{ Scan(...any) error }, comment *models.Comment, extra ...any) error {
+	var deletedAt, hiddenAt, editedAt sql.NullTime
+	dest := append([]any{&comment.ID, &comment.PostID, &comment.ParentID, &comment.Author, &comment.Username, &comment.Content,
+		&comment.CreatedAt, &comment.Score, &deletedAt, &hiddenAt, &editedAt, &comment.RevisionCount}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	if editedAt.Valid {
+		comment.EditedAt = &editedAt.Time
+	}
+	if deletedAt.Valid {
+		comment.Tombstone(deletedAt.Time, models.DeletedPlaceholder)
+	} else if hiddenAt.Valid {
+		comment.Tombstone(hiddenAt.Time, models.RemovedPlaceholder)
+	}
+	return nil
+}
+
+// Read comment rows into a slice
+func scanComments(rows *sql.Rows) ([]models.Comment, error) {
+	defer rows.Close()
+
+	comments := []models.Comment{}
+	for rows.Next() {
+		var comment models.Comment
+		if err := scanComment(rows, &comment); err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// List a page of comments matching the given condition, leaving out those of deleted and hidden posts
+func (s *Store) listComments(ctx context.Context, condition string, args []any, page pagination.Params) ([]models.Comment, error) {
+	where, tail, pageArgs, err := pageClause(commentSortSpec(page.Sort), "c.id", "c.created_at", page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn().query(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN posts p ON p.id = c.post_id
+		LEFT JOIN users u ON c.user_id = u.id
+		WHERE `+visible("p")+` AND `+condition+where+tail, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanComments(rows)
+}
+
+func (s *Store) ListPostComments(ctx context.Context, postID int, page pagination.Params) ([]models.Comment, error) {
+	// Top-level comments are the ones where parent_id is NULL
+	return s.listComments(ctx, "c.post_id = ? AND c.parent_id IS NULL", []any{postID}, page)
+}
+
+func (s *Store) ListSubComments(ctx context.Context, commentID int, page pagination.Params) ([]models.Comment, error) {
+	return s.listComments(ctx, "c.parent_id = ?", []any{commentID}, page)
+}
+
+func (s *Store) GetComment(ctx context.Context, id int) (models.Comment, error) {
+	row := s.conn().queryRow(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN posts p ON p.id = c.post_id
+		LEFT JOIN users u ON c.user_id = u.id
+		WHERE c.id = ? AND `+visible("p"), id)
+
+	comment := models.Comment{}
+	err := scanComment(row, &comment)
+	return comment, s.translateError(err)
+}
+
+func (s *Store) CreateComment(ctx context.Context, comment *models.Comment) error {
+	// A zero ParentID is stored as NULL, marking a top-level comment
+	var parentID sql.NullInt64
+	if comment.ParentID != 0 {
+		parentID = sql.NullInt64{Int64: int64(comment.ParentID), Valid: true}
+	}
+
+	return s.withTx(ctx, func(tx runner) error {
+		// Only live posts and comments can be replied to, merged posts having handed their thread over
+		var exists int
+		if err := tx.queryRow(ctx, "SELECT 1 FROM posts WHERE id = ? AND merged_into IS NULL AND "+visible(""), comment.PostID).Scan(&exists); err != nil {
+			return tx.translateError(err)
+		}
+		if parentID.Valid {
+			err := tx.queryRow(ctx, "SELECT 1 FROM comments WHERE id = ? AND post_id = ? AND "+visible(""), parentID, comment.PostID).Scan(&exists)
+			if err != nil {
+				return tx.translateError(err)
+			}
+		}
+
+		id, err := tx.insert(ctx, "INSERT INTO comments (post_id, parent_id, user_id, content, created_at) VALUES (?, ?, ?, ?, ?)",
+			comment.PostID, parentID, comment.Author, comment.Content, comment.CreatedAt)
+		if err != nil {
+			return err
+		}
+		comment.ID = id
+
+		// Keep the post's sort keys up to date
+		_, err = tx.exec(ctx, "UPDATE posts SET comment_count = comment_count + 1, last_activity_at = ? WHERE id = ?",
+			comment.CreatedAt, comment.PostID)
+		return err
+	})
+}
+
+func (s *Store) UpdateCommentContent(ctx context.Context, id int, content string, editedBy int, at time.Time) error {
+	return s.withTx(ctx, func(tx runner) error {
+		// Keep the version being replaced, credited to whoever wrote it
+		res, err := tx.exec(ctx, `
+			INSERT INTO comment_revisions (comment_id, revision, content, user_id, created_at)
+			SELECT c.id, c.revision_count + 1, c.content, COALESCE(c.edited_by, c.user_id), COALESCE(c.edited_at, c.created_at)
+			FROM comments c
+			JOIN posts p ON p.id = c.post_id
+			WHERE c.id = ? AND `+visible("c")+` AND `+visible("p"), id)
+		if err != nil {
+			return err
+		}
+		if err := checkAffected(res); err != nil {
+			return err
+		}
+
+		_, err = tx.exec(ctx, "UPDATE comments SET content = ?, edited_at = ?, edited_by = ?, revision_count = revision_count + 1 WHERE id = ?",
+			content, at, editedBy, id)
+		return err
+	})
+}
+
+// Update the tombstone columns of a comment matching condition and adjust the post's comment count by delta
+// Fails with ErrNotFound if the comment doesn't match
+func updateCommentState(ctx context.Context, tx runner, id int, delta int, condition, set string, args ...any) error {
+	var postID int
+	if err := tx.queryRow(ctx, "SELECT post_id FROM comments WHERE id = ? AND "+condition, id).Scan(&postID); err != nil {
+		return tx.translateError(err)
+	}
+
+	res, err := tx.exec(ctx, "UPDATE comments SET "+set+" WHERE id = ? AND "+condition, append(args, id)...)
+	if err != nil {
+		return err
+	}
+	if err := checkAffected(res); err != nil {
+		return err
+	}
+
+	_, err = tx.exec(ctx, "UPDATE posts SET comment_count = comment_count + ? WHERE id = ?", delta, postID)
+	return err
+}
+
+func (s *Store) DeleteComment(ctx context.Context, id, deletedBy int, at time.Time) error {
+	return s.withTx(ctx, func(tx runner) error {
+		// Like edits and votes, comments of a deleted or hidden post can't be deleted
+		condition := visible("") + " AND post_id IN (SELECT id FROM posts WHERE " + visible("") + ")"
+		return updateCommentState(ctx, tx, id, -1, condition, "deleted_at = ?, deleted_by = ?", at, deletedBy)
+	})
+}
+
+func (s *Store) RestoreComment(ctx context.Context, id int) error {
+	return s.withTx(ctx, func(tx runner) error {
+		return updateCommentState(ctx, tx, id, 1, restorable, "deleted_at = NULL, deleted_by = NULL, hidden_at = NULL")
+	})
+}
+
+func (s *Store) GetCommentOwner(ctx context.Context, id int) (int, int, error) {
+	var ownerID, topicID int
+	err := s.conn().queryRow(ctx, "SELECT c.user_id, COALESCE(p.topic_id, 0) FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.id = ?", id).
+		Scan(&ownerID, &topicID)
+	if err != nil {
+		return -1, 0, s.translateError(err)
+	}
+	return ownerID, topicID, nil
+}
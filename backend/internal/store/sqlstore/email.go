@@ -0,0 +1,214 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+func (s *Store) EmailSettings(ctx context.Context, userID int) (models.EmailSettings, error) {
+	var email, digest sql.NullString
+	var verifiedAt sql.NullTime
+	var notifications sql.NullBool
+	err := s.conn().queryRow(ctx, `SELECT u.email, u.email_verified_at, es.digest, es.notifications
+		FROM users u LEFT JOIN email_settings es ON es.user_id = u.id
+		WHERE u.id = ?`, userID).Scan(&email, &verifiedAt, &digest, &notifications)
+	if err != nil {
+		return models.EmailSettings{}, s.translateError(err)
+	}
+
+	settings := models.DefaultEmailSettings
+	settings.Email = email.String
+	if verifiedAt.Valid {
+		settings.VerifiedAt = &verifiedAt.Time
+	}
+	if digest.Valid {
+		settings.Digest = digest.String
+		settings.Notifications = notifications.Bool
+	}
+
+	var pendingSince time.Time
+	err = s.conn().queryRow(ctx, "SELECT email, created_at FROM email_verifications WHERE user_id = ? ORDER BY created_at DESC LIMIT 1",
+		userID).Scan(&settings.PendingEmail, &pendingSince)
+	if err != nil && err != sql.ErrNoRows {
+		return models.EmailSettings{}, err
+	}
+	if err == nil {
+		settings.PendingSince = &pendingSince
+	}
+	return settings, nil
+}
+
+func (s *Store) SetEmailSettings(ctx context.Context, userID int, settings models.EmailSettings) error {
+	_, err := s.conn().exec(ctx, `INSERT INTO email_settings (user_id, digest, notifications) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET digest = excluded.digest, notifications = excluded.notifications`,
+		userID, settings.Digest, settings.Notifications)
+	return err
+}
+
+func (s *Store) EmailInUse(ctx context.Context, email string, exceptUserID int) (bool, error) {
+	var inUse bool
+	err := s.conn().queryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE email = ? AND id <> ?)", email, exceptUserID).Scan(&inUse)
+	return inUse, err
+}
+
+func (s *Store) AddEmailVerification(ctx context.Context, verification models.EmailVerification) error {
+	return s.withTx(ctx, func(tx runner) error {
+		if _, err := tx.exec(ctx, "DELETE FROM email_verifications WHERE user_id = ?", verification.UserID); err != nil {
+			return err
+		}
+		_, err := tx.exec(ctx, "INSERT INTO email_verifications (token_hash, user_id, email, created_at, expires_at) VALUES (?, ?, ?, ?, ?)",
+			verification.TokenHash, verification.UserID, verification.Email, verification.CreatedAt, verification.ExpiresAt)
+		if err != nil {
+			return tx.translateError(err)
+		}
+
+		_, err = tx.exec(ctx, "DELETE FROM email_verifications_sent WHERE user_id = ? AND sent_at < ?",
+			verification.UserID, verification.CreatedAt.Add(-store.EmailVerificationLogRetention))
+		if err != nil {
+			return err
+		}
+		_, err = tx.exec(ctx, "INSERT INTO email_verifications_sent (user_id, sent_at) VALUES (?, ?)", verification.UserID, verification.CreatedAt)
+		return err
+	})
+}
+
+func (s *Store) CountEmailVerifications(ctx context.Context, userID int, since time.Time) (int, error) {
+	var count int
+	err := s.conn().queryRow(ctx, "SELECT COUNT(*) FROM email_verifications_sent WHERE user_id = ? AND sent_at >= ?", userID, since).Scan(&count)
+	return count, err
+}
+
+func (s *Store) VerifyEmail(ctx context.Context, tokenHash string, at time.Time) (int, error) {
+	var userID int
+	err := s.withTx(ctx, func(tx runner) error {
+		var email string
+		err := tx.queryRow(ctx, "SELECT user_id, email FROM email_verifications WHERE token_hash = ? AND expires_at > ?",
+			tokenHash, at).Scan(&userID, &email)
+		if err != nil {
+			return tx.translateError(err)
+		}
+
+		// Fails on the unique index if someone else verified the address meanwhile
+		if _, err := tx.exec(ctx, "UPDATE users SET email = ?, email_verified_at = ? WHERE id = ?", email, at, userID); err != nil {
+			return tx.translateError(err)
+		}
+		// Forget the used verification, and any that expired
+		_, err = tx.exec(ctx, "DELETE FROM email_verifications WHERE user_id = ? OR expires_at <= ?", userID, at)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+func (s *Store) RemoveEmail(ctx context.Context, userID int) error {
+	return s.withTx(ctx, func(tx runner) error {
+		pending, err := tx.exec(ctx, "DELETE FROM email_verifications WHERE user_id = ?", userID)
+		if err != nil {
+			return err
+		}
+		verified, err := tx.exec(ctx, "UPDATE users SET email = NULL, email_verified_at = NULL WHERE id = ? AND email IS NOT NULL", userID)
+		if err != nil {
+			return err
+		}
+		if checkAffected(pending) != nil && checkAffected(verified) != nil {
+			return store.ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s *Store) EnqueueEmail(ctx context.Context, email *models.Email) error {
+	id, err := s.conn().insert(ctx, `INSERT INTO email_outbox (to_address, subject, text_body, html_body, created_at, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, email.To, email.Subject, email.Text, email.HTML, email.CreatedAt, email.CreatedAt)
+	if err != nil {
+		return err
+	}
+	email.ID = id
+	email.NextAttemptAt = &email.CreatedAt
+	return nil
+}
+
+func (s *Store) DueEmails(ctx context.Context, now time.Time, limit int) ([]models.Email, error) {
+	rows, err := s.conn().query(ctx, `SELECT id, to_address, subject, text_body, html_body, attempts, last_error, created_at, next_attempt_at
+		FROM email_outbox WHERE next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []models.Email{}
+	for rows.Next() {
+		var email models.Email
+		var nextAttempt time.Time
+		if err := rows.Scan(&email.ID, &email.To, &email.Subject, &email.Text, &email.HTML, &email.Attempts, &email.LastError,
+			&email.CreatedAt, &nextAttempt); err != nil {
+			return nil, err
+		}
+		email.NextAttemptAt = &nextAttempt
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+func (s *Store) MarkEmailSent(ctx context.Context, id int, at time.Time) error {
+	res, err := s.conn().exec(ctx, "UPDATE email_outbox SET sent_at = ?, next_attempt_at = NULL WHERE id = ?", at, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *Store) MarkEmailFailed(ctx context.Context, id int, reason string, at, retryAt time.Time) error {
+	var res sql.Result
+	var err error
+	if retryAt.IsZero() {
+		res, err = s.conn().exec(ctx, `UPDATE email_outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = NULL, failed_at = ?
+			WHERE id = ?`, reason, at, id)
+	} else {
+		res, err = s.conn().exec(ctx, "UPDATE email_outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?",
+			reason, retryAt, id)
+	}
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *Store) DigestRecipients(ctx context.Context) ([]models.DigestRecipient, error) {
+	rows, err := s.conn().query(ctx, `SELECT u.id, u.username, u.email, COALESCE(es.digest, ?), u.email_verified_at, es.digest_sent_at
+		FROM users u LEFT JOIN email_settings es ON es.user_id = u.id
+		WHERE u.email IS NOT NULL AND COALESCE(es.digest, ?) <> ?
+		ORDER BY u.id`, models.DefaultEmailSettings.Digest, models.DefaultEmailSettings.Digest, models.DigestOff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recipients := []models.DigestRecipient{}
+	for rows.Next() {
+		var recipient models.DigestRecipient
+		var verifiedAt, sentAt sql.NullTime
+		if err := rows.Scan(&recipient.UserID, &recipient.Username, &recipient.Email, &recipient.Digest, &verifiedAt, &sentAt); err != nil {
+			return nil, err
+		}
+		recipient.Since = verifiedAt.Time
+		if sentAt.Valid {
+			recipient.Since = sentAt.Time
+		}
+		recipients = append(recipients, recipient)
+	}
+	return recipients, rows.Err()
+}
+
+func (s *Store) MarkDigestSent(ctx context.Context, userID int, at time.Time) error {
+	_, err := s.conn().exec(ctx, `INSERT INTO email_settings (user_id, digest, notifications, digest_sent_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET digest_sent_at = excluded.digest_sent_at`,
+		userID, models.DefaultEmailSettings.Digest, models.DefaultEmailSettings.Notifications, at)
+	return err
+}
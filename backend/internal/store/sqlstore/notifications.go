@@ -0,0 +1,151 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+const notificationColumns = `n.id, n.user_id, n.type, n.actor_id, COALESCE(u.username, 'Unknown'), n.post_id,
+	COALESCE(p.title, ''), COALESCE(n.comment_id, 0), n.created_at, n.read_at`
+
+// Sort columns for notifications (table alias n)
+func notificationSortSpec(sort string) sortSpec {
+	if sort == store.SortOldest {
+		return sortSpec{column: "n.created_at"}
+	}
+	return sortSpec{column: "n.created_at", desc: true}
+}
+
+func (s *Store) AddNotifications(ctx context.Context, notifications []models.Notification) ([]models.Notification, error) {
+	added := []models.Notification{}
+	err := s.withTx(ctx, func(tx runner) error {
+		for _, notification := range notifications {
+			// Types a user never set are on
+			var enabled bool
+			err := tx.queryRow(ctx, "SELECT enabled FROM notification_preferences WHERE user_id = ? AND type = ?",
+				notification.UserID, notification.Type).Scan(&enabled)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			if err == nil && !enabled {
+				continue
+			}
+
+			// Mentions in a post have no comment, stored as NULL
+			commentID := sql.NullInt64{Int64: int64(notification.CommentID), Valid: notification.CommentID != 0}
+			id, err := tx.insert(ctx, `INSERT INTO notifications (user_id, type, actor_id, post_id, comment_id, created_at)
+				VALUES (?, ?, ?, ?, ?, ?)`, notification.UserID, notification.Type, notification.ActorID,
+				notification.PostID, commentID, notification.CreatedAt)
+			if err != nil {
+				return err
+			}
+			notification.ID = id
+			added = append(added, notification)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+func (s *Store) ListNotifications(ctx context.Context, userID int, unreadOnly bool, page pagination.Params) ([]models.Notification, error) {
+	condition := "n.user_id = ?"
+	if unreadOnly {
+		condition += " AND n.read_at IS NULL"
+	}
+
+	where, tail, pageArgs, err := pageClause(notificationSortSpec(page.Sort), "n.id", "n.created_at", page)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.conn().query(ctx, "SELECT "+notificationColumns+`
+		FROM notifications n
+		LEFT JOIN users u ON n.actor_id = u.id
+		LEFT JOIN posts p ON n.post_id = p.id
+		WHERE `+condition+where+tail, append([]any{userID}, pageArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		var readAt sql.NullTime
+		if err := rows.Scan(&notification.ID, &notification.UserID, &notification.Type, &notification.ActorID,
+			&notification.ActorUsername, &notification.PostID, &notification.PostTitle, &notification.CommentID,
+			&notification.CreatedAt, &readAt); err != nil {
+			return nil, err
+		}
+		if readAt.Valid {
+			notification.ReadAt = &readAt.Time
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+func (s *Store) CountUnreadNotifications(ctx context.Context, userID int) (int, error) {
+	var count int
+	err := s.conn().queryRow(ctx, "SELECT COUNT(*) FROM notifications WHERE user_id = ? AND read_at IS NULL", userID).Scan(&count)
+	return count, err
+}
+
+func (s *Store) MarkNotificationRead(ctx context.Context, userID, id int, at time.Time) error {
+	res, err := s.conn().exec(ctx, "UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?",
+		at, id, userID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *Store) MarkAllNotificationsRead(ctx context.Context, userID int, at time.Time) (int, error) {
+	res, err := s.conn().exec(ctx, "UPDATE notifications SET read_at = ? WHERE user_id = ? AND read_at IS NULL", at, userID)
+	if err != nil {
+		return 0, err
+	}
+	marked, err := res.RowsAffected()
+	return int(marked), err
+}
+
+func (s *Store) NotificationPreferences(ctx context.Context, userID int) (map[string]bool, error) {
+	preferences := map[string]bool{}
+	for _, kind := range models.NotificationTypes {
+		preferences[kind] = true
+	}
+
+	rows, err := s.conn().query(ctx, "SELECT type, enabled FROM notification_preferences WHERE user_id = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var kind string
+		var enabled bool
+		if err := rows.Scan(&kind, &enabled); err != nil {
+			return nil, err
+		}
+		preferences[kind] = enabled
+	}
+	return preferences, rows.Err()
+}
+
+func (s *Store) SetNotificationPreferences(ctx context.Context, userID int, preferences map[string]bool) error {
+	return s.withTx(ctx, func(tx runner) error {
+		for kind, enabled := range preferences {
+			if _, err := tx.exec(ctx, `INSERT INTO notification_preferences (user_id, type, enabled) VALUES (?, ?, ?)
+				ON CONFLICT (user_id, type) DO UPDATE SET enabled = excluded.enabled`, userID, kind, enabled); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
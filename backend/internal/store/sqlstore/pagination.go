@@ -0,0 +1,107 @@
+package sqlstore
+
+import (
+	"fmt"
+	"time"
+
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+// Type of value a sort order's cursor carries
+type cursorKind int
+
+const (
+	timeCursor cursorKind = iota
+	intCursor
+	floatCursor
+)
+
+// Column and direction a sort order is keyed on
+type sortSpec struct {
+	column string
+	desc   bool
+	kind   cursorKind
+	// Only include rows created within this window (0 for no limit)
+	window time.Duration
+}
+
+// Sort columns for post lists (table alias p)
+func postSortSpec(sort string) sortSpec {
+	switch {
+	case sort == store.SortOldest:
+		return sortSpec{column: "p.created_at"}
+	case sort == store.SortMostCommented:
+		return sortSpec{column: "p.comment_count", desc: true, kind: intCursor}
+	case sort == store.SortActivity:
+		return sortSpec{column: "p.last_activity_at", desc: true}
+	case sort == store.SortHot:
+		return sortSpec{column: "p.hot_rank", desc: true, kind: floatCursor}
+	case store.IsTopSort(sort):
+		return sortSpec{column: "p.score", desc: true, kind: intCursor, window: store.TopWindows[sort]}
+	}
+	return sortSpec{column: "p.created_at", desc: true}
+}
+
+// Sort columns for comment lists (table alias c)
+func commentSortSpec(sort string) sortSpec {
+	if sort == store.SortOldest {
+		return sortSpec{column: "c.created_at"}
+	}
+	return sortSpec{column: "c.created_at", desc: true}
+}
+
+// Build the keyset condition, ORDER BY and LIMIT for a page
+// where starts with " AND" (or is empty) so it can follow an existing WHERE clause
+// idColumn breaks ties between rows with the same sort key, createdColumn is used for windowed sorts
+func pageClause(spec sortSpec, idColumn, createdColumn string, page pagination.Params) (where string, tail string, args []any, err error) {
+	direction := "ASC"
+	if spec.desc {
+		direction = "DESC"
+	}
+
+	if spec.window > 0 {
+		where += fmt.Sprintf(" AND %s >= ?", createdColumn)
+		args = append(args, time.Now().UTC().Add(-spec.window))
+	}
+
+	if page.After != nil {
+		condition, keysetArgs, err := keysetCondition(spec, idColumn, page.After)
+		if err != nil {
+			return "", "", nil, err
+		}
+		where += " AND " + condition
+		args = append(args, keysetArgs...)
+	}
+
+	tail = fmt.Sprintf(" ORDER BY %s %s, %s %s LIMIT ?", spec.column, direction, idColumn, direction)
+	args = append(args, page.Limit)
+	return where, tail, args, nil
+}
+
+// Build the condition selecting the rows that come after a cursor
+func keysetCondition(spec sortSpec, idColumn string, after *pagination.Cursor) (string, []any, error) {
+	cmp := ">"
+	if spec.desc {
+		cmp = "<"
+	}
+
+	var value any
+	var err error
+	switch spec.kind {
+	case intCursor:
+		value, err = after.Int()
+	case floatCursor:
+		value, err = after.Float()
+	default:
+		var t time.Time
+		t, err = after.Time()
+		// Bound in UTC, the zone timestamps are stored in, as SQLite compares them as text
+		value = t.UTC()
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	condition := fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", spec.column, cmp, spec.column, idColumn, cmp)
+	return condition, []any{value, value, after.ID}, nil
+}
@@ -0,0 +1,158 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/ranking"
+)
+
+// Columns selected for post lists (no content)
+const postListColumns = `p.id, p.title, COALESCE(t.topic, '') AS topic, COALESCE(p.topic_id, 0), p.user_id, COALESCE(u.username, 'Unknown') AS username, p.created_at,
+	p.comment_count, p.last_activity_at, p.score, p.hot_rank, p.edited_at, p.revision_count`
+
+// Fill in the post fields read from nullable columns
+func setPostTimes(post *models.Post, lastActivity, editedAt sql.NullTime) {
+	post.LastActivityAt = post.CreatedAt
+	if lastActivity.Valid {
+		post.LastActivityAt = lastActivity.Time
+	}
+	if editedAt.Valid {
+		post.EditedAt = &editedAt.Time
+	}
+}
+
+// Read the list columns of posts into a slice
+func scanPostList(rows *sql.Rows) ([]models.Post, error) {
+	defer rows.Close()
+
+	posts := []models.Post{}
+	for rows.Next() {
+		var post models.Post
+		var lastActivity, editedAt sql.NullTime
+		if err := rows.Scan(&post.ID, &post.Title, &post.Topic, &post.TopicID, &post.Author, &post.Username, &post.CreatedAt,
+			&post.CommentCount, &lastActivity, &post.Score, &post.HotRank, &editedAt, &post.RevisionCount); err != nil {
+			return nil, err
+		}
+		setPostTimes(&post, lastActivity, editedAt)
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// List a page of posts matching the given condition
+func (s *Store) listPosts(ctx context.Context, condition string, args []any, page pagination.Params) ([]models.Post, error) {
+	where, tail, pageArgs, err := pageClause(postSortSpec(page.Sort), "p.id", "p.created_at", page)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn().query(ctx, `
+		SELECT `+postListColumns+`
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		LEFT JOIN topics t ON t.id = p.topic_id
+		WHERE `+visible("p")+` AND p.merged_into IS NULL AND `+condition+where+tail, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	return scanPostList(rows)
+}
+
+func (s *Store) ListPosts(ctx context.Context, page pagination.Params) ([]models.Post, error) {
+	return s.listPosts(ctx, "1 = 1", nil, page)
+}
+
+func (s *Store) ListPostsByTopic(ctx context.Context, topicID int, subtopics bool, page pagination.Params) ([]models.Post, error) {
+	if subtopics {
+		return s.listPosts(ctx, "p.topic_id IN ("+topicSubtree+")", []any{topicID}, page)
+	}
+	return s.listPosts(ctx, "p.topic_id = ?", []any{topicID}, page)
+}
+
+func (s *Store) GetPost(ctx context.Context, id int) (models.Post, error) {
+	row := s.conn().queryRow(ctx, `
+		SELECT p.id, p.title, COALESCE(t.topic, '') AS topic, COALESCE(p.topic_id, 0), p.content, p.user_id, COALESCE(u.username, 'Unknown') AS username,
+			p.created_at, p.comment_count, p.last_activity_at, p.score, p.hot_rank, p.edited_at, p.revision_count, COALESCE(p.merged_into, 0)
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		LEFT JOIN topics t ON t.id = p.topic_id
+		WHERE p.id = ? AND `+visible("p"), id)
+
+	post := models.Post{}
+	var lastActivity, editedAt sql.NullTime
+	err := row.Scan(&post.ID, &post.Title, &post.Topic, &post.TopicID, &post.Content, &post.Author, &post.Username, &post.CreatedAt,
+		&post.CommentCount, &lastActivity, &post.Score, &post.HotRank, &editedAt, &post.RevisionCount, &post.MergedInto)
+	setPostTimes(&post, lastActivity, editedAt)
+	return post, s.translateError(err)
+}
+
+func (s *Store) CreatePost(ctx context.Context, post *models.Post) error {
+	return insertPost(ctx, s.conn(), post)
+}
+
+// Insert a new post and set its ID and sort keys
+func insertPost(ctx context.Context, tx runner, post *models.Post) error {
+	post.Score = 0
+	post.HotRank = ranking.HotRank(0, post.CreatedAt)
+	id, err := tx.insert(ctx, "INSERT INTO posts (title, topic_id, content, user_id, created_at, last_activity_at, hot_rank) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		post.Title, post.TopicID, post.Content, post.Author, post.CreatedAt, post.CreatedAt, post.HotRank)
+	if err != nil {
+		return err
+	}
+	post.ID = id
+	post.CommentCount = 0
+	post.LastActivityAt = post.CreatedAt
+	return nil
+}
+
+func (s *Store) UpdatePost(ctx context.Context, post models.Post, editedBy int, at time.Time) error {
+	return s.withTx(ctx, func(tx runner) error {
+		// Keep the version being replaced, credited to whoever wrote it
+		res, err := tx.exec(ctx, `
+			INSERT INTO post_revisions (post_id, revision, title, topic_id, content, user_id, created_at)
+			SELECT id, revision_count + 1, title, topic_id, content, COALESCE(edited_by, user_id), COALESCE(edited_at, created_at)
+			FROM posts
+			WHERE id = ? AND merged_into IS NULL AND `+visible(""), post.ID)
+		if err != nil {
+			return err
+		}
+		if err := checkAffected(res); err != nil {
+			return err
+		}
+
+		_, err = tx.exec(ctx, `UPDATE posts SET title = ?, topic_id = ?, content = ?, edited_at = ?, edited_by = ?, revision_count = revision_count + 1
+			WHERE id = ?`, post.Title, post.TopicID, post.Content, at, editedBy, post.ID)
+		return err
+	})
+}
+
+// Update the tombstone columns of a post matching condition, failing with ErrNotFound if it doesn't match
+func updatePostState(ctx context.Context, tx runner, id int, condition, set string, args ...any) error {
+	res, err := tx.exec(ctx, "UPDATE posts SET "+set+" WHERE id = ? AND "+condition, append(args, id)...)
+	if err != nil {
+		return tx.translateError(err)
+	}
+	return checkAffected(res)
+}
+
+func (s *Store) DeletePost(ctx context.Context, id, deletedBy int, at time.Time) error {
+	// Stubs left by merges are kept so their redirect keeps working
+	return updatePostState(ctx, s.conn(), id, "merged_into IS NULL AND "+visible(""), "deleted_at = ?, deleted_by = ?", at, deletedBy)
+}
+
+func (s *Store) RestorePost(ctx context.Context, id int) error {
+	return updatePostState(ctx, s.conn(), id, restorable, "deleted_at = NULL, deleted_by = NULL, hidden_at = NULL")
+}
+
+func (s *Store) GetPostOwner(ctx context.Context, id int) (int, int, error) {
+	var ownerID, topicID int
+	err := s.conn().queryRow(ctx, "SELECT user_id, COALESCE(topic_id, 0) FROM posts WHERE id = ?", id).Scan(&ownerID, &topicID)
+	if err != nil {
+		return -1, 0, s.translateError(err)
+	}
+	return ownerID, topicID, nil
+}
@@ -0,0 +1,108 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+)
+
+// Tombstoned comments without replies, which can be removed without breaking a thread
+const purgeableComments = `SELECT c.id FROM comments c
+	WHERE c.deleted_at < ? AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id)`
+
+// Remove what points at the posts selected by postsQuery, or at their comments, without belonging to them,
+// before the posts are deleted: their notifications and reports (which sanctions stop referring to),
+// and the redirects of posts merged into them, which become standalone posts again
+func detachPosts(ctx context.Context, tx runner, postsQuery string, args ...any) error {
+	if _, err := tx.exec(ctx, "UPDATE posts SET merged_into = NULL WHERE merged_into IN ("+postsQuery+")", args...); err != nil {
+		return err
+	}
+	if _, err := tx.exec(ctx, "DELETE FROM notifications WHERE post_id IN ("+postsQuery+")", args...); err != nil {
+		return err
+	}
+	return deleteReports(ctx, tx, `(target_type = 'post' AND target_id IN (`+postsQuery+`))
+		OR (target_type = 'comment' AND target_id IN (SELECT id FROM comments WHERE post_id IN (`+postsQuery+`)))`,
+		append(append([]any{}, args...), args...)...)
+}
+
+// Remove the notifications and reports about the comments selected by commentsQuery, before the comments are deleted
+func detachComments(ctx context.Context, tx runner, commentsQuery string, args ...any) error {
+	if _, err := tx.exec(ctx, "DELETE FROM notifications WHERE comment_id IN ("+commentsQuery+")", args...); err != nil {
+		return err
+	}
+	return deleteReports(ctx, tx, "target_type = 'comment' AND target_id IN ("+commentsQuery+")", args...)
+}
+
+// Delete the reports matching condition, keeping the sanctions handed out for them
+func deleteReports(ctx context.Context, tx runner, condition string, args ...any) error {
+	if _, err := tx.exec(ctx, "UPDATE user_sanctions SET report_id = NULL WHERE report_id IN (SELECT id FROM reports WHERE "+condition+")", args...); err != nil {
+		return err
+	}
+	_, err := tx.exec(ctx, "DELETE FROM reports WHERE "+condition, args...)
+	return err
+}
+
+func (s *Store) PurgeDeleted(ctx context.Context, before time.Time) (int, int, error) {
+	var posts, comments int
+	err := s.withTx(ctx, func(tx runner) error {
+		// Step 1: Delete expired posts with everything below them, like deleting a topic does
+		if err := detachPosts(ctx, tx, "SELECT id FROM posts WHERE deleted_at < ?", before); err != nil {
+			return err
+		}
+		for _, table := range []string{"comment_votes", "comment_revisions"} {
+			if _, err := tx.exec(ctx, `DELETE FROM `+table+` WHERE comment_id IN (
+				SELECT c.id FROM comments c JOIN posts p ON p.id = c.post_id WHERE p.deleted_at < ?)`, before); err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{"post_votes", "post_revisions"} {
+			if _, err := tx.exec(ctx, "DELETE FROM "+table+" WHERE post_id IN (SELECT id FROM posts WHERE deleted_at < ?)", before); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.exec(ctx, `DELETE FROM subscriptions WHERE target_type = 'post'
+			AND target_id IN (SELECT id FROM posts WHERE deleted_at < ?)`, before); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, "DELETE FROM comments WHERE post_id IN (SELECT id FROM posts WHERE deleted_at < ?)", before); err != nil {
+			return err
+		}
+		res, err := tx.exec(ctx, "DELETE FROM posts WHERE deleted_at < ?", before)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		posts = int(n)
+
+		// Step 2: Delete expired comment tombstones from the leaves up, since removing a reply
+		// may leave its (also deleted) parent without replies
+		for {
+			if err := detachComments(ctx, tx, purgeableComments, before); err != nil {
+				return err
+			}
+			for _, table := range []string{"comment_votes", "comment_revisions"} {
+				if _, err := tx.exec(ctx, "DELETE FROM "+table+" WHERE comment_id IN ("+purgeableComments+")", before); err != nil {
+					return err
+				}
+			}
+			res, err := tx.exec(ctx, "DELETE FROM comments WHERE id IN ("+purgeableComments+")", before)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return nil
+			}
+			comments += int(n)
+		}
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return posts, comments, nil
+}
@@ -0,0 +1,192 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+const reportColumns = `r.id, r.target_type, r.target_id, r.target_user_id, r.reporter_id, COALESCE(u.username, 'Unknown'),
+	r.reason, r.details, r.status, r.created_at, r.resolved_at, COALESCE(r.resolved_by, 0), COALESCE(r.action, ''), COALESCE(r.note, '')`
+
+// Scan a row selected with reportColumns
+func scanReport(row interface{ Scan(...any) error }) (models.Report, error) {
+	var report models.Report
+	var resolvedAt sql.NullTime
+	err := row.Scan(&report.ID, &report.TargetType, &report.TargetID, &report.TargetUserID, &report.ReporterID, &report.ReporterUsername,
+		&report.Reason, &report.Details, &report.Status, &report.CreatedAt, &resolvedAt, &report.ResolvedBy, &report.Action, &report.Note)
+	if resolvedAt.Valid {
+		report.ResolvedAt = &resolvedAt.Time
+	}
+	return report, err
+}
+
+// Sort columns for the moderation queue (table alias r)
+func reportSortSpec(sort string) sortSpec {
+	if sort == store.SortNewest {
+		return sortSpec{column: "r.created_at", desc: true}
+	}
+	return sortSpec{column: "r.created_at"}
+}
+
+// Find the user behind a report's target, failing with ErrNotFound if readers can't see it
+func reportTargetUser(ctx context.Context, tx runner, targetType string, targetID int) (int, error) {
+	var query string
+	switch targetType {
+	case models.ReportPost:
+		query = "SELECT user_id FROM posts WHERE id = ? AND " + visible("")
+	case models.ReportComment:
+		query = "SELECT c.user_id FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.id = ? AND " + visible("c") + " AND " + visible("p")
+	case models.ReportUser:
+		query = "SELECT id FROM users WHERE id = ?"
+	default:
+		return 0, store.ErrNotFound
+	}
+
+	var userID int
+	err := tx.queryRow(ctx, query, targetID).Scan(&userID)
+	return userID, tx.translateError(err)
+}
+
+func (s *Store) CreateReport(ctx context.Context, report *models.Report) error {
+	return s.withTx(ctx, func(tx runner) error {
+		targetUserID, err := reportTargetUser(ctx, tx, report.TargetType, report.TargetID)
+		if err != nil {
+			return err
+		}
+
+		id, err := tx.insert(ctx, `INSERT INTO reports (target_type, target_id, target_user_id, reporter_id, reason, details, status, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, report.TargetType, report.TargetID, targetUserID, report.ReporterID,
+			report.Reason, report.Details, models.ReportOpen, report.CreatedAt)
+		if err != nil {
+			return err
+		}
+		report.ID = id
+		report.TargetUserID = targetUserID
+		report.Status = models.ReportOpen
+		return nil
+	})
+}
+
+func getReport(ctx context.Context, tx runner, id int) (models.Report, error) {
+	row := tx.queryRow(ctx, "SELECT "+reportColumns+" FROM reports r LEFT JOIN users u ON r.reporter_id = u.id WHERE r.id = ?", id)
+	report, err := scanReport(row)
+	return report, tx.translateError(err)
+}
+
+func (s *Store) GetReport(ctx context.Context, id int) (models.Report, error) {
+	return getReport(ctx, s.conn(), id)
+}
+
+func (s *Store) ListReports(ctx context.Context, filter store.ReportFilter, page pagination.Params) ([]models.Report, error) {
+	condition := "1 = 1"
+	var args []any
+	if filter.Status != "" {
+		condition += " AND r.status = ?"
+		args = append(args, filter.Status)
+	}
+	if filter.TargetType != "" {
+		condition += " AND r.target_type = ?"
+		args = append(args, filter.TargetType)
+	}
+	if filter.Reason != "" {
+		condition += " AND r.reason = ?"
+		args = append(args, filter.Reason)
+	}
+	if filter.TargetUserID != 0 {
+		condition += " AND r.target_user_id = ?"
+		args = append(args, filter.TargetUserID)
+	}
+
+	where, tail, pageArgs, err := pageClause(reportSortSpec(page.Sort), "r.id", "r.created_at", page)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.conn().query(ctx, "SELECT "+reportColumns+`
+		FROM reports r
+		LEFT JOIN users u ON r.reporter_id = u.id
+		WHERE `+condition+where+tail, append(args, pageArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// Hide or soft delete the post / comment a report targets
+func moderateTarget(ctx context.Context, tx runner, report models.Report, set string, args ...any) error {
+	switch report.TargetType {
+	case models.ReportPost:
+		return updatePostState(ctx, tx, report.TargetID, visible(""), set, args...)
+	case models.ReportComment:
+		return updateCommentState(ctx, tx, report.TargetID, -1, visible(""), set, args...)
+	}
+	return fmt.Errorf("cannot moderate a %s report", report.TargetType)
+}
+
+func (s *Store) ResolveReport(ctx context.Context, id int, resolution store.Resolution) (models.Report, error) {
+	var resolved models.Report
+	err := s.withTx(ctx, func(tx runner) error {
+		report, err := getReport(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if report.Status != models.ReportOpen {
+			return store.ErrConflict
+		}
+
+		// Step 1: Apply the action
+		reason := resolution.Note
+		if reason == "" {
+			reason = report.Reason
+		}
+		sanction := models.Sanction{UserID: report.TargetUserID, Reason: reason, ReportID: report.ID,
+			IssuedBy: resolution.ResolvedBy, CreatedAt: resolution.At}
+		switch resolution.Action {
+		case models.ActionHide:
+			err = moderateTarget(ctx, tx, report, "hidden_at = ?", resolution.At)
+		case models.ActionDelete:
+			err = moderateTarget(ctx, tx, report, "deleted_at = ?, deleted_by = ?", resolution.At, resolution.ResolvedBy)
+		case models.ActionWarn:
+			sanction.Kind = models.SanctionWarning
+			_, err = addSanction(ctx, tx, sanction)
+		case models.ActionSuspend:
+			// Logs the user out everywhere, Login refuses them until the suspension ends
+			sanction.Kind = models.SanctionSuspension
+			sanction.ExpiresAt = &resolution.SuspendUntil
+			_, err = addSanction(ctx, tx, sanction)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Step 2: Close every open report against the same target, since the action settles them all
+		status := models.ReportResolved
+		if resolution.Action == models.ActionDismiss {
+			status = models.ReportDismissed
+		}
+		if _, err := tx.exec(ctx, `UPDATE reports SET status = ?, resolved_at = ?, resolved_by = ?, action = ?, note = ?
+			WHERE target_type = ? AND target_id = ? AND status = ?`,
+			status, resolution.At, resolution.ResolvedBy, resolution.Action, resolution.Note,
+			report.TargetType, report.TargetID, models.ReportOpen); err != nil {
+			return err
+		}
+
+		resolved, err = getReport(ctx, tx, id)
+		return err
+	})
+	return resolved, err
+}
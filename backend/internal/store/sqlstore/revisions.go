@@ -0,0 +1,109 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"sample-go-app/internal/models"
+)
+
+// Read the stored (replaced) versions of a post or comment, oldest first
+func (s *Store) listRevisions(ctx context.Context, query string, id int, withTitle bool) ([]models.Revision, error) {
+	rows, err := s.conn().query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.Revision{}
+	for rows.Next() {
+		var revision models.Revision
+		var title, topic sql.NullString
+		var topicID sql.NullInt64
+		dest := []any{&revision.Revision, &revision.Content, &revision.Author, &revision.Username, &revision.CreatedAt}
+		if withTitle {
+			dest = append(dest, &title, &topic, &topicID)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		revision.Title = title.String
+		revision.Topic = topic.String
+		revision.TopicID = int(topicID.Int64)
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// Append the current version, written by editedBy at editedAt if it has been edited
+func appendCurrent(revisions []models.Revision, current models.Revision, editedBy sql.NullInt64, editedAt sql.NullTime, editorName sql.NullString) []models.Revision {
+	current.Revision = len(revisions) + 1
+	current.Current = true
+	if editedAt.Valid {
+		current.Author = int(editedBy.Int64)
+		current.Username = editorName.String
+		current.CreatedAt = editedAt.Time
+	}
+	return append(revisions, current)
+}
+
+func (s *Store) ListPostRevisions(ctx context.Context, postID int) ([]models.Revision, error) {
+	var current models.Revision
+	var editedBy sql.NullInt64
+	var editedAt sql.NullTime
+	var editorName sql.NullString
+	err := s.conn().queryRow(ctx, `
+		SELECT p.title, COALESCE(t.topic, ''), COALESCE(p.topic_id, 0), p.content, p.user_id, COALESCE(u.username, 'Unknown'), p.created_at,
+			p.edited_by, p.edited_at, COALESCE(e.username, 'Unknown')
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		LEFT JOIN users e ON p.edited_by = e.id
+		LEFT JOIN topics t ON t.id = p.topic_id
+		WHERE p.id = ? AND `+visible("p"), postID).Scan(&current.Title, &current.Topic, &current.TopicID, &current.Content, &current.Author, &current.Username, &current.CreatedAt,
+		&editedBy, &editedAt, &editorName)
+	if err != nil {
+		return nil, s.translateError(err)
+	}
+
+	revisions, err := s.listRevisions(ctx, `
+		SELECT r.revision, COALESCE(r.content, ''), COALESCE(r.user_id, 0), COALESCE(u.username, 'Unknown'), r.created_at, r.title, t.topic, r.topic_id
+		FROM post_revisions r
+		LEFT JOIN users u ON r.user_id = u.id
+		LEFT JOIN topics t ON t.id = r.topic_id
+		WHERE r.post_id = ?
+		ORDER BY r.revision`, postID, true)
+	if err != nil {
+		return nil, err
+	}
+	return appendCurrent(revisions, current, editedBy, editedAt, editorName), nil
+}
+
+func (s *Store) ListCommentRevisions(ctx context.Context, commentID int) ([]models.Revision, error) {
+	var current models.Revision
+	var editedBy sql.NullInt64
+	var editedAt sql.NullTime
+	var editorName sql.NullString
+	err := s.conn().queryRow(ctx, `
+		SELECT c.content, c.user_id, COALESCE(u.username, 'Unknown'), c.created_at,
+			c.edited_by, c.edited_at, COALESCE(e.username, 'Unknown')
+		FROM comments c
+		JOIN posts p ON p.id = c.post_id
+		LEFT JOIN users u ON c.user_id = u.id
+		LEFT JOIN users e ON c.edited_by = e.id
+		WHERE c.id = ? AND `+visible("c")+` AND `+visible("p"), commentID).Scan(&current.Content, &current.Author, &current.Username, &current.CreatedAt,
+		&editedBy, &editedAt, &editorName)
+	if err != nil {
+		return nil, s.translateError(err)
+	}
+
+	revisions, err := s.listRevisions(ctx, `
+		SELECT r.revision, r.content, COALESCE(r.user_id, 0), COALESCE(u.username, 'Unknown'), r.created_at
+		FROM comment_revisions r
+		LEFT JOIN users u ON r.user_id = u.id
+		WHERE r.comment_id = ?
+		ORDER BY r.revision`, commentID, false)
+	if err != nil {
+		return nil, err
+	}
+	return appendCurrent(revisions, current, editedBy, editedAt, editorName), nil
+}
@@ -0,0 +1,135 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"sample-go-app/internal/models"
+)
+
+func (s *Store) RolePermissions(ctx context.Context) (map[string][]models.RolePermission, error) {
+	rows, err := s.conn().query(ctx, "SELECT r.name, p.permission, p.own_only FROM roles r LEFT JOIN role_permissions p ON p.role = r.name ORDER BY r.name, p.permission")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := map[string][]models.RolePermission{}
+	for rows.Next() {
+		var role string
+		var permission sql.NullString
+		var ownOnly sql.NullBool
+		if err := rows.Scan(&role, &permission, &ownOnly); err != nil {
+			return nil, err
+		}
+		// Roles without any permission still get an entry
+		if !permission.Valid {
+			permissions[role] = []models.RolePermission{}
+			continue
+		}
+		permissions[role] = append(permissions[role], models.RolePermission{Permission: permission.String, OwnOnly: ownOnly.Bool})
+	}
+	return permissions, rows.Err()
+}
+
+func (s *Store) UserRoles(ctx context.Context, userID int) ([]models.RoleGrant, error) {
+	rows, err := s.conn().query(ctx, `
+		SELECT r.id, r.user_id, r.role, COALESCE(r.topic_id, 0), COALESCE(t.topic, ''), COALESCE(r.granted_by, 0), r.granted_at
+		FROM user_roles r
+		LEFT JOIN topics t ON t.id = r.topic_id
+		WHERE r.user_id = ?
+		ORDER BY r.topic_id IS NOT NULL, t.position, r.topic_id, r.role`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []models.RoleGrant{}
+	for rows.Next() {
+		var grant models.RoleGrant
+		if err := rows.Scan(&grant.ID, &grant.UserID, &grant.Role, &grant.TopicID, &grant.Topic, &grant.GrantedBy, &grant.GrantedAt); err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+func (s *Store) TopicAncestors(ctx context.Context, topicID int) ([]int, error) {
+	rows, err := s.conn().query(ctx, topicAncestors, topicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Keep users.isAdmin in step with the user's global role
+func syncIsAdmin(ctx context.Context, tx runner, userID int, role string) error {
+	isAdmin := 0
+	if role == models.RoleAdmin {
+		isAdmin = 1
+	}
+	_, err := tx.exec(ctx, "UPDATE users SET isAdmin = ? WHERE id = ?", isAdmin, userID)
+	return err
+}
+
+func (s *Store) GrantRole(ctx context.Context, grant *models.RoleGrant) error {
+	return s.withTx(ctx, func(tx runner) error {
+		var found int
+		if err := tx.queryRow(ctx, "SELECT 1 FROM users WHERE id = ?", grant.UserID).Scan(&found); err != nil {
+			return tx.translateError(err)
+		}
+
+		var topicID any
+		if grant.TopicID != 0 {
+			if err := tx.queryRow(ctx, "SELECT topic FROM topics WHERE id = ?", grant.TopicID).Scan(&grant.Topic); err != nil {
+				return tx.translateError(err)
+			}
+			topicID = grant.TopicID
+		} else {
+			// A user holds a single global role, so the new one replaces the old
+			if _, err := tx.exec(ctx, "DELETE FROM user_roles WHERE user_id = ? AND topic_id IS NULL", grant.UserID); err != nil {
+				return err
+			}
+			if err := syncIsAdmin(ctx, tx, grant.UserID, grant.Role); err != nil {
+				return err
+			}
+		}
+
+		id, err := tx.insert(ctx, "INSERT INTO user_roles (user_id, role, topic_id, granted_by, granted_at) VALUES (?, ?, ?, ?, ?)",
+			grant.UserID, grant.Role, topicID, grant.GrantedBy, grant.GrantedAt)
+		if err != nil {
+			return err
+		}
+		grant.ID = id
+		return nil
+	})
+}
+
+func (s *Store) RevokeRole(ctx context.Context, grantID int) error {
+	return s.withTx(ctx, func(tx runner) error {
+		var userID int
+		var topicID sql.NullInt64
+		if err := tx.queryRow(ctx, "SELECT user_id, topic_id FROM user_roles WHERE id = ?", grantID).Scan(&userID, &topicID); err != nil {
+			return tx.translateError(err)
+		}
+		if _, err := tx.exec(ctx, "DELETE FROM user_roles WHERE id = ?", grantID); err != nil {
+			return err
+		}
+		// Without a global role the user falls back to being a member
+		if !topicID.Valid {
+			return syncIsAdmin(ctx, tx, userID, models.RoleMember)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,129 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+const sanctionColumns = "id, user_id, kind, reason, report_id, issued_by, created_at, expires_at, lifted_at, lifted_by"
+
+func scanSanction(row interface{ Scan(...any) error }) (models.Sanction, error) {
+	var sanction models.Sanction
+	var reportID, liftedBy sql.NullInt64
+	var expiresAt, liftedAt sql.NullTime
+	if err := row.Scan(&sanction.ID, &sanction.UserID, &sanction.Kind, &sanction.Reason, &reportID, &sanction.IssuedBy,
+		&sanction.CreatedAt, &expiresAt, &liftedAt, &liftedBy); err != nil {
+		return models.Sanction{}, err
+	}
+	sanction.ReportID = int(reportID.Int64)
+	sanction.LiftedBy = int(liftedBy.Int64)
+	if expiresAt.Valid {
+		sanction.ExpiresAt = &expiresAt.Time
+	}
+	if liftedAt.Valid {
+		sanction.LiftedAt = &liftedAt.Time
+	}
+	return sanction, nil
+}
+
+func scanSanctions(rows *sql.Rows) ([]models.Sanction, error) {
+	defer rows.Close()
+
+	sanctions := []models.Sanction{}
+	for rows.Next() {
+		sanction, err := scanSanction(rows)
+		if err != nil {
+			return nil, err
+		}
+		sanctions = append(sanctions, sanction)
+	}
+	return sanctions, rows.Err()
+}
+
+// Record a sanction against a user, logging them out everywhere if it locks them out
+func addSanction(ctx context.Context, tx runner, sanction models.Sanction) (int, error) {
+	// A zero ReportID is stored as NULL, marking a sanction issued directly by an admin
+	var reportID sql.NullInt64
+	if sanction.ReportID != 0 {
+		reportID = sql.NullInt64{Int64: int64(sanction.ReportID), Valid: true}
+	}
+
+	id, err := tx.insert(ctx, `INSERT INTO user_sanctions (user_id, kind, reason, report_id, issued_by, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, sanction.UserID, sanction.Kind, sanction.Reason, reportID,
+		sanction.IssuedBy, sanction.CreatedAt, sanction.ExpiresAt)
+	if err != nil {
+		return 0, err
+	}
+	if sanction.LocksOut() {
+		if _, err := revokeSessionsTx(ctx, tx, sanction.CreatedAt, "user_id = ?", sanction.UserID); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+func getSanction(ctx context.Context, tx runner, userID, id int) (models.Sanction, error) {
+	sanction, err := scanSanction(tx.queryRow(ctx, "SELECT "+sanctionColumns+" FROM user_sanctions WHERE id = ? AND user_id = ?", id, userID))
+	return sanction, tx.translateError(err)
+}
+
+func (s *Store) AddSanction(ctx context.Context, sanction *models.Sanction) error {
+	return s.withTx(ctx, func(tx runner) error {
+		var found int
+		if err := tx.queryRow(ctx, "SELECT 1 FROM users WHERE id = ?", sanction.UserID).Scan(&found); err != nil {
+			return tx.translateError(err)
+		}
+
+		id, err := addSanction(ctx, tx, *sanction)
+		if err != nil {
+			return err
+		}
+		sanction.ID = id
+		return nil
+	})
+}
+
+func (s *Store) ListSanctions(ctx context.Context, userID int) ([]models.Sanction, error) {
+	rows, err := s.conn().query(ctx, "SELECT "+sanctionColumns+" FROM user_sanctions WHERE user_id = ? ORDER BY created_at DESC, id DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	return scanSanctions(rows)
+}
+
+func (s *Store) ActiveSanctions(ctx context.Context, userID int, now time.Time) ([]models.Sanction, error) {
+	rows, err := s.conn().query(ctx, `
+		SELECT `+sanctionColumns+`
+		FROM user_sanctions
+		WHERE user_id = ? AND kind IN (?, ?, ?) AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY created_at DESC, id DESC`,
+		userID, models.SanctionSuspension, models.SanctionBan, models.SanctionMute, now)
+	if err != nil {
+		return nil, err
+	}
+	return scanSanctions(rows)
+}
+
+func (s *Store) LiftSanction(ctx context.Context, userID, id, liftedBy int, at time.Time) (models.Sanction, error) {
+	var lifted models.Sanction
+	err := s.withTx(ctx, func(tx runner) error {
+		sanction, err := getSanction(ctx, tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if sanction.Kind == models.SanctionWarning || !sanction.ActiveAt(at) {
+			return store.ErrConflict
+		}
+
+		if _, err := tx.exec(ctx, "UPDATE user_sanctions SET lifted_at = ?, lifted_by = ? WHERE id = ?", at, liftedBy, id); err != nil {
+			return err
+		}
+		lifted, err = getSanction(ctx, tx, userID, id)
+		return err
+	})
+	return lifted, err
+}
@@ -0,0 +1,167 @@
+package sqlstore
+
+import (
+	"context"
+	"html"
+	"strings"
+
+	db "sample-go-app/internal/database"
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Delimiters the database wraps matches in, turned into <mark> tags once the rest of the snippet is escaped
+// They are private use characters, which can't be mistaken for markup in the content
+const (
+	matchStart = "\ue000"
+	matchEnd   = "\ue001"
+)
+
+// Turn a snippet of raw content into HTML, escaping the content and marking the matches
+func highlight(snippet string) string {
+	return strings.NewReplacer(matchStart, "<mark>", matchEnd, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+// Builds one SELECT of the search UNION together with its arguments
+type searchPart struct {
+	sql  string
+	args []any
+}
+
+// Add the optional topic / author / date filters shared by posts and comments
+// alias is the table alias holding user_id and created_at
+func (part *searchPart) addFilters(params store.SearchParams, alias string) {
+	if params.TopicID != 0 {
+		part.sql += " AND p.topic_id = ?"
+		part.args = append(part.args, params.TopicID)
+	}
+	if params.AuthorID != 0 {
+		part.sql += " AND " + alias + ".user_id = ?"
+		part.args = append(part.args, params.AuthorID)
+	}
+	if !params.From.IsZero() {
+		part.sql += " AND " + alias + ".created_at >= ?"
+		part.args = append(part.args, params.From.UTC())
+	}
+	if !params.To.IsZero() {
+		part.sql += " AND " + alias + ".created_at < ?"
+		part.args = append(part.args, params.To.UTC())
+	}
+}
+
+// SELECT matching posts, SQLite ranks with bm25 (lower is better, so it is negated)
+// and weights title matches above content matches
+func (s *Store) searchPostsPart(params store.SearchParams) searchPart {
+	if s.dialect == db.Postgres {
+		q := params.Query.TSQuery()
+		part := searchPart{
+			sql: `
+			SELECT 'post' AS type, p.id AS post_id, 0 AS comment_id, COALESCE(p.title, '') AS title, COALESCE(t.topic, '') AS topic,
+				p.user_id AS author, COALESCE(u.username, 'Unknown') AS username,
+				ts_headline('english', COALESCE(p.content, ''), to_tsquery('english', ?), 'StartSel=` + matchStart + `, StopSel=` + matchEnd + `, MaxWords=24, MinWords=8') AS snippet,
+				ts_rank(p.search_vector, to_tsquery('english', ?)) AS score, p.created_at AS created_at
+			FROM posts p
+			LEFT JOIN users u ON p.user_id = u.id
+			LEFT JOIN topics t ON t.id = p.topic_id
+			WHERE p.search_vector @@ to_tsquery('english', ?) AND p.merged_into IS NULL AND ` + visible("p"),
+			args: []any{q, q, q},
+		}
+		part.addFilters(params, "p")
+		return part
+	}
+
+	part := searchPart{
+		sql: `
+			SELECT 'post' AS type, p.id AS post_id, 0 AS comment_id, COALESCE(p.title, '') AS title, COALESCE(t.topic, '') AS topic,
+				p.user_id AS author, COALESCE(u.username, 'Unknown') AS username,
+				snippet(posts_fts, -1, '` + matchStart + `', '` + matchEnd + `', '…', 16) AS snippet,
+				-bm25(posts_fts, 10.0, 1.0) AS score, p.created_at AS created_at
+			FROM posts_fts
+			JOIN posts p ON p.id = posts_fts.rowid
+			LEFT JOIN users u ON p.user_id = u.id
+			LEFT JOIN topics t ON t.id = p.topic_id
+			WHERE posts_fts MATCH ? AND p.merged_into IS NULL AND ` + visible("p"),
+		args: []any{params.Query.FTS5()},
+	}
+	part.addFilters(params, "p")
+	return part
+}
+
+// SELECT matching comments whose post still exists, leaving out deleted or hidden comments and posts
+func (s *Store) searchCommentsPart(params store.SearchParams) searchPart {
+	if s.dialect == db.Postgres {
+		q := params.Query.TSQuery()
+		part := searchPart{
+			sql: `
+			SELECT 'comment' AS type, c.post_id AS post_id, c.id AS comment_id, COALESCE(p.title, '') AS title, COALESCE(t.topic, '') AS topic,
+				c.user_id AS author, COALESCE(u.username, 'Unknown') AS username,
+				ts_headline('english', c.content, to_tsquery('english', ?), 'StartSel=` + matchStart + `, StopSel=` + matchEnd + `, MaxWords=24, MinWords=8') AS snippet,
+				ts_rank(c.search_vector, to_tsquery('english', ?)) AS score, c.created_at AS created_at
+			FROM comments c
+			JOIN posts p ON p.id = c.post_id
+			LEFT JOIN users u ON c.user_id = u.id
+			LEFT JOIN topics t ON t.id = p.topic_id
+			WHERE c.search_vector @@ to_tsquery('english', ?) AND ` + visible("c") + ` AND ` + visible("p"),
+			args: []any{q, q, q},
+		}
+		part.addFilters(params, "c")
+		return part
+	}
+
+	part := searchPart{
+		sql: `
+			SELECT 'comment' AS type, c.post_id AS post_id, c.id AS comment_id, COALESCE(p.title, '') AS title, COALESCE(t.topic, '') AS topic,
+				c.user_id AS author, COALESCE(u.username, 'Unknown') AS username,
+				snippet(comments_fts, 0, '` + matchStart + `', '` + matchEnd + `', '…', 16) AS snippet,
+				-bm25(comments_fts) AS score, c.created_at AS created_at
+			FROM comments_fts
+			JOIN comments c ON c.id = comments_fts.rowid
+			JOIN posts p ON p.id = c.post_id
+			LEFT JOIN users u ON c.user_id = u.id
+			LEFT JOIN topics t ON t.id = p.topic_id
+			WHERE comments_fts MATCH ? AND ` + visible("c") + ` AND ` + visible("p"),
+		args: []any{params.Query.FTS5()},
+	}
+	part.addFilters(params, "c")
+	return part
+}
+
+func (s *Store) Search(ctx context.Context, params store.SearchParams) ([]models.SearchResult, error) {
+	var parts []searchPart
+	if params.Type != store.SearchComments {
+		parts = append(parts, s.searchPostsPart(params))
+	}
+	if params.Type != store.SearchPosts {
+		parts = append(parts, s.searchCommentsPart(params))
+	}
+
+	// Combine the parts and rank them together
+	sqls := make([]string, 0, len(parts))
+	var args []any
+	for _, part := range parts {
+		sqls = append(sqls, part.sql)
+		args = append(args, part.args...)
+	}
+	query := strings.Join(sqls, "\nUNION ALL\n") + `
+		ORDER BY score DESC, created_at DESC
+		LIMIT ? OFFSET ?`
+	args = append(args, params.Limit, params.Offset)
+
+	rows, err := s.conn().query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var result models.SearchResult
+		if err := rows.Scan(&result.Type, &result.PostID, &result.CommentID, &result.Title, &result.Topic,
+			&result.Author, &result.Username, &result.Snippet, &result.Score, &result.CreatedAt); err != nil {
+			return nil, err
+		}
+		result.Snippet = highlight(result.Snippet)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,171 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+const sessionColumns = `id, user_id, refresh_token_hash, COALESCE(previous_token_hash, ''), access_token_id, access_expires_at,
+	user_agent, ip, created_at, last_used_at, expires_at, revoked_at`
+
+// Scan a row selected with sessionColumns
+func scanSession(row interface{ Scan(dest ...any) error }) (models.Session, error) {
+	var session models.Session
+	var revokedAt sql.NullTime
+	err := row.Scan(&session.ID, &session.UserID, &session.RefreshTokenHash, &session.PreviousTokenHash,
+		&session.AccessTokenID, &session.AccessExpiresAt, &session.UserAgent, &session.IP,
+		&session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt, &revokedAt)
+	session.Revoked = revokedAt.Valid
+	return session, err
+}
+
+// Add an access token to the denylist until it expires, clearing out entries that no longer matter
+func denyToken(ctx context.Context, tx runner, tokenID string, expiresAt, now time.Time) error {
+	if _, err := tx.exec(ctx, "DELETE FROM revoked_tokens WHERE expires_at < ?", now); err != nil {
+		return err
+	}
+	if !expiresAt.After(now) {
+		return nil
+	}
+	_, err := tx.exec(ctx, "INSERT INTO revoked_tokens (token_id, expires_at) VALUES (?, ?) ON CONFLICT (token_id) DO NOTHING",
+		tokenID, expiresAt)
+	return err
+}
+
+func (s *Store) CreateSession(ctx context.Context, session models.Session) error {
+	_, err := s.conn().exec(ctx, `
+		INSERT INTO sessions (id, user_id, refresh_token_hash, access_token_id, access_expires_at,
+			user_agent, ip, created_at, last_used_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.UserID, session.RefreshTokenHash, session.AccessTokenID, session.AccessExpiresAt,
+		session.UserAgent, session.IP, session.CreatedAt, session.LastUsedAt, session.ExpiresAt)
+	return s.translateError(err)
+}
+
+func (s *Store) GetSessionByTokenHash(ctx context.Context, tokenHash string) (models.Session, error) {
+	row := s.conn().queryRow(ctx, "SELECT "+sessionColumns+" FROM sessions WHERE refresh_token_hash = ? OR previous_token_hash = ?",
+		tokenHash, tokenHash)
+	session, err := scanSession(row)
+	return session, s.translateError(err)
+}
+
+func (s *Store) RotateSession(ctx context.Context, session models.Session, previousHash string) error {
+	return s.withTx(ctx, func(tx runner) error {
+		// The access token being replaced stops working straight away
+		var oldTokenID string
+		var oldExpiresAt time.Time
+		err := tx.queryRow(ctx, "SELECT access_token_id, access_expires_at FROM sessions WHERE id = ? AND refresh_token_hash = ? AND revoked_at IS NULL",
+			session.ID, previousHash).Scan(&oldTokenID, &oldExpiresAt)
+		if err != nil {
+			return tx.translateError(err)
+		}
+
+		res, err := tx.exec(ctx, `
+			UPDATE sessions SET refresh_token_hash = ?, previous_token_hash = ?, access_token_id = ?, access_expires_at = ?,
+				last_used_at = ?, expires_at = ?
+			WHERE id = ? AND refresh_token_hash = ? AND revoked_at IS NULL`,
+			session.RefreshTokenHash, previousHash, session.AccessTokenID, session.AccessExpiresAt,
+			session.LastUsedAt, session.ExpiresAt, session.ID, previousHash)
+		if err != nil {
+			return err
+		}
+		if err := checkAffected(res); err != nil {
+			return err
+		}
+		return denyToken(ctx, tx, oldTokenID, oldExpiresAt, session.LastUsedAt)
+	})
+}
+
+func (s *Store) ListUserSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error) {
+	rows, err := s.conn().query(ctx, "SELECT "+sessionColumns+` FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_used_at DESC, id`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.Session{}
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Revoke the live sessions matching condition, denying their latest access tokens
+func (s *Store) revokeSessions(ctx context.Context, now time.Time, condition string, args ...any) (int, error) {
+	revoked := 0
+	err := s.withTx(ctx, func(tx runner) error {
+		var err error
+		revoked, err = revokeSessionsTx(ctx, tx, now, condition, args...)
+		return err
+	})
+	return revoked, err
+}
+
+// Revoke the live sessions matching condition as part of a larger transaction
+func revokeSessionsTx(ctx context.Context, tx runner, now time.Time, condition string, args ...any) (int, error) {
+	rows, err := tx.query(ctx, "SELECT id, access_token_id, access_expires_at FROM sessions WHERE revoked_at IS NULL AND "+condition, args...)
+	if err != nil {
+		return 0, err
+	}
+	type liveSession struct {
+		id, tokenID string
+		expiresAt   time.Time
+	}
+	var sessions []liveSession
+	for rows.Next() {
+		var session liveSession
+		if err := rows.Scan(&session.id, &session.tokenID, &session.expiresAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		sessions = append(sessions, session)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		if _, err := tx.exec(ctx, "UPDATE sessions SET revoked_at = ? WHERE id = ?", now, session.id); err != nil {
+			return 0, err
+		}
+		if err := denyToken(ctx, tx, session.tokenID, session.expiresAt, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+func (s *Store) RevokeSession(ctx context.Context, userID int, sessionID string, now time.Time) error {
+	revoked, err := s.revokeSessions(ctx, now, "id = ? AND user_id = ?", sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) RevokeUserSessions(ctx context.Context, userID int, now time.Time) (int, error) {
+	return s.revokeSessions(ctx, now, "user_id = ?", userID)
+}
+
+func (s *Store) IsTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	var found int
+	err := s.conn().queryRow(ctx, "SELECT 1 FROM revoked_tokens WHERE token_id = ?", tokenID).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
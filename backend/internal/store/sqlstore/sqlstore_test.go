@@ -0,0 +1,55 @@
+package sqlstore_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	db "sample-go-app/internal/database"
+	"sample-go-app/internal/store"
+	"sample-go-app/internal/store/sqlstore"
+	"sample-go-app/internal/store/storetest"
+)
+
+// Names a throwaway Postgres database to run the tests against as well, they are skipped when it is unset
+// Its public schema is wiped before every test
+const postgresDSNEnv = "FORUM_TEST_POSTGRES_DSN"
+
+// Open the database, closing it when the test ends, and bring its schema up to date
+func openStores(t *testing.T, dialect db.Dialect, dsn string, reset string) store.Stores {
+	t.Helper()
+	conn, err := sql.Open(dialect.DriverName(), dsn)
+	if err != nil {
+		t.Fatalf("open %s: %v", dialect, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if reset != "" {
+		if _, err := conn.Exec(reset); err != nil {
+			t.Fatalf("reset %s: %v", dialect, err)
+		}
+	}
+	if err := db.MigrateUp(conn, dialect); err != nil {
+		t.Fatalf("migrate %s: %v", dialect, err)
+	}
+	return sqlstore.New(conn, dialect).Stores()
+}
+
+func TestSQLite(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Stores {
+		// Foreign keys are enforced as they are on Postgres
+		dsn := "file:" + filepath.Join(t.TempDir(), "forum.db") + "?_pragma=foreign_keys(1)"
+		return openStores(t, db.SQLite, dsn, "")
+	})
+}
+
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("set %s to run the store tests against Postgres", postgresDSNEnv)
+	}
+	storetest.Run(t, func(t *testing.T) store.Stores {
+		return openStores(t, db.Postgres, dsn, "DROP SCHEMA public CASCADE; CREATE SCHEMA public")
+	})
+}
@@ -0,0 +1,147 @@
+// Package sqlstore implements the store interfaces on top of database/sql.
+// Queries are written with ? placeholders and rewritten for the configured dialect.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	db "sample-go-app/internal/database"
+	"sample-go-app/internal/store"
+)
+
+// SQL-backed implementation of every store interface
+type Store struct {
+	db      *sql.DB
+	dialect db.Dialect
+}
+
+// Create a store using an already opened and migrated database
+func New(conn *sql.DB, dialect db.Dialect) *Store {
+	return &Store{db: conn, dialect: dialect}
+}
+
+// Get the store interfaces backed by this database
+func (s *Store) Stores() store.Stores {
+	return store.Stores{
+		Posts:         s,
+		Comments:      s,
+		Topics:        s,
+		Users:         s,
+		Search:        s,
+		Votes:         s,
+		Sessions:      s,
+		Purge:         s,
+		Revisions:     s,
+		Reports:       s,
+		Sanctions:     s,
+		Audit:         s,
+		Roles:         s,
+		Threads:       s,
+		Notifications: s,
+		Subscriptions: s,
+		Emails:        s,
+	}
+}
+
+// Condition matching the posts / comments readers can see, i.e. neither deleted nor hidden by a moderator
+// alias is the table alias, empty for unqualified columns
+func visible(alias string) string {
+	if alias != "" {
+		alias += "."
+	}
+	return alias + "deleted_at IS NULL AND " + alias + "hidden_at IS NULL"
+}
+
+// Condition matching deleted or hidden posts / comments, which can be restored
+const restorable = "(deleted_at IS NOT NULL OR hidden_at IS NOT NULL)"
+
+// Implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Runs dialect-neutral queries against either the database or a transaction
+type runner struct {
+	q       queryer
+	dialect db.Dialect
+}
+
+// Get a runner outside of any transaction
+func (s *Store) conn() runner {
+	return runner{q: s.db, dialect: s.dialect}
+}
+
+// Run fn inside a transaction, committing only if it returns nil
+func (s *Store) withTx(ctx context.Context, fn func(r runner) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Defer a rollback in case anything goes wrong
+	defer tx.Rollback()
+
+	if err := fn(runner{q: tx, dialect: s.dialect}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r runner) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return r.q.ExecContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+func (r runner) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return r.q.QueryContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+func (r runner) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return r.q.QueryRowContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+// Run an INSERT into a table with an "id" primary key and return the new ID
+// Postgres has no LastInsertId, so the ID is read back with RETURNING instead
+func (r runner) insert(ctx context.Context, query string, args ...any) (int, error) {
+	if r.dialect == db.Postgres {
+		var id int
+		err := r.queryRow(ctx, query+" RETURNING id", args...).Scan(&id)
+		return id, r.translateError(err)
+	}
+
+	res, err := r.exec(ctx, query, args...)
+	if err != nil {
+		return 0, r.translateError(err)
+	}
+	id, err := res.LastInsertId()
+	return int(id), err
+}
+
+// Map database errors onto the store's sentinel errors
+func (r runner) translateError(err error) error {
+	if err == sql.ErrNoRows {
+		return store.ErrNotFound
+	}
+	if r.dialect.IsUniqueViolation(err) {
+		return store.ErrConflict
+	}
+	return err
+}
+
+// Map database errors onto the store's sentinel errors
+func (s *Store) translateError(err error) error {
+	return s.conn().translateError(err)
+}
+
+// Treat an update / delete that touched no rows as a missing row
+func checkAffected(res sql.Result) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sample-go-app/internal/models"
+)
+
+// A topic and every topic above it, given the topic's ID
+const topicAncestors = `
+	WITH RECURSIVE ancestors (id, parent_id) AS (
+		SELECT id, parent_id FROM topics WHERE id = ?
+		UNION ALL
+		SELECT t.id, t.parent_id FROM topics t JOIN ancestors a ON t.id = a.parent_id
+	)
+	SELECT id FROM ancestors`
+
+const subscriptionColumns = `s.user_id, s.target_type, s.target_id,
+	COALESCE(CASE s.target_type WHEN 'topic' THEN t.topic ELSE p.title END, ''), s.muted, s.created_at`
+
+const subscriptionJoins = `
+	LEFT JOIN topics t ON s.target_type = 'topic' AND s.target_id = t.id
+	LEFT JOIN posts p ON s.target_type = 'post' AND s.target_id = p.id`
+
+func scanSubscription(row interface{ Scan(...any) error }) (models.Subscription, error) {
+	var subscription models.Subscription
+	err := row.Scan(&subscription.UserID, &subscription.TargetType, &subscription.TargetID, &subscription.TargetName,
+		&subscription.Muted, &subscription.CreatedAt)
+	return subscription, err
+}
+
+func (s *Store) ListSubscriptions(ctx context.Context, userID int) ([]models.Subscription, error) {
+	rows, err := s.conn().query(ctx, "SELECT "+subscriptionColumns+" FROM subscriptions s"+subscriptionJoins+`
+		WHERE s.user_id = ? ORDER BY s.created_at DESC, s.target_type, s.target_id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []models.Subscription{}
+	for rows.Next() {
+		subscription, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (s *Store) GetSubscription(ctx context.Context, userID int, targetType string, targetID int) (models.Subscription, error) {
+	subscription, err := scanSubscription(s.conn().queryRow(ctx, "SELECT "+subscriptionColumns+" FROM subscriptions s"+subscriptionJoins+`
+		WHERE s.user_id = ? AND s.target_type = ? AND s.target_id = ?`, userID, targetType, targetID))
+	return subscription, s.translateError(err)
+}
+
+func (s *Store) Subscribe(ctx context.Context, subscription models.Subscription) error {
+	_, err := s.conn().exec(ctx, `INSERT INTO subscriptions (user_id, target_type, target_id, muted, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, target_type, target_id) DO UPDATE SET muted = excluded.muted`,
+		subscription.UserID, subscription.TargetType, subscription.TargetID, subscription.Muted, subscription.CreatedAt)
+	return err
+}
+
+func (s *Store) AutoSubscribe(ctx context.Context, userID, postID int, at time.Time) error {
+	_, err := s.conn().exec(ctx, `INSERT INTO subscriptions (user_id, target_type, target_id, muted, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, target_type, target_id) DO NOTHING`, userID, models.SubscriptionPost, postID, false, at)
+	return err
+}
+
+func (s *Store) Unsubscribe(ctx context.Context, userID int, targetType string, targetID int) error {
+	res, err := s.conn().exec(ctx, "DELETE FROM subscriptions WHERE user_id = ? AND target_type = ? AND target_id = ?",
+		userID, targetType, targetID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *Store) PostSubscribers(ctx context.Context, postID int) ([]int, []int, error) {
+	rows, err := s.conn().query(ctx, "SELECT user_id, muted FROM subscriptions WHERE target_type = ? AND target_id = ? ORDER BY user_id",
+		models.SubscriptionPost, postID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	watching, muted := []int{}, []int{}
+	for rows.Next() {
+		var userID int
+		var isMuted bool
+		if err := rows.Scan(&userID, &isMuted); err != nil {
+			return nil, nil, err
+		}
+		if isMuted {
+			muted = append(muted, userID)
+		} else {
+			watching = append(watching, userID)
+		}
+	}
+	return watching, muted, rows.Err()
+}
+
+func (s *Store) TopicSubscribers(ctx context.Context, topicID int) ([]int, error) {
+	rows, err := s.conn().query(ctx, `SELECT DISTINCT user_id FROM subscriptions
+		WHERE target_type = 'topic' AND target_id IN (`+topicAncestors+`) ORDER BY user_id`, topicID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+func (s *Store) SubscriptionSettings(ctx context.Context, userID int) (models.SubscriptionSettings, error) {
+	var settings models.SubscriptionSettings
+	err := s.conn().queryRow(ctx, "SELECT on_post, on_comment FROM subscription_settings WHERE user_id = ?", userID).
+		Scan(&settings.OnPost, &settings.OnComment)
+	if err == sql.ErrNoRows {
+		return models.DefaultSubscriptionSettings, nil
+	}
+	return settings, err
+}
+
+func (s *Store) SetSubscriptionSettings(ctx context.Context, userID int, settings models.SubscriptionSettings) error {
+	_, err := s.conn().exec(ctx, `INSERT INTO subscription_settings (user_id, on_post, on_comment) VALUES (?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET on_post = excluded.on_post, on_comment = excluded.on_comment`,
+		userID, settings.OnPost, settings.OnComment)
+	return err
+}
+
+func (s *Store) WatchedTopicPosts(ctx context.Context, userID int, since time.Time, limit int) ([]models.Post, error) {
+	rows, err := s.conn().query(ctx, `
+		WITH RECURSIVE watched (id) AS (
+			SELECT target_id FROM subscriptions WHERE user_id = ? AND target_type = 'topic'
+			UNION
+			SELECT t.id FROM topics t JOIN watched w ON t.parent_id = w.id
+		)
+		SELECT `+postListColumns+`
+		FROM posts p
+		LEFT JOIN users u ON p.user_id = u.id
+		LEFT JOIN topics t ON t.id = p.topic_id
+		WHERE p.topic_id IN (SELECT id FROM watched) AND `+visible("p")+` AND p.merged_into IS NULL
+			AND p.user_id <> ? AND p.created_at > ?
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT ?`, userID, userID, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanPostList(rows)
+}
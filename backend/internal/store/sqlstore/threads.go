@@ -0,0 +1,114 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Subquery of the IDs of a comment and all of its replies, at any depth, taking the comment ID as its only argument
+const commentSubtree = `
+	WITH RECURSIVE subtree (id) AS (
+		SELECT id FROM comments WHERE id = ?
+		UNION ALL
+		SELECT c.id FROM comments c JOIN subtree s ON c.parent_id = s.id
+	)
+	SELECT id FROM subtree`
+
+// Check a post is visible and hasn't been merged, so its thread can be reorganised
+func checkThreadPost(ctx context.Context, tx runner, id int) error {
+	var mergedInto sql.NullInt64
+	if err := tx.queryRow(ctx, "SELECT merged_into FROM posts WHERE id = ? AND "+visible(""), id).Scan(&mergedInto); err != nil {
+		return tx.translateError(err)
+	}
+	if mergedInto.Valid {
+		return store.ErrConflict
+	}
+	return nil
+}
+
+// Recompute the comment count and last activity of a post after comments moved in or out
+func recountComments(ctx context.Context, tx runner, postID int) error {
+	_, err := tx.exec(ctx, `
+		UPDATE posts SET
+			comment_count = (SELECT COUNT(*) FROM comments c WHERE c.post_id = posts.id AND `+visible("c")+`),
+			last_activity_at = COALESCE((SELECT MAX(c.created_at) FROM comments c WHERE c.post_id = posts.id), created_at)
+		WHERE id = ?`, postID)
+	return err
+}
+
+func (s *Store) MovePost(ctx context.Context, id, topicID int) error {
+	return s.withTx(ctx, func(tx runner) error {
+		if err := checkThreadPost(ctx, tx, id); err != nil {
+			return err
+		}
+		_, err := tx.exec(ctx, "UPDATE posts SET topic_id = ? WHERE id = ?", topicID, id)
+		return err
+	})
+}
+
+func (s *Store) MergePost(ctx context.Context, id, into int) error {
+	return s.withTx(ctx, func(tx runner) error {
+		for _, postID := range []int{id, into} {
+			if err := checkThreadPost(ctx, tx, postID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.exec(ctx, "UPDATE comments SET post_id = ? WHERE post_id = ?", into, id); err != nil {
+			return err
+		}
+		// Stubs of posts merged earlier follow along, so redirects never chain
+		if _, err := tx.exec(ctx, "UPDATE posts SET merged_into = ? WHERE id = ? OR merged_into = ?", into, id, id); err != nil {
+			return err
+		}
+
+		for _, postID := range []int{id, into} {
+			if err := recountComments(ctx, tx, postID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) SplitComment(ctx context.Context, commentID int, post *models.Post, splitBy int, at time.Time) error {
+	return s.withTx(ctx, func(tx runner) error {
+		var fromID int
+		err := tx.queryRow(ctx, "SELECT post_id, user_id, content, created_at FROM comments WHERE id = ? AND "+visible(""), commentID).
+			Scan(&fromID, &post.Author, &post.Content, &post.CreatedAt)
+		if err != nil {
+			return tx.translateError(err)
+		}
+		if err := checkThreadPost(ctx, tx, fromID); err != nil {
+			return err
+		}
+
+		if err := insertPost(ctx, tx, post); err != nil {
+			return err
+		}
+
+		// Move the replies over, the direct ones becoming top-level comments of the new post
+		if _, err := tx.exec(ctx, "UPDATE comments SET post_id = ? WHERE id IN ("+commentSubtree+") AND id <> ?", post.ID, commentID, commentID); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, "UPDATE comments SET parent_id = NULL WHERE parent_id = ?", commentID); err != nil {
+			return err
+		}
+		// The comment itself now lives on as the post
+		if _, err := tx.exec(ctx, "UPDATE comments SET deleted_at = ?, deleted_by = ? WHERE id = ?", at, splitBy, commentID); err != nil {
+			return err
+		}
+
+		for _, postID := range []int{fromID, post.ID} {
+			if err := recountComments(ctx, tx, postID); err != nil {
+				return err
+			}
+		}
+		return tx.queryRow(ctx, "SELECT comment_count, last_activity_at FROM posts WHERE id = ?", post.ID).
+			Scan(&post.CommentCount, &post.LastActivityAt)
+	})
+}
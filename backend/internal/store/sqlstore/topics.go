@@ -0,0 +1,182 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+const topicColumns = "id, topic, slug, description, icon, position, COALESCE(parent_id, 0)"
+
+// Subquery of the IDs of a topic and all of its subtopics, at any depth, taking the topic ID as its only argument
+const topicSubtree = `
+	WITH RECURSIVE subtree (id) AS (
+		SELECT id FROM topics WHERE id = ?
+		UNION ALL
+		SELECT t.id FROM topics t JOIN subtree s ON t.parent_id = s.id
+	)
+	SELECT id FROM subtree`
+
+func scanTopic(row interface{ Scan(...any) error }) (models.Topic, error) {
+	var topic models.Topic
+	err := row.Scan(&topic.ID, &topic.TopicName, &topic.Slug, &topic.Description, &topic.Icon, &topic.Position, &topic.ParentID)
+	return topic, err
+}
+
+// Parent ID to store, top-level topics having none
+func topicParent(parentID int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(parentID), Valid: parentID != 0}
+}
+
+func (s *Store) ListTopics(ctx context.Context) ([]models.Topic, error) {
+	rows, err := s.conn().query(ctx, `SELECT `+topicColumns+` FROM topics ORDER BY position, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	topics := []models.Topic{}
+	for rows.Next() {
+		topic, err := scanTopic(rows)
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, topic)
+	}
+	return topics, rows.Err()
+}
+
+func (s *Store) GetTopic(ctx context.Context, id int) (models.Topic, error) {
+	topic, err := scanTopic(s.conn().queryRow(ctx, `SELECT `+topicColumns+` FROM topics WHERE id = ?`, id))
+	return topic, s.translateError(err)
+}
+
+func (s *Store) FindTopic(ctx context.Context, slugOrName string) (models.Topic, error) {
+	topic, err := scanTopic(s.conn().queryRow(ctx, `
+		SELECT `+topicColumns+` FROM topics
+		WHERE slug = ? OR topic = ?
+		ORDER BY slug = ? DESC
+		LIMIT 1`, slugOrName, slugOrName, slugOrName))
+	return topic, s.translateError(err)
+}
+
+func (s *Store) CreateTopic(ctx context.Context, topic *models.Topic) error {
+	return s.withTx(ctx, func(tx runner) error {
+		if topic.Position == 0 {
+			if err := tx.queryRow(ctx, "SELECT COALESCE(MAX(position), 0) + 1 FROM topics WHERE COALESCE(parent_id, 0) = ?",
+				topic.ParentID).Scan(&topic.Position); err != nil {
+				return err
+			}
+		}
+
+		id, err := tx.insert(ctx, "INSERT INTO topics (topic, slug, description, icon, position, parent_id) VALUES (?, ?, ?, ?, ?, ?)",
+			topic.TopicName, topic.Slug, topic.Description, topic.Icon, topic.Position, topicParent(topic.ParentID))
+		if err != nil {
+			return tx.translateError(err)
+		}
+		topic.ID = id
+		return nil
+	})
+}
+
+func (s *Store) UpdateTopic(ctx context.Context, topic models.Topic) error {
+	res, err := s.conn().exec(ctx, "UPDATE topics SET topic = ?, slug = ?, description = ?, icon = ?, position = ?, parent_id = ? WHERE id = ?",
+		topic.TopicName, topic.Slug, topic.Description, topic.Icon, topic.Position, topicParent(topic.ParentID), topic.ID)
+	if err != nil {
+		return s.translateError(err)
+	}
+	return checkAffected(res)
+}
+
+func (s *Store) DeleteTopic(ctx context.Context, id, moveTo int) error {
+	return s.withTx(ctx, func(tx runner) error {
+		if moveTo != 0 {
+			return moveTopicContent(ctx, tx, id, moveTo)
+		}
+
+		// Step 1: Delete all votes and revisions of posts in the topic or its subtopics, and of their comments,
+		// and what points at them from outside
+		inSubtree := "IN (SELECT id FROM posts WHERE topic_id IN (" + topicSubtree + "))"
+		if err := detachPosts(ctx, tx, "SELECT id FROM posts WHERE topic_id IN ("+topicSubtree+")", id); err != nil {
+			return err
+		}
+		for _, table := range []string{"comment_votes", "comment_revisions"} {
+			if _, err := tx.exec(ctx, `DELETE FROM `+table+` WHERE comment_id IN (
+				SELECT id FROM comments WHERE post_id `+inSubtree+`)`, id); err != nil {
+				return err
+			}
+		}
+		for _, table := range []string{"post_votes", "post_revisions"} {
+			if _, err := tx.exec(ctx, "DELETE FROM "+table+" WHERE post_id "+inSubtree, id); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.exec(ctx, "DELETE FROM subscriptions WHERE target_type = 'post' AND target_id "+inSubtree, id); err != nil {
+			return err
+		}
+
+		// Step 2: Delete all comments on those posts
+		if _, err := tx.exec(ctx, "DELETE FROM comments WHERE post_id "+inSubtree, id); err != nil {
+			return err
+		}
+
+		// Step 3: Delete the posts themselves
+		if _, err := tx.exec(ctx, "DELETE FROM posts WHERE topic_id IN ("+topicSubtree+")", id); err != nil {
+			return err
+		}
+
+		// Step 4: Delete the roles granted for and subscriptions to the topics, and forget them in revisions of posts that moved elsewhere
+		if _, err := tx.exec(ctx, "DELETE FROM user_roles WHERE topic_id IN ("+topicSubtree+")", id); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, "DELETE FROM subscriptions WHERE target_type = 'topic' AND target_id IN ("+topicSubtree+")", id); err != nil {
+			return err
+		}
+		if _, err := tx.exec(ctx, "UPDATE post_revisions SET topic_id = NULL WHERE topic_id IN ("+topicSubtree+")", id); err != nil {
+			return err
+		}
+
+		// Step 5: Delete the topic and its subtopics
+		res, err := tx.exec(ctx, "DELETE FROM topics WHERE id IN ("+topicSubtree+")", id)
+		if err != nil {
+			return err
+		}
+		return checkAffected(res)
+	})
+}
+
+// Move the subtopics and posts of a topic to another topic, then delete it
+func moveTopicContent(ctx context.Context, tx runner, id, moveTo int) error {
+	var exists bool
+	if err := tx.queryRow(ctx, "SELECT EXISTS (SELECT 1 FROM topics WHERE id = ?)", moveTo).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return store.ErrNotFound
+	}
+
+	if _, err := tx.exec(ctx, "UPDATE topics SET parent_id = ? WHERE parent_id = ?", moveTo, id); err != nil {
+		return err
+	}
+	if _, err := tx.exec(ctx, "UPDATE posts SET topic_id = ? WHERE topic_id = ?", moveTo, id); err != nil {
+		return err
+	}
+	if _, err := tx.exec(ctx, "DELETE FROM user_roles WHERE topic_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.exec(ctx, "DELETE FROM subscriptions WHERE target_type = 'topic' AND target_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.exec(ctx, "UPDATE post_revisions SET topic_id = NULL WHERE topic_id = ?", id); err != nil {
+		return err
+	}
+
+	res, err := tx.exec(ctx, "DELETE FROM topics WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
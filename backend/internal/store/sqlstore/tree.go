@@ -0,0 +1,73 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+func (s *Store) GetCommentTree(ctx context.Context, postID, rootID int, params store.TreeParams) ([]models.CommentNode, error) {
+	spec := commentSortSpec(params.Page.Sort)
+	direction := "ASC"
+	if spec.desc {
+		direction = "DESC"
+	}
+
+	// The first level: top-level comments, or the replies to the root comment
+	anchor := "c.post_id = ? AND c.parent_id IS NULL"
+	args := []any{postID}
+	if rootID != 0 {
+		anchor = "c.post_id = ? AND c.parent_id = ?"
+		args = append(args, rootID)
+	}
+	if params.Page.After != nil {
+		condition, keysetArgs, err := keysetCondition(spec, "c.id", params.Page.After)
+		if err != nil {
+			return nil, err
+		}
+		anchor += " AND " + condition
+		args = append(args, keysetArgs...)
+	}
+	args = append(args, params.MaxDepth, params.Page.Limit+1)
+
+	// Walk the thread down to MaxDepth in one recursive query, then rank the replies of each
+	// comment so only the first Limit+1 of every level are returned
+	rows, err := s.conn().query(ctx, fmt.Sprintf(`
+		WITH RECURSIVE thread (id, parent_id, depth) AS (
+			SELECT c.id, COALESCE(c.parent_id, 0), 0
+			FROM comments c
+			WHERE %s
+			UNION ALL
+			SELECT c.id, c.parent_id, t.depth + 1
+			FROM comments c
+			JOIN thread t ON c.parent_id = t.id
+			WHERE t.depth < ?
+		)
+		SELECT * FROM (
+			SELECT %s, t.depth,
+				(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count,
+				ROW_NUMBER() OVER (PARTITION BY t.parent_id ORDER BY %s %s, c.id %s) AS sibling_rank
+			FROM thread t
+			JOIN comments c ON c.id = t.id
+			LEFT JOIN users u ON c.user_id = u.id
+		) ranked
+		WHERE sibling_rank <= ?
+		ORDER BY depth, sibling_rank`, anchor, commentColumns, spec.column, direction, direction), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := []models.CommentNode{}
+	for rows.Next() {
+		var node models.CommentNode
+		var rank int
+		if err := scanComment(rows, &node.Comment, &node.Depth, &node.ReplyCount, &rank); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
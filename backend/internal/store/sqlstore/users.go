@@ -0,0 +1,30 @@
+package sqlstore
+
+import (
+	"context"
+
+	"sample-go-app/internal/models"
+)
+
+func (s *Store) CreateUser(ctx context.Context, user *models.User) error {
+	id, err := s.conn().insert(ctx, "INSERT INTO users (username, password) VALUES (?, ?)", user.Username, user.Password)
+	if err != nil {
+		return err
+	}
+	user.ID = id
+	return nil
+}
+
+func (s *Store) GetUserByID(ctx context.Context, id int) (models.User, error) {
+	user := models.User{}
+	err := s.conn().queryRow(ctx, "SELECT id, username, password, isAdmin FROM users WHERE id = ?", id).
+		Scan(&user.ID, &user.Username, &user.Password, &user.IsAdmin)
+	return user, s.translateError(err)
+}
+
+func (s *Store) GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	user := models.User{}
+	err := s.conn().queryRow(ctx, "SELECT id, username, password, isAdmin FROM users WHERE username = ?", username).
+		Scan(&user.ID, &user.Username, &user.Password, &user.IsAdmin)
+	return user, s.translateError(err)
+}
@@ -0,0 +1,145 @@
+package sqlstore
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"sample-go-app/internal/ranking"
+)
+
+// Table and column names for one kind of votable content
+type voteTarget struct {
+	table     string // table holding the content and its cached score
+	voteTable string
+	idColumn  string // column of voteTable referencing table
+	// Query selecting a row of table by ID if it can be voted on
+	votable string
+}
+
+var (
+	postVotes = voteTarget{table: "posts", voteTable: "post_votes", idColumn: "post_id",
+		votable: "SELECT 1 FROM posts WHERE id = ? AND " + visible("")}
+	// Comments of deleted and hidden posts can't be voted on either
+	commentVotes = voteTarget{table: "comments", voteTable: "comment_votes", idColumn: "comment_id",
+		votable: "SELECT 1 FROM comments c JOIN posts p ON p.id = c.post_id WHERE c.id = ? AND " + visible("c") + " AND " + visible("p")}
+)
+
+// Sum the votes on a row and store the result as its score
+// Posts also get their hot rank recomputed, since it depends on the score
+func (t voteTarget) refreshScore(ctx context.Context, tx runner, id int) (int, error) {
+	var score int
+	err := tx.queryRow(ctx, "SELECT COALESCE(SUM(value), 0) FROM "+t.voteTable+" WHERE "+t.idColumn+" = ?", id).Scan(&score)
+	if err != nil {
+		return 0, err
+	}
+
+	if t.table != postVotes.table {
+		_, err = tx.exec(ctx, "UPDATE comments SET score = ? WHERE id = ?", score, id)
+		return score, err
+	}
+
+	var createdAt time.Time
+	if err := tx.queryRow(ctx, "SELECT created_at FROM posts WHERE id = ?", id).Scan(&createdAt); err != nil {
+		return 0, err
+	}
+	_, err = tx.exec(ctx, "UPDATE posts SET score = ?, hot_rank = ? WHERE id = ?", score, ranking.HotRank(score, createdAt), id)
+	return score, err
+}
+
+// Record or replace a user's vote on a row and return the row's new score
+func (s *Store) vote(ctx context.Context, t voteTarget, id, userID, value int) (int, error) {
+	var score int
+	err := s.withTx(ctx, func(tx runner) error {
+		// Make sure the content exists, and is visible, before voting on it
+		var exists int
+		if err := tx.queryRow(ctx, t.votable, id).Scan(&exists); err != nil {
+			return tx.translateError(err)
+		}
+
+		_, err := tx.exec(ctx, "INSERT INTO "+t.voteTable+" ("+t.idColumn+", user_id, value, created_at) VALUES (?, ?, ?, ?) "+
+			"ON CONFLICT ("+t.idColumn+", user_id) DO UPDATE SET value = excluded.value",
+			id, userID, value, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		score, err = t.refreshScore(ctx, tx, id)
+		return err
+	})
+	return score, err
+}
+
+// Remove a user's vote on a row, if any, and return the row's new score
+func (s *Store) unvote(ctx context.Context, t voteTarget, id, userID int) (int, error) {
+	var score int
+	err := s.withTx(ctx, func(tx runner) error {
+		var exists int
+		if err := tx.queryRow(ctx, t.votable, id).Scan(&exists); err != nil {
+			return tx.translateError(err)
+		}
+
+		if _, err := tx.exec(ctx, "DELETE FROM "+t.voteTable+" WHERE "+t.idColumn+" = ? AND user_id = ?", id, userID); err != nil {
+			return err
+		}
+
+		var err error
+		score, err = t.refreshScore(ctx, tx, id)
+		return err
+	})
+	return score, err
+}
+
+// Get a user's votes on the given rows, keyed by row ID
+func (s *Store) votesBy(ctx context.Context, t voteTarget, userID int, ids []int) (map[int]int, error) {
+	votes := map[int]int{}
+	if len(ids) == 0 {
+		return votes, nil
+	}
+
+	args := []any{userID}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	rows, err := s.conn().query(ctx, "SELECT "+t.idColumn+", value FROM "+t.voteTable+
+		" WHERE user_id = ? AND "+t.idColumn+" IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, value int
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, err
+		}
+		votes[id] = value
+	}
+	return votes, rows.Err()
+}
+
+func (s *Store) VotePost(ctx context.Context, postID, userID, value int) (int, error) {
+	return s.vote(ctx, postVotes, postID, userID, value)
+}
+
+func (s *Store) UnvotePost(ctx context.Context, postID, userID int) (int, error) {
+	return s.unvote(ctx, postVotes, postID, userID)
+}
+
+func (s *Store) VoteComment(ctx context.Context, commentID, userID, value int) (int, error) {
+	return s.vote(ctx, commentVotes, commentID, userID, value)
+}
+
+func (s *Store) UnvoteComment(ctx context.Context, commentID, userID int) (int, error) {
+	return s.unvote(ctx, commentVotes, commentID, userID)
+}
+
+func (s *Store) PostVotesBy(ctx context.Context, userID int, postIDs []int) (map[int]int, error) {
+	return s.votesBy(ctx, postVotes, userID, postIDs)
+}
+
+func (s *Store) CommentVotesBy(ctx context.Context, userID int, commentIDs []int) (map[int]int, error) {
+	return s.votesBy(ctx, commentVotes, userID, commentIDs)
+}
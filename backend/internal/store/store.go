@@ -0,0 +1,453 @@
+// Package store defines the storage interfaces used by the HTTP handlers.
+// Implementations live in sub-packages (sqlstore for database/sql, memory for tests).
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/search"
+)
+
+var (
+	// Returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+	// Returned when a write would violate a uniqueness constraint (e.g. a taken username)
+	ErrConflict = errors.New("conflict")
+)
+
+// Sort orders accepted by the list endpoints
+const (
+	SortNewest        = "newest"
+	SortOldest        = "oldest"
+	SortMostCommented = "most_commented"
+	SortActivity      = "activity" // most recent post or comment first
+	SortHot           = "hot"      // score decayed by age
+	SortTop           = "top"      // highest score of all time
+	SortTopDay        = "top_day"  // highest score among posts from the last day
+	SortTopWeek       = "top_week"
+	SortTopMonth      = "top_month"
+	SortTopYear       = "top_year"
+)
+
+// Sort orders for post lists, the first is the default
+var PostSorts = []string{SortNewest, SortOldest, SortMostCommented, SortActivity,
+	SortHot, SortTop, SortTopDay, SortTopWeek, SortTopMonth, SortTopYear}
+
+// How far back each windowed "top" sort looks
+var TopWindows = map[string]time.Duration{
+	SortTopDay:   24 * time.Hour,
+	SortTopWeek:  7 * 24 * time.Hour,
+	SortTopMonth: 30 * 24 * time.Hour,
+	SortTopYear:  365 * 24 * time.Hour,
+}
+
+// Whether the sort orders posts by score
+func IsTopSort(sort string) bool {
+	_, windowed := TopWindows[sort]
+	return sort == SortTop || windowed
+}
+
+// Sort orders for comment lists, the first is the default
+var CommentSorts = []string{SortNewest, SortOldest}
+
+// Cursor positioned after a post in the given sort order
+func PostCursor(sort string, post models.Post) pagination.Cursor {
+	switch {
+	case sort == SortMostCommented:
+		return pagination.IntCursor(sort, post.CommentCount, post.ID)
+	case sort == SortActivity:
+		return pagination.TimeCursor(sort, post.LastActivityAt, post.ID)
+	case sort == SortHot:
+		return pagination.FloatCursor(sort, post.HotRank, post.ID)
+	case IsTopSort(sort):
+		return pagination.IntCursor(sort, post.Score, post.ID)
+	}
+	return pagination.TimeCursor(sort, post.CreatedAt, post.ID)
+}
+
+// Cursor positioned after a comment in the given sort order
+func CommentCursor(sort string, comment models.Comment) pagination.Cursor {
+	return pagination.TimeCursor(sort, comment.CreatedAt, comment.ID)
+}
+
+// Sort orders for the moderation queue, longest waiting first by default
+var ReportSorts = []string{SortOldest, SortNewest}
+
+// Cursor positioned after a report in the given sort order
+func ReportCursor(sort string, report models.Report) pagination.Cursor {
+	return pagination.TimeCursor(sort, report.CreatedAt, report.ID)
+}
+
+// Sort orders for the audit log, most recent first by default
+var AuditSorts = []string{SortNewest, SortOldest}
+
+// Cursor positioned after an audit entry in the given sort order
+func AuditCursor(sort string, entry models.AuditEntry) pagination.Cursor {
+	return pagination.TimeCursor(sort, entry.CreatedAt, entry.ID)
+}
+
+// Sort orders for notifications, most recent first by default
+var NotificationSorts = []string{SortNewest, SortOldest}
+
+// Cursor positioned after a notification in the given sort order
+func NotificationCursor(sort string, notification models.Notification) pagination.Cursor {
+	return pagination.TimeCursor(sort, notification.CreatedAt, notification.ID)
+}
+
+// Storage for posts
+// Deleted posts, and posts hidden by a moderator, are left out of every read until they are restored
+type PostStore interface {
+	// List a page of every post (content is not loaded)
+	ListPosts(ctx context.Context, page pagination.Params) ([]models.Post, error)
+	// List a page of the posts under a topic, including those in its subtopics (at any depth) if subtopics is set
+	// Content is not loaded
+	ListPostsByTopic(ctx context.Context, topicID int, subtopics bool, page pagination.Params) ([]models.Post, error)
+	GetPost(ctx context.Context, id int) (models.Post, error)
+	// Insert the post and set its ID, the topic is taken from TopicID
+	CreatePost(ctx context.Context, post *models.Post) error
+	// Update the title, topic (by TopicID) and content of an existing post, keeping the replaced version as a revision
+	UpdatePost(ctx context.Context, post models.Post, editedBy int, at time.Time) error
+	// Soft delete a post, hiding it (and so its comments) until restored or purged
+	DeletePost(ctx context.Context, id, deletedBy int, at time.Time) error
+	// Undo DeletePost (or a moderator hiding the post), failing with ErrNotFound if the post is visible
+	RestorePost(ctx context.Context, id int) error
+	// Get the author and topic of a post, deleted or not
+	GetPostOwner(ctx context.Context, id int) (ownerID, topicID int, err error)
+}
+
+// Options for loading a comment tree
+type TreeParams struct {
+	// Sort order and number of comments per level; After only applies to the first level
+	Page pagination.Params
+	// Deepest level to load, counting the first level as 0
+	MaxDepth int
+}
+
+// Storage for comments (both top-level and nested)
+// Deleted and hidden comments are returned as tombstones (see models.Comment.Tombstone) so their replies stay in place
+// The comments of a deleted or hidden post are hidden along with it: reads, edits and votes treat them as not found
+type CommentStore interface {
+	// List a page of the top-level comments of a post
+	ListPostComments(ctx context.Context, postID int, page pagination.Params) ([]models.Comment, error)
+	// List a page of the direct replies to a comment
+	ListSubComments(ctx context.Context, commentID int, page pagination.Params) ([]models.Comment, error)
+	GetComment(ctx context.Context, id int) (models.Comment, error)
+	// Load the replies below rootID (0 for the top-level comments of the post), level by level,
+	// as a flat list in sort order with at most Page.Limit+1 replies per comment
+	// Replies of comments beyond the first Page.Limit of their level may be included and should be ignored
+	GetCommentTree(ctx context.Context, postID, rootID int, params TreeParams) ([]models.CommentNode, error)
+	// Insert the comment and set its ID, a ParentID of 0 makes it a top-level comment
+	// Also bumps the post's comment count and last activity
+	// Fails with ErrNotFound if the post or the parent comment is missing, deleted or hidden, or the post has been merged
+	CreateComment(ctx context.Context, comment *models.Comment) error
+	// Update the content of a visible comment, keeping the replaced version as a revision
+	UpdateCommentContent(ctx context.Context, id int, content string, editedBy int, at time.Time) error
+	// Soft delete a comment, leaving its replies untouched, and drop it from the post's comment count
+	DeleteComment(ctx context.Context, id, deletedBy int, at time.Time) error
+	// Undo DeleteComment (or a moderator hiding the comment), failing with ErrNotFound if the comment is visible
+	RestoreComment(ctx context.Context, id int) error
+	// Get the author of a comment and the topic of its post, deleted or not
+	GetCommentOwner(ctx context.Context, id int) (ownerID, topicID int, err error)
+}
+
+// Moderator tools for reorganising threads, each running in a single transaction
+// Deleted and hidden posts / comments can't be moved, merged or split (ErrNotFound), nor can merged posts (ErrConflict)
+type ThreadStore interface {
+	// Move a post to another topic
+	MovePost(ctx context.Context, id, topicID int) error
+	// Move every comment of a post to another post, keeping their replies in place,
+	// and leave the post behind as a stub redirecting to the other one
+	MergePost(ctx context.Context, id, into int) error
+	// Turn a comment into a new post (by the comment's author, with its content), its replies becoming
+	// the new post's comments, and delete the comment from its thread
+	// The post's title and topic are taken from the given post, which gets its ID and the rest of its fields set
+	SplitComment(ctx context.Context, commentID int, post *models.Post, splitBy int, at time.Time) error
+}
+
+// Storage for topics
+// Posts and topic roles refer to topics by ID, so topics can be renamed freely
+type TopicStore interface {
+	// List every topic by position, as a flat list (parents are given by ParentID)
+	ListTopics(ctx context.Context) ([]models.Topic, error)
+	GetTopic(ctx context.Context, id int) (models.Topic, error)
+	// Find a topic by its slug or, failing that, its name
+	FindTopic(ctx context.Context, slugOrName string) (models.Topic, error)
+	// Insert the topic and set its ID, a zero Position puts it after every other topic
+	// Fails with ErrConflict if the name or slug is taken
+	CreateTopic(ctx context.Context, topic *models.Topic) error
+	// Replace the name, slug, description, icon, position and parent of a topic
+	// Callers must make sure the parent exists and isn't the topic itself or one of its subtopics
+	// Fails with ErrNotFound if it doesn't exist, and with ErrConflict if the name or slug is taken
+	UpdateTopic(ctx context.Context, topic models.Topic) error
+	// Delete a topic
+	// With moveTo set, its subtopics and posts are moved to that topic first (which mustn't be in the deleted subtree),
+	// otherwise its subtopics are deleted too, together with all of the posts and their comments
+	DeleteTopic(ctx context.Context, id, moveTo int) error
+}
+
+// Storage for user accounts
+type UserStore interface {
+	// Insert the user (with an already hashed password) and set its ID
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUserByID(ctx context.Context, id int) (models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (models.User, error)
+}
+
+// Storage for votes on posts and comments
+// Every vote updates the cached score (and hot rank) of the voted row
+type VoteStore interface {
+	// Record a user's vote (1 or -1), replacing any earlier vote, and return the new score
+	VotePost(ctx context.Context, postID, userID, value int) (int, error)
+	// Remove a user's vote, if any, and return the new score
+	UnvotePost(ctx context.Context, postID, userID int) (int, error)
+	VoteComment(ctx context.Context, commentID, userID, value int) (int, error)
+	UnvoteComment(ctx context.Context, commentID, userID int) (int, error)
+	// Get a user's votes on the given posts, keyed by post ID (posts without a vote are omitted)
+	PostVotesBy(ctx context.Context, userID int, postIDs []int) (map[int]int, error)
+	// Get a user's votes on the given comments, keyed by comment ID
+	CommentVotesBy(ctx context.Context, userID int, commentIDs []int) (map[int]int, error)
+}
+
+// Storage for login sessions and revoked access tokens
+type SessionStore interface {
+	CreateSession(ctx context.Context, session models.Session) error
+	// Get the session whose current or previous refresh token has the given hash, revoked or not
+	GetSessionByTokenHash(ctx context.Context, tokenHash string) (models.Session, error)
+	// Replace the refresh and access tokens of a live session
+	// Fails with ErrNotFound if the session was revoked or already rotated away from previousHash
+	RotateSession(ctx context.Context, session models.Session, previousHash string) error
+	// List the unrevoked, unexpired sessions of a user, most recently used first
+	ListUserSessions(ctx context.Context, userID int, now time.Time) ([]models.Session, error)
+	// Revoke a session of the given user and deny its latest access token
+	RevokeSession(ctx context.Context, userID int, sessionID string, now time.Time) error
+	// Revoke every live session of a user, returning how many were revoked
+	RevokeUserSessions(ctx context.Context, userID int, now time.Time) (int, error)
+	// Whether the access token with the given ID (jti) has been revoked
+	IsTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// Edit history of posts and comments
+type RevisionStore interface {
+	// List every version of a post, oldest first and ending with the current one
+	// Fails with ErrNotFound for missing, deleted or hidden posts
+	ListPostRevisions(ctx context.Context, postID int) ([]models.Revision, error)
+	// List every version of a comment, failing with ErrNotFound for missing, deleted or hidden comments
+	ListCommentRevisions(ctx context.Context, commentID int) ([]models.Revision, error)
+}
+
+// Filters for the moderation queue, empty / zero values match everything
+type ReportFilter struct {
+	Status       string
+	TargetType   string
+	Reason       string
+	TargetUserID int
+}
+
+// How an admin settles a report
+type Resolution struct {
+	Action     string // one of the models.Action* constants
+	Note       string
+	ResolvedBy int
+	At         time.Time
+	// End of the author's suspension, for models.ActionSuspend
+	SuspendUntil time.Time
+}
+
+// Storage for user reports and the moderation queue
+type ReportStore interface {
+	// Insert the report and set its ID, status and the user behind the reported content
+	// Fails with ErrNotFound if the target is missing (or deleted / hidden), and with ErrConflict
+	// if the reporter already has an open report against it
+	CreateReport(ctx context.Context, report *models.Report) error
+	GetReport(ctx context.Context, id int) (models.Report, error)
+	ListReports(ctx context.Context, filter ReportFilter, page pagination.Params) ([]models.Report, error)
+	// Apply the resolution's action and close every open report against the same target, all in one transaction
+	// Fails with ErrConflict if the report is already closed, and with ErrNotFound if the action
+	// hides or deletes content that is no longer visible
+	ResolveReport(ctx context.Context, id int, resolution Resolution) (models.Report, error)
+}
+
+// Storage for warnings and restrictions placed on users
+type SanctionStore interface {
+	// Insert the sanction and set its ID, ending every session of the user it locks out
+	// Fails with ErrNotFound if the user doesn't exist
+	AddSanction(ctx context.Context, sanction *models.Sanction) error
+	// List every sanction placed on a user, newest first
+	ListSanctions(ctx context.Context, userID int) ([]models.Sanction, error)
+	// Get the suspensions, bans and mutes of a user in force at now
+	// Sanctions stop applying by themselves once they expire, so nothing needs to lift them
+	ActiveSanctions(ctx context.Context, userID int, now time.Time) ([]models.Sanction, error)
+	// End a sanction of a user before it expires
+	// Fails with ErrNotFound if the user has no such sanction, and with ErrConflict if it no longer applies
+	LiftSanction(ctx context.Context, userID, id, liftedBy int, at time.Time) (models.Sanction, error)
+}
+
+// Filters for the audit log, empty / zero values match everything
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   string
+	// Entries created in [From, To)
+	From time.Time
+	To   time.Time
+}
+
+// Storage for the audit log, which is append-only
+type AuditStore interface {
+	// Insert the entry and set its ID
+	AddAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	ListAuditEntries(ctx context.Context, filter AuditFilter, page pagination.Params) ([]models.AuditEntry, error)
+}
+
+// Storage for roles, their permissions and the roles granted to users
+type RoleStore interface {
+	// Get the permissions of every role, keyed by role name
+	RolePermissions(ctx context.Context) (map[string][]models.RolePermission, error)
+	// Get the roles granted to a user, the global one first
+	// Users without a global role are members
+	UserRoles(ctx context.Context, userID int) ([]models.RoleGrant, error)
+	// Get the IDs of a topic and every topic above it, the topic first, as roles granted in any of them apply to it
+	TopicAncestors(ctx context.Context, topicID int) ([]int, error)
+	// Grant a role and set the grant's ID, replacing the user's global role unless grant.TopicID is set
+	// Fails with ErrNotFound if the user or topic doesn't exist, and with ErrConflict if the topic role is already held
+	GrantRole(ctx context.Context, grant *models.RoleGrant) error
+	// Take back a granted role, failing with ErrNotFound if there is no such grant
+	RevokeRole(ctx context.Context, grantID int) error
+}
+
+// Storage for in-app notifications and the kinds of notification each user receives
+type NotificationStore interface {
+	// Insert the notifications, leaving out those of a type their recipient has turned off,
+	// and return the ones inserted with their IDs set
+	AddNotifications(ctx context.Context, notifications []models.Notification) ([]models.Notification, error)
+	// List a page of a user's notifications, only the unread ones if unreadOnly is set
+	ListNotifications(ctx context.Context, userID int, unreadOnly bool, page pagination.Params) ([]models.Notification, error)
+	CountUnreadNotifications(ctx context.Context, userID int) (int, error)
+	// Mark one of a user's notifications read, keeping the time it was first read
+	// Fails with ErrNotFound if the user has no such notification
+	MarkNotificationRead(ctx context.Context, userID, id int, at time.Time) error
+	// Mark every unread notification of a user read, returning how many there were
+	MarkAllNotificationsRead(ctx context.Context, userID int, at time.Time) (int, error)
+	// Get whether a user receives each type of notification, types they never set being on
+	NotificationPreferences(ctx context.Context, userID int) (map[string]bool, error)
+	// Turn the given types of notification on or off for a user, leaving the other types as they are
+	SetNotificationPreferences(ctx context.Context, userID int, preferences map[string]bool) error
+}
+
+// Storage for the topics and posts users watch or mute, and which posts they watch automatically
+type SubscriptionStore interface {
+	// List a user's subscriptions, newest first
+	ListSubscriptions(ctx context.Context, userID int) ([]models.Subscription, error)
+	// Get a user's subscription to a topic or post, failing with ErrNotFound if they have none
+	GetSubscription(ctx context.Context, userID int, targetType string, targetID int) (models.Subscription, error)
+	// Subscribe a user to a topic or post, replacing their earlier subscription to it (keeping when it was made)
+	Subscribe(ctx context.Context, subscription models.Subscription) error
+	// Subscribe a user to a post unless they already are, so a post they muted stays muted
+	AutoSubscribe(ctx context.Context, userID, postID int, at time.Time) error
+	// Remove a subscription, failing with ErrNotFound if there is none
+	Unsubscribe(ctx context.Context, userID int, targetType string, targetID int) error
+	// Get the users watching a post, and those who muted it
+	PostSubscribers(ctx context.Context, postID int) (watching, muted []int, err error)
+	// Get the users watching a topic or any topic above it
+	TopicSubscribers(ctx context.Context, topicID int) ([]int, error)
+	// Get the newest posts created after since in the topics a user watches (subtopics included),
+	// leaving out the user's own posts
+	WatchedTopicPosts(ctx context.Context, userID int, since time.Time, limit int) ([]models.Post, error)
+	// Get which posts a user is subscribed to automatically, DefaultSubscriptionSettings if they never changed it
+	SubscriptionSettings(ctx context.Context, userID int) (models.SubscriptionSettings, error)
+	SetSubscriptionSettings(ctx context.Context, userID int, settings models.SubscriptionSettings) error
+}
+
+// How long the creation of each email verification is remembered for CountEmailVerifications
+const EmailVerificationLogRetention = 24 * time.Hour
+
+// Storage for users' email addresses and settings, and the outbox of emails waiting to be sent
+type EmailStore interface {
+	// Get a user's verified and pending addresses and what is emailed to them, DefaultEmailSettings if they never changed it
+	EmailSettings(ctx context.Context, userID int) (models.EmailSettings, error)
+	// Change a user's digest frequency and whether notifications are emailed (the address fields are ignored)
+	SetEmailSettings(ctx context.Context, userID int, settings models.EmailSettings) error
+	// Whether a user other than exceptUserID has verified the address
+	EmailInUse(ctx context.Context, email string, exceptUserID int) (bool, error)
+	// Store a new address waiting to be verified, replacing the user's earlier pending one
+	// Its creation is remembered for EmailVerificationLogRetention, even once it is replaced or used
+	AddEmailVerification(ctx context.Context, verification models.EmailVerification) error
+	// Count the verifications created for a user since the given time, up to EmailVerificationLogRetention ago
+	CountEmailVerifications(ctx context.Context, userID int, since time.Time) (int, error)
+	// Make the address of an unexpired verification the user's, returning the user's ID
+	// Fails with ErrNotFound if there is no such verification, and with ErrConflict if another user verified the address first
+	VerifyEmail(ctx context.Context, tokenHash string, at time.Time) (int, error)
+	// Remove a user's verified and pending addresses, failing with ErrNotFound if they have neither
+	RemoveEmail(ctx context.Context, userID int) error
+
+	// Queue an email to be sent straight away and set its ID
+	EnqueueEmail(ctx context.Context, email *models.Email) error
+	// Get up to limit emails due to be sent at now, oldest first
+	DueEmails(ctx context.Context, now time.Time, limit int) ([]models.Email, error)
+	MarkEmailSent(ctx context.Context, id int, at time.Time) error
+	// Record a failed attempt at sending an email, to be retried at retryAt or, if it is zero, given up on
+	MarkEmailFailed(ctx context.Context, id int, reason string, at, retryAt time.Time) error
+
+	// Get the users with a verified address who get digests, with when their last digest was sent
+	DigestRecipients(ctx context.Context) ([]models.DigestRecipient, error)
+	MarkDigestSent(ctx context.Context, userID int, at time.Time) error
+}
+
+// Permanent removal of soft deleted content
+type PurgeStore interface {
+	// Delete posts deleted before the cutoff, with their comments and votes, and comments deleted
+	// before the cutoff that have no replies left, returning how many posts and comments were removed
+	// Older tombstones that still have replies are kept so the thread below them stays intact
+	PurgeDeleted(ctx context.Context, before time.Time) (posts, comments int, err error)
+}
+
+// Which kinds of content a search covers
+const (
+	SearchAll      = ""
+	SearchPosts    = "posts"
+	SearchComments = "comments"
+)
+
+// Filters and paging for a full-text search
+type SearchParams struct {
+	Query    search.Query
+	Type     string // SearchAll, SearchPosts or SearchComments
+	TopicID  int    // 0 for every topic
+	AuthorID int    // 0 for every author
+	From     time.Time
+	To       time.Time // exclusive, zero for no upper bound
+	Limit    int
+	Offset   int
+}
+
+// Full-text search over posts and comments
+type SearchStore interface {
+	// Get matching posts and comments, most relevant first
+	Search(ctx context.Context, params SearchParams) ([]models.SearchResult, error)
+}
+
+// Bundles every store the handlers depend on
+type Stores struct {
+	Posts         PostStore
+	Comments      CommentStore
+	Topics        TopicStore
+	Users         UserStore
+	Search        SearchStore
+	Votes         VoteStore
+	Sessions      SessionStore
+	Purge         PurgeStore
+	Revisions     RevisionStore
+	Reports       ReportStore
+	Sanctions     SanctionStore
+	Audit         AuditStore
+	Roles         RoleStore
+	Threads       ThreadStore
+	Notifications NotificationStore
+	Subscriptions SubscriptionStore
+	Emails        EmailStore
+}
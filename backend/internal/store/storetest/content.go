@@ -0,0 +1,379 @@
+package storetest
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/search"
+	"sample-go-app/internal/store"
+)
+
+func testUsers(f *fixture) {
+	alice := f.user("alice")
+	if alice.ID == 0 {
+		f.t.Fatal("created user has no ID")
+	}
+	f.check("create taken username", f.stores.Users.CreateUser(f.ctx, &models.User{Username: "alice", Password: "hash"}), store.ErrConflict)
+
+	byID, err := f.stores.Users.GetUserByID(f.ctx, alice.ID)
+	f.check("get user by ID", err, nil)
+	byName, err := f.stores.Users.GetUserByUsername(f.ctx, "alice")
+	f.check("get user by username", err, nil)
+	if byID != byName || byID.Username != "alice" || byID.Password != "hash" {
+		f.t.Errorf("got users %+v and %+v, want alice", byID, byName)
+	}
+
+	_, err = f.stores.Users.GetUserByID(f.ctx, alice.ID+1000)
+	f.check("get missing user", err, store.ErrNotFound)
+	_, err = f.stores.Users.GetUserByUsername(f.ctx, "bob")
+	f.check("get missing username", err, store.ErrNotFound)
+}
+
+func testTopics(f *fixture) {
+	physics := f.topic("Physics", "physics", 0)
+	quantum := f.topic("Quantum Physics", "quantum", physics.ID)
+	chemistry := f.topic("Chemistry", "chemistry", 0)
+	if physics.Position >= chemistry.Position {
+		f.t.Errorf("positions %d and %d, want new topics last", physics.Position, chemistry.Position)
+	}
+
+	f.check("create taken name", f.stores.Topics.CreateTopic(f.ctx, &models.Topic{TopicName: "Physics", Slug: "other"}), store.ErrConflict)
+	f.check("create taken slug", f.stores.Topics.CreateTopic(f.ctx, &models.Topic{TopicName: "Other", Slug: "physics"}), store.ErrConflict)
+
+	for _, ref := range []string{"quantum", "Quantum Physics"} {
+		found, err := f.stores.Topics.FindTopic(f.ctx, ref)
+		f.check("find topic "+ref, err, nil)
+		if found.ID != quantum.ID || found.ParentID != physics.ID {
+			f.t.Errorf("find %q = %+v, want %+v", ref, found, quantum)
+		}
+	}
+	_, err := f.stores.Topics.FindTopic(f.ctx, "biology")
+	f.check("find missing topic", err, store.ErrNotFound)
+
+	ancestors, err := f.stores.Roles.TopicAncestors(f.ctx, quantum.ID)
+	f.check("topic ancestors", err, nil)
+	if !slices.Equal(ancestors, []int{quantum.ID, physics.ID}) {
+		f.t.Errorf("ancestors of %d = %v, want [%d %d]", quantum.ID, ancestors, quantum.ID, physics.ID)
+	}
+
+	quantum.TopicName, quantum.ParentID = "Quantum Chemistry", chemistry.ID
+	f.check("update topic", f.stores.Topics.UpdateTopic(f.ctx, quantum), nil)
+	updated, err := f.stores.Topics.GetTopic(f.ctx, quantum.ID)
+	f.check("get topic", err, nil)
+	if updated.TopicName != "Quantum Chemistry" || updated.ParentID != chemistry.ID {
+		f.t.Errorf("updated topic = %+v", updated)
+	}
+	quantum.TopicName = "Chemistry"
+	f.check("rename to a taken name", f.stores.Topics.UpdateTopic(f.ctx, quantum), store.ErrConflict)
+}
+
+func testDeleteTopic(f *fixture) {
+	alice := f.user("alice")
+	physics := f.topic("Physics", "physics", 0)
+	quantum := f.topic("Quantum Physics", "quantum", physics.ID)
+	chemistry := f.topic("Chemistry", "chemistry", 0)
+	moved := f.post(alice, physics, "Moved", at(0))
+	kept := f.post(alice, quantum, "Kept", at(time.Minute))
+	f.comment(alice, moved.ID, 0, "comment", at(2*time.Minute))
+
+	// Moving the contents keeps the posts and subtopics
+	f.check("delete topic moving its contents", f.stores.Topics.DeleteTopic(f.ctx, physics.ID, chemistry.ID), nil)
+	_, err := f.stores.Topics.GetTopic(f.ctx, physics.ID)
+	f.check("get deleted topic", err, store.ErrNotFound)
+	post, err := f.stores.Posts.GetPost(f.ctx, moved.ID)
+	f.check("get moved post", err, nil)
+	if post.TopicID != chemistry.ID {
+		f.t.Errorf("moved post is in topic %d, want %d", post.TopicID, chemistry.ID)
+	}
+	subtopic, err := f.stores.Topics.GetTopic(f.ctx, quantum.ID)
+	f.check("get moved subtopic", err, nil)
+	if subtopic.ParentID != chemistry.ID {
+		f.t.Errorf("moved subtopic's parent is %d, want %d", subtopic.ParentID, chemistry.ID)
+	}
+
+	// Otherwise the whole subtree goes, posts and comments included, leaving posts merged into them elsewhere
+	biology := f.topic("Biology", "biology", 0)
+	stub := f.post(alice, biology, "Stub", at(3*time.Minute))
+	f.check("merge post", f.stores.Threads.MergePost(f.ctx, stub.ID, kept.ID), nil)
+	f.check("delete topic with its contents", f.stores.Topics.DeleteTopic(f.ctx, chemistry.ID, 0), nil)
+	for _, id := range []int{moved.ID, kept.ID} {
+		_, err := f.stores.Posts.GetPost(f.ctx, id)
+		f.check("get post of a deleted topic", err, store.ErrNotFound)
+	}
+	merged, err := f.stores.Posts.GetPost(f.ctx, stub.ID)
+	f.check("get post merged into a deleted topic's post", err, nil)
+	if merged.MergedInto != 0 {
+		f.t.Errorf("stub still merged into deleted post %d", merged.MergedInto)
+	}
+	_, err = f.stores.Topics.GetTopic(f.ctx, quantum.ID)
+	f.check("get deleted subtopic", err, store.ErrNotFound)
+	f.check("delete missing topic", f.stores.Topics.DeleteTopic(f.ctx, chemistry.ID, 0), store.ErrNotFound)
+}
+
+func testPostLifecycle(f *fixture) {
+	alice := f.user("alice")
+	bob := f.user("bob")
+	physics := f.topic("Physics", "physics", 0)
+	chemistry := f.topic("Chemistry", "chemistry", 0)
+
+	created := f.post(alice, physics, "Hello", at(0))
+	post, err := f.stores.Posts.GetPost(f.ctx, created.ID)
+	f.check("get post", err, nil)
+	if post.Title != "Hello" || post.Username != "alice" || post.Topic != "Physics" || !post.CreatedAt.Equal(at(0)) {
+		f.t.Errorf("got post %+v", post)
+	}
+
+	post.Title, post.Content, post.TopicID = "Hello again", "Edited", chemistry.ID
+	f.check("update post", f.stores.Posts.UpdatePost(f.ctx, post, bob.ID, at(time.Hour)), nil)
+	revisions, err := f.stores.Revisions.ListPostRevisions(f.ctx, post.ID)
+	f.check("list revisions", err, nil)
+	if len(revisions) != 2 || revisions[0].Title != "Hello" || revisions[0].Author != alice.ID ||
+		revisions[1].Title != "Hello again" || revisions[1].Author != bob.ID || revisions[1].TopicID != chemistry.ID {
+		f.t.Errorf("got revisions %+v", revisions)
+	}
+
+	f.check("delete post", f.stores.Posts.DeletePost(f.ctx, post.ID, alice.ID, at(2*time.Hour)), nil)
+	_, err = f.stores.Posts.GetPost(f.ctx, post.ID)
+	f.check("get deleted post", err, store.ErrNotFound)
+	_, err = f.stores.Revisions.ListPostRevisions(f.ctx, post.ID)
+	f.check("list revisions of a deleted post", err, store.ErrNotFound)
+	owner, topicID, err := f.stores.Posts.GetPostOwner(f.ctx, post.ID)
+	f.check("get owner of a deleted post", err, nil)
+	if owner != alice.ID || topicID != chemistry.ID {
+		f.t.Errorf("owner of deleted post = %d in topic %d, want %d in %d", owner, topicID, alice.ID, chemistry.ID)
+	}
+
+	f.check("restore post", f.stores.Posts.RestorePost(f.ctx, post.ID), nil)
+	f.check("restore visible post", f.stores.Posts.RestorePost(f.ctx, post.ID), store.ErrNotFound)
+	_, err = f.stores.Posts.GetPost(f.ctx, post.ID)
+	f.check("get restored post", err, nil)
+}
+
+func testMergedPost(f *fixture) {
+	alice := f.user("alice")
+	physics := f.topic("Physics", "physics", 0)
+	stub := f.post(alice, physics, "Stub", at(0))
+	target := f.post(alice, physics, "Target", at(time.Minute))
+	f.check("merge post", f.stores.Threads.MergePost(f.ctx, stub.ID, target.ID), nil)
+
+	// The stub only redirects to the post it was merged into, and must stay to do so
+	stub.Title = "Edited"
+	f.check("edit merged post", f.stores.Posts.UpdatePost(f.ctx, stub, alice.ID, at(time.Hour)), store.ErrNotFound)
+	f.check("delete merged post", f.stores.Posts.DeletePost(f.ctx, stub.ID, alice.ID, at(time.Hour)), store.ErrNotFound)
+	comment := models.Comment{PostID: stub.ID, Author: alice.ID, Content: "late", CreatedAt: at(time.Hour)}
+	f.check("comment on merged post", f.stores.Comments.CreateComment(f.ctx, &comment), store.ErrNotFound)
+
+	merged, err := f.stores.Posts.GetPost(f.ctx, stub.ID)
+	f.check("get merged post", err, nil)
+	if merged.Title != "Stub" || merged.MergedInto != target.ID {
+		f.t.Errorf("got merged post %+v, want the unchanged stub redirecting to %d", merged, target.ID)
+	}
+}
+
+// Page through posts with the given page size, returning their IDs
+func (f *fixture) pagePosts(sort string, limit int, list func(pagination.Params) ([]models.Post, error)) []int {
+	f.t.Helper()
+	ids := []int{}
+	params := pagination.Params{Sort: sort, Limit: limit}
+	for {
+		posts, err := list(params.Probe())
+		f.check("list posts by "+sort, err, nil)
+		page := pagination.NewPage(posts, params, func(p models.Post) pagination.Cursor { return store.PostCursor(sort, p) })
+		for _, post := range page.Items {
+			ids = append(ids, post.ID)
+		}
+		if page.NextCursor == nil {
+			return ids
+		}
+		cursor, err := pagination.DecodeCursor(*page.NextCursor)
+		f.check("decode cursor", err, nil)
+		params.After = &cursor
+		if len(ids) > 100 {
+			f.t.Fatalf("paging by %s doesn't end: %v", sort, ids)
+		}
+	}
+}
+
+func testPostPages(f *fixture) {
+	alice := f.user("alice")
+	physics := f.topic("Physics", "physics", 0)
+	quantum := f.topic("Quantum Physics", "quantum", physics.ID)
+
+	// Posts sharing a timestamp are ordered by ID
+	var oldest []int
+	for i, offset := range []time.Duration{0, time.Minute, time.Minute, time.Minute, 2 * time.Minute} {
+		topic := physics
+		if i%2 == 1 {
+			topic = quantum
+		}
+		oldest = append(oldest, f.post(alice, topic, "Post", at(offset)).ID)
+	}
+	newest := slices.Clone(oldest)
+	slices.Reverse(newest)
+
+	all := func(page pagination.Params) ([]models.Post, error) { return f.stores.Posts.ListPosts(f.ctx, page) }
+	for _, limit := range []int{1, 2, 10} {
+		if got := f.pagePosts(store.SortNewest, limit, all); !slices.Equal(got, newest) {
+			f.t.Errorf("newest posts %d at a time = %v, want %v", limit, got, newest)
+		}
+		if got := f.pagePosts(store.SortOldest, limit, all); !slices.Equal(got, oldest) {
+			f.t.Errorf("oldest posts %d at a time = %v, want %v", limit, got, oldest)
+		}
+	}
+
+	inPhysics := func(subtopics bool) func(pagination.Params) ([]models.Post, error) {
+		return func(page pagination.Params) ([]models.Post, error) {
+			return f.stores.Posts.ListPostsByTopic(f.ctx, physics.ID, subtopics, page)
+		}
+	}
+	if got := f.pagePosts(store.SortOldest, 2, inPhysics(false)); !slices.Equal(got, []int{oldest[0], oldest[2], oldest[4]}) {
+		f.t.Errorf("posts in the topic = %v, want %v", got, []int{oldest[0], oldest[2], oldest[4]})
+	}
+	if got := f.pagePosts(store.SortOldest, 2, inPhysics(true)); !slices.Equal(got, oldest) {
+		f.t.Errorf("posts in the topic and subtopics = %v, want %v", got, oldest)
+	}
+}
+
+func testComments(f *fixture) {
+	alice := f.user("alice")
+	bob := f.user("bob")
+	physics := f.topic("Physics", "physics", 0)
+	post := f.post(alice, physics, "Hello", at(0))
+	other := f.post(alice, physics, "Other", at(0))
+
+	comment := f.comment(bob, post.ID, 0, "first", at(time.Minute))
+	reply := f.comment(alice, post.ID, comment.ID, "reply", at(2*time.Minute))
+	misplaced := models.Comment{PostID: other.ID, ParentID: comment.ID, Author: bob.ID, Content: "misplaced", CreatedAt: at(3 * time.Minute)}
+	f.check("reply from another post", f.stores.Comments.CreateComment(f.ctx, &misplaced), store.ErrNotFound)
+
+	got, err := f.stores.Posts.GetPost(f.ctx, post.ID)
+	f.check("get post", err, nil)
+	if got.CommentCount != 2 || !got.LastActivityAt.Equal(at(2*time.Minute)) {
+		f.t.Errorf("post after commenting = %+v, want 2 comments and activity at the reply", got)
+	}
+
+	page := pagination.Params{Sort: store.SortOldest, Limit: 10}
+	top, err := f.stores.Comments.ListPostComments(f.ctx, post.ID, page)
+	f.check("list top-level comments", err, nil)
+	replies, err := f.stores.Comments.ListSubComments(f.ctx, comment.ID, page)
+	f.check("list replies", err, nil)
+	if len(top) != 1 || top[0].ID != comment.ID || top[0].Username != "bob" || len(replies) != 1 || replies[0].ID != reply.ID {
+		f.t.Errorf("got top-level comments %+v and replies %+v", top, replies)
+	}
+
+	f.check("edit comment", f.stores.Comments.UpdateCommentContent(f.ctx, comment.ID, "edited", bob.ID, at(time.Hour)), nil)
+	revisions, err := f.stores.Revisions.ListCommentRevisions(f.ctx, comment.ID)
+	f.check("list comment revisions", err, nil)
+	if len(revisions) != 2 || revisions[0].Content != "first" || revisions[1].Content != "edited" {
+		f.t.Errorf("got comment revisions %+v", revisions)
+	}
+
+	// Deleted comments stay as tombstones so their replies keep their place
+	f.check("delete comment", f.stores.Comments.DeleteComment(f.ctx, comment.ID, bob.ID, at(2*time.Hour)), nil)
+	tombstone, err := f.stores.Comments.GetComment(f.ctx, comment.ID)
+	f.check("get deleted comment", err, nil)
+	if !tombstone.Deleted || tombstone.Content == "edited" {
+		f.t.Errorf("deleted comment = %+v, want a tombstone", tombstone)
+	}
+	f.check("edit deleted comment", f.stores.Comments.UpdateCommentContent(f.ctx, comment.ID, "again", bob.ID, at(3*time.Hour)), store.ErrNotFound)
+	if got, _ := f.stores.Posts.GetPost(f.ctx, post.ID); got.CommentCount != 1 {
+		f.t.Errorf("post has %d comments after deleting one, want 1", got.CommentCount)
+	}
+	f.check("restore comment", f.stores.Comments.RestoreComment(f.ctx, comment.ID), nil)
+	f.check("restore visible comment", f.stores.Comments.RestoreComment(f.ctx, comment.ID), store.ErrNotFound)
+}
+
+func testCommentsOfDeletedPost(f *fixture) {
+	alice := f.user("alice")
+	physics := f.topic("Physics", "physics", 0)
+	post := f.post(alice, physics, "Hello", at(0))
+	comment := f.comment(alice, post.ID, 0, "first", at(time.Minute))
+	f.check("delete post", f.stores.Posts.DeletePost(f.ctx, post.ID, alice.ID, at(time.Hour)), nil)
+
+	_, err := f.stores.Comments.GetComment(f.ctx, comment.ID)
+	f.check("get comment of a deleted post", err, store.ErrNotFound)
+	comments, err := f.stores.Comments.ListPostComments(f.ctx, post.ID, pagination.Params{Sort: store.SortNewest, Limit: 10})
+	if err == nil && len(comments) > 0 {
+		f.t.Errorf("listed comments %+v of a deleted post", comments)
+	}
+	f.check("edit comment of a deleted post", f.stores.Comments.UpdateCommentContent(f.ctx, comment.ID, "edited", alice.ID, at(2*time.Hour)), store.ErrNotFound)
+	f.check("delete comment of a deleted post", f.stores.Comments.DeleteComment(f.ctx, comment.ID, alice.ID, at(2*time.Hour)), store.ErrNotFound)
+	_, err = f.stores.Votes.VoteComment(f.ctx, comment.ID, alice.ID, 1)
+	f.check("vote on a comment of a deleted post", err, store.ErrNotFound)
+	_, err = f.stores.Revisions.ListCommentRevisions(f.ctx, comment.ID)
+	f.check("list revisions of a comment of a deleted post", err, store.ErrNotFound)
+	reply := models.Comment{PostID: post.ID, ParentID: comment.ID, Author: alice.ID, Content: "reply", CreatedAt: at(2 * time.Hour)}
+	f.check("reply on a deleted post", f.stores.Comments.CreateComment(f.ctx, &reply), store.ErrNotFound)
+
+	f.check("restore post", f.stores.Posts.RestorePost(f.ctx, post.ID), nil)
+	_, err = f.stores.Comments.GetComment(f.ctx, comment.ID)
+	f.check("get comment of a restored post", err, nil)
+	f.check("delete comment of a restored post", f.stores.Comments.DeleteComment(f.ctx, comment.ID, alice.ID, at(3*time.Hour)), nil)
+}
+
+func testVotes(f *fixture) {
+	alice := f.user("alice")
+	bob := f.user("bob")
+	physics := f.topic("Physics", "physics", 0)
+	post := f.post(alice, physics, "Hello", at(0))
+	comment := f.comment(alice, post.ID, 0, "first", at(time.Minute))
+
+	steps := []struct {
+		what  string
+		vote  func() (int, error)
+		score int
+	}{
+		{"upvote", func() (int, error) { return f.stores.Votes.VotePost(f.ctx, post.ID, alice.ID, 1) }, 1},
+		{"upvote again", func() (int, error) { return f.stores.Votes.VotePost(f.ctx, post.ID, alice.ID, 1) }, 1},
+		{"change to a downvote", func() (int, error) { return f.stores.Votes.VotePost(f.ctx, post.ID, alice.ID, -1) }, -1},
+		{"upvote by another user", func() (int, error) { return f.stores.Votes.VotePost(f.ctx, post.ID, bob.ID, 1) }, 0},
+		{"remove a vote", func() (int, error) { return f.stores.Votes.UnvotePost(f.ctx, post.ID, alice.ID) }, 1},
+		{"remove a missing vote", func() (int, error) { return f.stores.Votes.UnvotePost(f.ctx, post.ID, alice.ID) }, 1},
+		{"upvote comment", func() (int, error) { return f.stores.Votes.VoteComment(f.ctx, comment.ID, bob.ID, 1) }, 1},
+		{"downvote comment", func() (int, error) { return f.stores.Votes.VoteComment(f.ctx, comment.ID, alice.ID, -1) }, 0},
+	}
+	for _, step := range steps {
+		score, err := step.vote()
+		f.check(step.what, err, nil)
+		if score != step.score {
+			f.t.Errorf("%s: score %d, want %d", step.what, score, step.score)
+		}
+	}
+
+	postVotes, err := f.stores.Votes.PostVotesBy(f.ctx, bob.ID, []int{post.ID})
+	f.check("get post votes", err, nil)
+	commentVotes, err := f.stores.Votes.CommentVotesBy(f.ctx, alice.ID, []int{comment.ID})
+	f.check("get comment votes", err, nil)
+	if postVotes[post.ID] != 1 || commentVotes[comment.ID] != -1 {
+		f.t.Errorf("got post votes %v and comment votes %v", postVotes, commentVotes)
+	}
+	if got, _ := f.stores.Posts.GetPost(f.ctx, post.ID); got.Score != 1 {
+		f.t.Errorf("post score = %d, want 1", got.Score)
+	}
+	_, err = f.stores.Votes.VotePost(f.ctx, post.ID+1000, alice.ID, 1)
+	f.check("vote on a missing post", err, store.ErrNotFound)
+}
+
+func testSearch(f *fixture) {
+	alice := f.user("alice")
+	physics := f.topic("Physics", "physics", 0)
+	post := models.Post{Title: "Entanglement", Content: `<script>alert("x")</script> quantum entanglement explained`,
+		TopicID: physics.ID, Author: alice.ID, CreatedAt: at(0)}
+	f.check("create post", f.stores.Posts.CreatePost(f.ctx, &post), nil)
+	f.post(alice, physics, "Unrelated", at(time.Minute))
+
+	query, err := search.Parse("quantum")
+	f.check("parse query", err, nil)
+	results, err := f.stores.Search.Search(f.ctx, store.SearchParams{Query: query, Limit: 10})
+	f.check("search", err, nil)
+	if len(results) != 1 || results[0].PostID != post.ID || results[0].Type != "post" {
+		f.t.Fatalf("got results %+v, want the one post", results)
+	}
+	// Snippets are HTML, so the content in them must be escaped
+	if snippet := results[0].Snippet; strings.Contains(snippet, "<script>") || !strings.Contains(snippet, "&lt;script&gt;") {
+		f.t.Errorf("snippet %q doesn't escape the content", snippet)
+	}
+}
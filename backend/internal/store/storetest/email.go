@@ -0,0 +1,77 @@
+package storetest
+
+import (
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Store a pending address for a user, created at the given time and valid for a day
+func (f *fixture) verification(user models.User, tokenHash, email string, createdAt time.Time) {
+	f.t.Helper()
+	verification := models.EmailVerification{TokenHash: tokenHash, UserID: user.ID, Email: email,
+		CreatedAt: createdAt, ExpiresAt: createdAt.Add(24 * time.Hour)}
+	f.check("add verification for "+email, f.stores.Emails.AddEmailVerification(f.ctx, verification), nil)
+}
+
+// Fail the test unless the user has the wanted number of verifications created since the given time
+func (f *fixture) checkVerifications(user models.User, since time.Time, want int) {
+	f.t.Helper()
+	count, err := f.stores.Emails.CountEmailVerifications(f.ctx, user.ID, since)
+	f.check("count verifications", err, nil)
+	if count != want {
+		f.t.Errorf("%d verifications since %v, want %d", count, since, want)
+	}
+}
+
+func testEmailVerification(f *fixture) {
+	alice := f.user("alice")
+	bob := f.user("bob")
+
+	// A new address replaces the pending one, but both count towards the user's verifications
+	f.verification(alice, "first", "a@example.com", at(0))
+	f.verification(alice, "second", "b@example.com", at(time.Minute))
+	settings, err := f.stores.Emails.EmailSettings(f.ctx, alice.ID)
+	f.check("get email settings", err, nil)
+	if settings.PendingEmail != "b@example.com" || settings.PendingSince == nil || !settings.PendingSince.Equal(at(time.Minute)) {
+		f.t.Errorf("settings with a pending address = %+v", settings)
+	}
+	f.checkVerifications(alice, at(0), 2)
+	f.checkVerifications(alice, at(30*time.Second), 1)
+	f.checkVerifications(bob, at(0), 0)
+
+	_, err = f.stores.Emails.VerifyEmail(f.ctx, "first", at(2*time.Minute))
+	f.check("verify a replaced address", err, store.ErrNotFound)
+	_, err = f.stores.Emails.VerifyEmail(f.ctx, "second", at(25*time.Hour))
+	f.check("verify an expired address", err, store.ErrNotFound)
+	userID, err := f.stores.Emails.VerifyEmail(f.ctx, "second", at(2*time.Minute))
+	f.check("verify address", err, nil)
+	if userID != alice.ID {
+		f.t.Errorf("verified the address of user %d, want %d", userID, alice.ID)
+	}
+	settings, err = f.stores.Emails.EmailSettings(f.ctx, alice.ID)
+	f.check("get email settings", err, nil)
+	if settings.Email != "b@example.com" || settings.VerifiedAt == nil || settings.PendingEmail != "" {
+		f.t.Errorf("settings with a verified address = %+v", settings)
+	}
+	f.checkVerifications(alice, at(0), 2)
+
+	inUse, err := f.stores.Emails.EmailInUse(f.ctx, "b@example.com", bob.ID)
+	f.check("check address in use", err, nil)
+	ownUse, err := f.stores.Emails.EmailInUse(f.ctx, "b@example.com", alice.ID)
+	f.check("check own address in use", err, nil)
+	if !inUse || ownUse {
+		f.t.Errorf("address in use by others: %v, by others than its owner: %v", inUse, ownUse)
+	}
+	f.verification(bob, "third", "b@example.com", at(3*time.Minute))
+	_, err = f.stores.Emails.VerifyEmail(f.ctx, "third", at(4*time.Minute))
+	f.check("verify an address taken meanwhile", err, store.ErrConflict)
+
+	// Verifications are only remembered for a while
+	f.verification(alice, "fourth", "c@example.com", at(store.EmailVerificationLogRetention+time.Hour))
+	f.checkVerifications(alice, at(0), 1)
+
+	f.check("remove email", f.stores.Emails.RemoveEmail(f.ctx, alice.ID), nil)
+	f.check("remove missing email", f.stores.Emails.RemoveEmail(f.ctx, alice.ID), store.ErrNotFound)
+}
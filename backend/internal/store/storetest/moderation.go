@@ -0,0 +1,85 @@
+package storetest
+
+import (
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/pagination"
+	"sample-go-app/internal/store"
+)
+
+func (f *fixture) report(reporter models.User, targetType string, targetID int) models.Report {
+	f.t.Helper()
+	report := models.Report{TargetType: targetType, TargetID: targetID, ReporterID: reporter.ID, Reason: "spam", CreatedAt: at(0)}
+	f.check("report "+targetType, f.stores.Reports.CreateReport(f.ctx, &report), nil)
+	return report
+}
+
+func testPurge(f *fixture) {
+	alice := f.user("alice")
+	bob := f.user("bob")
+	physics := f.topic("Physics", "physics", 0)
+
+	// A deleted post with everything that can point at it: comments, votes, reports, a warning
+	// given for one of the reports, notifications and a post merged into it
+	post := f.post(alice, physics, "Purged", at(0))
+	comment := f.comment(bob, post.ID, 0, "comment", at(time.Minute))
+	f.comment(alice, post.ID, comment.ID, "reply", at(2*time.Minute))
+	_, err := f.stores.Votes.VotePost(f.ctx, post.ID, bob.ID, 1)
+	f.check("vote on post", err, nil)
+	_, err = f.stores.Votes.VoteComment(f.ctx, comment.ID, alice.ID, 1)
+	f.check("vote on comment", err, nil)
+	postReport := f.report(bob, models.ReportPost, post.ID)
+	commentReport := f.report(alice, models.ReportComment, comment.ID)
+	_, err = f.stores.Reports.ResolveReport(f.ctx, commentReport.ID, store.Resolution{Action: models.ActionWarn, ResolvedBy: alice.ID, At: at(3 * time.Minute)})
+	f.check("warn for a report", err, nil)
+	_, err = f.stores.Notifications.AddNotifications(f.ctx, []models.Notification{
+		{UserID: alice.ID, Type: models.NotificationPostReply, ActorID: bob.ID, PostID: post.ID, CommentID: comment.ID, CreatedAt: at(time.Minute)},
+	})
+	f.check("notify", err, nil)
+	stub := f.post(bob, physics, "Stub", at(4*time.Minute))
+	f.check("merge post", f.stores.Threads.MergePost(f.ctx, stub.ID, post.ID), nil)
+	f.check("delete post", f.stores.Posts.DeletePost(f.ctx, post.ID, alice.ID, at(time.Hour)), nil)
+
+	// A deleted comment without replies on a post that stays, and one deleted after the cutoff
+	kept := f.post(alice, physics, "Kept", at(0))
+	lone := f.comment(bob, kept.ID, 0, "lone", at(time.Minute))
+	loneReport := f.report(alice, models.ReportComment, lone.ID)
+	f.check("delete comment", f.stores.Comments.DeleteComment(f.ctx, lone.ID, bob.ID, at(time.Hour)), nil)
+	recent := f.comment(bob, kept.ID, 0, "recent", at(time.Minute))
+	f.check("delete recent comment", f.stores.Comments.DeleteComment(f.ctx, recent.ID, bob.ID, at(3*time.Hour)), nil)
+
+	posts, comments, err := f.stores.Purge.PurgeDeleted(f.ctx, at(2*time.Hour))
+	f.check("purge", err, nil)
+	if posts != 1 || comments < 1 {
+		f.t.Errorf("purged %d posts and %d comments, want 1 post and its comments", posts, comments)
+	}
+
+	_, _, err = f.stores.Posts.GetPostOwner(f.ctx, post.ID)
+	f.check("get owner of a purged post", err, store.ErrNotFound)
+	_, _, err = f.stores.Comments.GetCommentOwner(f.ctx, lone.ID)
+	f.check("get owner of a purged comment", err, store.ErrNotFound)
+	_, _, err = f.stores.Comments.GetCommentOwner(f.ctx, recent.ID)
+	f.check("get owner of a comment deleted after the cutoff", err, nil)
+	for _, report := range []models.Report{postReport, commentReport, loneReport} {
+		_, err := f.stores.Reports.GetReport(f.ctx, report.ID)
+		f.check("get report on purged content", err, store.ErrNotFound)
+	}
+
+	// What pointed at the purged content stays, without the pointer
+	merged, err := f.stores.Posts.GetPost(f.ctx, stub.ID)
+	f.check("get post merged into a purged post", err, nil)
+	if merged.MergedInto != 0 {
+		f.t.Errorf("stub still merged into purged post %d", merged.MergedInto)
+	}
+	sanctions, err := f.stores.Sanctions.ListSanctions(f.ctx, bob.ID)
+	f.check("list sanctions", err, nil)
+	if len(sanctions) != 1 || sanctions[0].Kind != models.SanctionWarning || sanctions[0].ReportID != 0 {
+		f.t.Errorf("got sanctions %+v, want the warning without its purged report", sanctions)
+	}
+	notifications, err := f.stores.Notifications.ListNotifications(f.ctx, alice.ID, false, pagination.Params{Sort: store.SortNewest, Limit: 10})
+	f.check("list notifications", err, nil)
+	if len(notifications) != 0 {
+		f.t.Errorf("got notifications %+v about purged content", notifications)
+	}
+}
@@ -0,0 +1,95 @@
+// Package storetest holds conformance tests every implementation of the store interfaces must pass,
+// so the in-memory store used by the handler tests behaves like the SQL stores used in production.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"sample-go-app/internal/models"
+	"sample-go-app/internal/store"
+)
+
+// Opens a fresh, empty set of stores for a single test (migrated, but without seeded users, topics or posts)
+type Opener func(t *testing.T) store.Stores
+
+// Run every conformance test against the stores open returns
+func Run(t *testing.T, open Opener) {
+	tests := []struct {
+		name string
+		run  func(f *fixture)
+	}{
+		{"Users", testUsers},
+		{"Topics", testTopics},
+		{"DeleteTopic", testDeleteTopic},
+		{"PostLifecycle", testPostLifecycle},
+		{"MergedPost", testMergedPost},
+		{"PostPages", testPostPages},
+		{"Comments", testComments},
+		{"CommentsOfDeletedPost", testCommentsOfDeletedPost},
+		{"Votes", testVotes},
+		{"Search", testSearch},
+		{"Purge", testPurge},
+		{"EmailVerification", testEmailVerification},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.run(&fixture{t: t, ctx: context.Background(), stores: open(t)})
+		})
+	}
+}
+
+// Stores under test, with helpers creating the rows a test needs
+type fixture struct {
+	t      *testing.T
+	ctx    context.Context
+	stores store.Stores
+}
+
+// Fixed point in time the tests' content is created around, in whole seconds so every database keeps it exactly
+var epoch = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+// Time at the given offset from epoch
+func at(offset time.Duration) time.Time {
+	return epoch.Add(offset)
+}
+
+// Fail the test unless err is the wanted error (nil for success)
+func (f *fixture) check(what string, err, want error) {
+	f.t.Helper()
+	if !errors.Is(err, want) {
+		f.t.Fatalf("%s: got error %v, want %v", what, err, want)
+	}
+}
+
+func (f *fixture) user(username string) models.User {
+	f.t.Helper()
+	user := models.User{Username: username, Password: "hash"}
+	f.check("create user "+username, f.stores.Users.CreateUser(f.ctx, &user), nil)
+	return user
+}
+
+// Create a topic, under parentID unless it is 0
+func (f *fixture) topic(name, slug string, parentID int) models.Topic {
+	f.t.Helper()
+	topic := models.Topic{TopicName: name, Slug: slug, ParentID: parentID}
+	f.check("create topic "+name, f.stores.Topics.CreateTopic(f.ctx, &topic), nil)
+	return topic
+}
+
+func (f *fixture) post(author models.User, topic models.Topic, title string, createdAt time.Time) models.Post {
+	f.t.Helper()
+	post := models.Post{Title: title, Content: title + " content", TopicID: topic.ID, Author: author.ID, CreatedAt: createdAt}
+	f.check("create post "+title, f.stores.Posts.CreatePost(f.ctx, &post), nil)
+	return post
+}
+
+// Create a comment, replying to parentID unless it is 0
+func (f *fixture) comment(author models.User, postID, parentID int, content string, createdAt time.Time) models.Comment {
+	f.t.Helper()
+	comment := models.Comment{PostID: postID, ParentID: parentID, Author: author.ID, Content: content, CreatedAt: createdAt}
+	f.check("create comment "+content, f.stores.Comments.CreateComment(f.ctx, &comment), nil)
+	return comment
+}
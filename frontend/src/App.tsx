@@ -8,6 +8,8 @@ import ParentComment from "./pages/ParentComment";
 import Login from "./pages/Login";
 import CreateAccount from "./pages/CreateAccount";
 import NotFoundPage from "./pages/NotFoundPage";
+import VerifyEmail from "./pages/VerifyEmail";
+import EmailSettings from "./pages/EmailSettings";
 import RequireAuth from "./components/ProtectedRoute";
 
 import React from "react";
@@ -37,10 +39,12 @@ const App: React.FC = () => {
                         <Route path="/posts/:post_id/comments/:comment_id" element={<ParentComment />} />
                         <Route path="/login" element={<Login />} />
                         <Route path="/create_account" element={<CreateAccount />} />
+                        <Route path="/verify-email" element={<VerifyEmail />} />
 
                         <Route element={<RequireAuth />}>
                             <Route path="/create-post" element={<CreatePost />} />
                             <Route path="/posts/edit/:post_id" element={<EditPost />} />
+                            <Route path="/settings/email" element={<EmailSettings />} />
                         </Route>
                         <Route path="*" element={<NotFoundPage />} />
                     </Routes>
@@ -31,6 +31,9 @@ const Header: React.FC = () => {
                 {isAuthenticated ? (
                     <>
                         <br />
+                        <Link to="/settings/email">
+                            <Button color="inherit">Email Settings</Button>
+                        </Link>
                         <Link to="/">
                             <Button color="inherit" onClick={handleLogout}>
                                 Logout
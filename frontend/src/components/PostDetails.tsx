@@ -2,7 +2,7 @@ import Post from "../types/Post";
 import apiClient, { handleAxiosError } from "../utils/apiClient";
 import { RootState } from "../redux/Store"; // Import Redux RootState
 import "../index.css";
-import React, { useState } from "react";
+import React, { useEffect, useState } from "react";
 import { useParams, Link, useNavigate } from "react-router-dom";
 import { Card, CardContent, Typography, Button } from "@mui/material";
 import { useSelector } from "react-redux";
@@ -23,6 +23,24 @@ const PostDetails: React.FC<PostDetailsProps> = ({ post }: PostDetailsProps) =>
     const isAuthenticated = useSelector((state: RootState) => state.auth.isAuthenticated) && user != null;
     const isAuthor = isAuthenticated && (user?.isAdmin === 1 || user?.id === post.author); // Compare logged-in user ID with post author ID
 
+    // Whether the user watches the post (notified of every comment) or muted it (notified of nothing)
+    const [subscription, setSubscription] = useState<Post["subscription"]>(post.subscription);
+    useEffect(() => setSubscription(post.subscription), [post.subscription]);
+
+    // Watch, mute or stop following the post
+    const handleSubscription = async (next: Post["subscription"]) => {
+        try {
+            if (next) {
+                await apiClient.put(`/api/posts/${post.id}/subscription`, { muted: next === "muted" });
+            } else {
+                await apiClient.delete(`/api/posts/${post.id}/subscription`);
+            }
+            setSubscription(next);
+        } catch (err) {
+            handleAxiosError(err, setError, navigate);
+        }
+    };
+
     // Handle post deletion
     const handleDelete = async () => {
         if (!isAuthenticated || !isAuthor) {
@@ -101,6 +119,22 @@ const PostDetails: React.FC<PostDetailsProps> = ({ post }: PostDetailsProps) =>
                             </Button>
                         </>
                     )}
+                    {isAuthenticated && (
+                        <>
+                            <Button
+                                variant="outlined"
+                                onClick={() => handleSubscription(subscription === "watching" ? undefined : "watching")}
+                            >
+                                {subscription === "watching" ? "Unwatch" : "Watch"}
+                            </Button>
+                            <Button
+                                variant="outlined"
+                                onClick={() => handleSubscription(subscription === "muted" ? undefined : "muted")}
+                            >
+                                {subscription === "muted" ? "Unmute" : "Mute"}
+                            </Button>
+                        </>
+                    )}
                     <Link to={`/topics/${post.topic}`}>
                         <Button variant="contained" color="secondary">
                             Back to posts
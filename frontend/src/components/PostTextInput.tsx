@@ -1,5 +1,5 @@
 import Post from "../types/Post";
-import PostTopic from "../types/PostTopic";
+import PostTopic, { flattenTopics } from "../types/PostTopic";
 import apiClient, { handleAxiosError } from "../utils/apiClient";
 import { Button, TextField, Box, Container, Paper, FormControl, InputLabel, Select, MenuItem } from "@mui/material";
 import React, { useEffect, useState } from "react";
@@ -26,7 +26,7 @@ const TextInput: React.FC<TextInputProps> = ({ newPost, setNewPost, handleSubmit
         const fetchTopics = async () => {
             try {
                 const response = await apiClient.get(`/api/topics`);
-                setTopics(response.data); // Update posts state with fetched data
+                setTopics(flattenTopics(response.data)); // Update posts state with fetched data
             } catch (err) {
                 handleAxiosError(err, setError);
             } finally {
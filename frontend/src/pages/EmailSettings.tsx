@@ -0,0 +1,139 @@
+import EmailSettingsType from "../types/EmailSettings";
+import apiClient, { handleAxiosError } from "../utils/apiClient";
+import "../index.css";
+
+import React, { useEffect, useState } from "react";
+import { useNavigate } from "react-router-dom";
+import {
+    Box,
+    Button,
+    Checkbox,
+    Container,
+    FormControlLabel,
+    MenuItem,
+    Paper,
+    TextField,
+    Typography,
+} from "@mui/material";
+
+// Page to set the current user's email address and choose what is emailed to them
+const EmailSettings: React.FC = () => {
+    const [settings, setSettings] = useState<EmailSettingsType | null>(null);
+    const [address, setAddress] = useState("");
+    const [message, setMessage] = useState("");
+    const [error, setError] = useState("");
+    const navigate = useNavigate();
+
+    useEffect(() => {
+        apiClient
+            .get("/api/account/email")
+            .then((response) => {
+                setSettings(response.data);
+                setAddress(response.data.pending_email || response.data.email);
+            })
+            .catch((err) => handleAxiosError(err, setError, navigate));
+    }, []);
+
+    // Send a verification link to the address typed in
+    const handleSubmit = async (e: React.FormEvent<HTMLFormElement>) => {
+        e.preventDefault();
+        setError("");
+        setMessage("");
+        try {
+            const response = await apiClient.put("/api/account/email", { email: address });
+            setSettings(response.data);
+            setMessage(`A verification link was sent to ${response.data.pending_email}.`);
+        } catch (err) {
+            handleAxiosError(err, setError, navigate);
+        }
+    };
+
+    const handleRemove = async () => {
+        setError("");
+        setMessage("");
+        try {
+            await apiClient.delete("/api/account/email");
+            const response = await apiClient.get("/api/account/email");
+            setSettings(response.data);
+            setAddress("");
+        } catch (err) {
+            handleAxiosError(err, setError, navigate);
+        }
+    };
+
+    const updateSettings = async (changes: Partial<EmailSettingsType>) => {
+        setError("");
+        try {
+            const response = await apiClient.put("/api/account/email/settings", changes);
+            setSettings(response.data);
+        } catch (err) {
+            handleAxiosError(err, setError, navigate);
+        }
+    };
+
+    if (settings == null) {
+        return error ? <p className="error">{error}</p> : <p>Loading...</p>;
+    }
+
+    return (
+        <Container maxWidth="sm" style={{ marginTop: "2rem" }}>
+            <Paper elevation={3} style={{ padding: "2rem" }}>
+                <h2>Email Settings</h2>
+                <Typography variant="body2" marginBottom={2}>
+                    {settings.email ? `Your address is ${settings.email}.` : "You have no verified address."}
+                    {settings.pending_email && ` ${settings.pending_email} is waiting to be verified.`}
+                </Typography>
+                <form onSubmit={handleSubmit}>
+                    <Box marginBottom={2}>
+                        <TextField
+                            fullWidth
+                            label="Email"
+                            type="email"
+                            id="email"
+                            name="email"
+                            value={address}
+                            onChange={(e) => setAddress(e.target.value)}
+                            required
+                        />
+                    </Box>
+                    <Box display="flex" justifyContent="center" gap={2}>
+                        <Button type="submit" variant="contained" color="primary">
+                            Send verification link
+                        </Button>
+                        {(settings.email || settings.pending_email) && (
+                            <Button variant="outlined" color="secondary" onClick={handleRemove}>
+                                Remove
+                            </Button>
+                        )}
+                    </Box>
+                </form>
+                <Box marginTop={3}>
+                    <TextField
+                        select
+                        fullWidth
+                        label="Digest"
+                        value={settings.digest}
+                        onChange={(e) => updateSettings({ digest: e.target.value as EmailSettingsType["digest"] })}
+                    >
+                        <MenuItem value="off">Off</MenuItem>
+                        <MenuItem value="daily">Daily</MenuItem>
+                        <MenuItem value="weekly">Weekly</MenuItem>
+                    </TextField>
+                    <FormControlLabel
+                        control={
+                            <Checkbox
+                                checked={settings.notifications}
+                                onChange={(e) => updateSettings({ notifications: e.target.checked })}
+                            />
+                        }
+                        label="Email me notifications as they happen"
+                    />
+                </Box>
+                {message && <p>{message}</p>}
+                {error && <p className="error">{error}</p>}
+            </Paper>
+        </Container>
+    );
+};
+
+export default EmailSettings;
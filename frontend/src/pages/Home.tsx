@@ -1,4 +1,4 @@
-import PostTopic, { getDefaultPostTopic } from "../types/PostTopic";
+import PostTopic, { flattenTopics, getDefaultPostTopic } from "../types/PostTopic";
 import Topic from "../components/Topic";
 import { RootState } from "../redux/Store";
 import apiClient, { handleAxiosError } from "../utils/apiClient";
@@ -24,7 +24,7 @@ const Home: React.FC = () => {
         const fetchTopics = async () => {
             try {
                 const response = await apiClient.get(`/api/topics`);
-                setTopics(response.data); // Update topics state with fetched data
+                setTopics(flattenTopics(response.data)); // Update topics state with fetched data
             } catch (err) {
                 handleAxiosError(err, setError);
             } finally {
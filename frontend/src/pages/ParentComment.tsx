@@ -2,7 +2,7 @@ import Post, { getDefaultPost } from "../types/Post";
 import PostComment, { getDefaultPostComment } from "../types/Comment";
 import CommentDetails from "../components/CommentDetails";
 import PostDetails from "../components/PostDetails";
-import apiClient, { handleAxiosError } from "../utils/apiClient";
+import apiClient, { fetchAllPages, handleAxiosError } from "../utils/apiClient";
 import "../index.css";
 import React, { useEffect, useState } from "react";
 import { useParams, Link } from "react-router-dom";
@@ -30,10 +30,10 @@ const ParentComment: React.FC = () => {
                 const parentCommentResponse = await apiClient.get(`/api/posts/${post_id}/comments/${comment_id}`);
                 setParentComment(parentCommentResponse.data); // Update parent comment state with fetched data
 
-                const subCommentsResponse = await apiClient.get(
+                const fetchedSubComments = await fetchAllPages<PostComment>(
                     `/api/posts/${post_id}/comments/${comment_id}/subcomments`,
                 );
-                setSubComments(subCommentsResponse.data); // Update subcomment state with fetched data
+                setSubComments(fetchedSubComments); // Update subcomment state with fetched data
             } catch (err) {
                 handleAxiosError(err, setError);
             } finally {
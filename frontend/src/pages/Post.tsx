@@ -2,7 +2,7 @@ import Post, { getDefaultPost } from "../types/Post";
 import PostComment, { getDefaultPostComment } from "../types/Comment";
 import PostDetails from "../components/PostDetails";
 import CommentList from "../components/CommentList";
-import apiClient, { handleAxiosError } from "../utils/apiClient";
+import apiClient, { fetchAllPages, handleAxiosError } from "../utils/apiClient";
 import { RootState } from "../redux/Store"; // Import RootState for Redux
 import "../index.css";
 import React, { useEffect, useState } from "react";
@@ -35,10 +35,15 @@ const Posts: React.FC = () => {
         const fetchPost = async () => {
             try {
                 const postResponse = await apiClient.get(`/api/posts/${post_id}`);
+                if (postResponse.data.merged_into) {
+                    // Merged posts redirect to the post that took over their comments
+                    navigate(`/posts/${postResponse.data.merged_into}`, { replace: true });
+                    return;
+                }
                 setPost(postResponse.data); // Update post state with fetched data
 
-                const commentsResponse = await apiClient.get(`/api/posts/${post_id}/comments`);
-                setComments(commentsResponse.data); // Update comments state with fetched data
+                const fetchedComments = await fetchAllPages<PostComment>(`/api/posts/${post_id}/comments`);
+                setComments(fetchedComments); // Update comments state with fetched data
             } catch (err) {
                 handleAxiosError(err, setError, navigate);
             } finally {
@@ -47,7 +52,39 @@ const Posts: React.FC = () => {
         };
 
         fetchPost();
-    }, []); // Empty dependency array to run this effect only once on component mount
+
+        // Keep the post and its top-level comments live while the page is open
+        const stream = new EventSource(`${apiClient.defaults.baseURL}api/stream?channel=post:${post_id}`, {
+            withCredentials: true,
+        });
+        const upsertComment = (event: MessageEvent) => {
+            const comment: PostComment = JSON.parse(event.data);
+            if (comment.parent_id !== 0) {
+                return; // replies are shown on the parent comment's page
+            }
+            setComments((prevComments) =>
+                prevComments.some((c) => c.id === comment.id)
+                    ? prevComments.map((c) => (c.id === comment.id ? comment : c))
+                    : [...prevComments, comment],
+            );
+        };
+        ["comment.created", "comment.updated", "comment.deleted", "comment.restored"].forEach((type) =>
+            stream.addEventListener(type, upsertComment),
+        );
+        stream.addEventListener("post.updated", (event) => {
+            const updated: Post = JSON.parse(event.data);
+            if (updated.merged_into) {
+                navigate(`/posts/${updated.merged_into}`, { replace: true });
+            } else {
+                setPost(updated);
+            }
+        });
+        stream.addEventListener("post.deleted", () => setError("This post has been deleted"));
+        // Some events were missed while disconnected, so reload everything
+        stream.addEventListener("reset", () => fetchPost());
+
+        return () => stream.close();
+    }, [post_id]); // Run again when following a merged post's redirect
 
     // Handle adding a comment
     const handleAddComment = async () => {
@@ -68,8 +105,10 @@ const Posts: React.FC = () => {
                 // Successfully created the comment
                 alert("Comment added successfully!");
 
-                // Add the new comment to the current comments state
-                setComments((prevComments) => [...prevComments, response.data]);
+                // Add the new comment to the current comments state, unless the live stream already did
+                setComments((prevComments) =>
+                    prevComments.some((c) => c.id === response.data.id) ? prevComments : [...prevComments, response.data],
+                );
                 setNewComment(getDefaultPostComment); // Clear the comment input
                 setOpen(false); // Close the comment form
             } else {
@@ -1,6 +1,6 @@
 import PostList from "../components/PostList";
 import Post from "../types/Post";
-import apiClient, { handleAxiosError } from "../utils/apiClient";
+import { fetchAllPages, handleAxiosError } from "../utils/apiClient";
 import "../index.css";
 import React, { useEffect, useState } from "react";
 import { Button, TextField, FormControl, InputLabel, Select, MenuItem, Typography } from "@mui/material";
@@ -22,10 +22,10 @@ const TopicPosts: React.FC = () => {
             try {
                 // if topic was provided, get posts attached to it
                 // else get all posts
-                const response = topic
-                    ? await apiClient.get(`/api/topics/${topic}`)
-                    : await apiClient.get(`/api/posts`);
-                setPosts(response.data); // Update posts state with fetched data
+                const fetchedPosts = topic
+                    ? await fetchAllPages<Post>(`/api/topics/${topic}`)
+                    : await fetchAllPages<Post>(`/api/posts`);
+                setPosts(fetchedPosts); // Update posts state with fetched data
             } catch (err) {
                 handleAxiosError(err, setError);
             } finally {
@@ -0,0 +1,40 @@
+import apiClient, { handleAxiosError } from "../utils/apiClient";
+import "../index.css";
+
+import React, { useEffect, useState } from "react";
+import { Link, useSearchParams } from "react-router-dom";
+
+// Page opened from the link in a verification email, confirming the address with the token it carries
+const VerifyEmail: React.FC = () => {
+    const [searchParams] = useSearchParams();
+    const [verified, setVerified] = useState(false);
+    const [error, setError] = useState("");
+
+    useEffect(() => {
+        const token = searchParams.get("token");
+        if (!token) {
+            setError("Error: The link is missing its token");
+            return;
+        }
+        apiClient
+            .post("/api/account/email/verify", { token })
+            .then(() => setVerified(true))
+            .catch((err) => handleAxiosError(err, setError));
+    }, [searchParams]);
+
+    return (
+        <>
+            <h2>Verify Email</h2>
+            {verified && (
+                <p>
+                    Your email address is verified. You can choose what we email you in your{" "}
+                    <Link to="/settings/email">email settings</Link>.
+                </p>
+            )}
+            {!verified && !error && <p>Verifying...</p>}
+            {error && <p className="error">{error}</p>}
+        </>
+    );
+};
+
+export default VerifyEmail;
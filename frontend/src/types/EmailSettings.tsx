@@ -0,0 +1,11 @@
+// Represents the current user's email address and what the forum emails them
+type EmailSettings = {
+    email: string;
+    verified_at: string | null;
+    pending_email?: string;
+    pending_since?: string;
+    digest: "off" | "daily" | "weekly";
+    notifications: boolean;
+};
+
+export default EmailSettings;
@@ -7,6 +7,8 @@ type Post = {
     author: number;
     username: string;
     created_at: string;
+    merged_into?: number; // Set when the post was merged into another one
+    subscription?: "watching" | "muted"; // How the current user follows the post, unset if they don't
 };
 
 // Utility function to initialize default placeholder post
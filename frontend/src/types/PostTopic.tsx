@@ -1,6 +1,7 @@
 // Represents a topic
 type PostTopic = {
     topic_name: string;
+    children?: PostTopic[]; // Subtopics, when topics are fetched as a tree
 };
 
 // Utility function to initialize default placeholder topic
@@ -10,4 +11,9 @@ export const getDefaultPostTopic = (): PostTopic => {
     };
 };
 
+// Utility function to flatten a topic tree into a list, parents before their subtopics
+export const flattenTopics = (topics: PostTopic[]): PostTopic[] => {
+    return topics.flatMap((topic) => [topic, ...flattenTopics(topic.children ?? [])]);
+};
+
 export default PostTopic;
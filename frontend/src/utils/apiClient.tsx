@@ -6,6 +6,39 @@ const apiClient = axios.create({
     withCredentials: true, // Automatically include cookies
 });
 
+// Access tokens are short-lived; when one expires, exchange the refresh token for a new one
+// and retry the request once. Concurrent requests share a single refresh, since a refresh
+// token can only be used once.
+let refreshing: Promise<unknown> | null = null;
+
+apiClient.interceptors.response.use(undefined, async (err) => {
+    const config = err.config;
+    const url: string = config?.url ?? "";
+    if (
+        !isAxiosError(err) ||
+        err.response?.status !== 401 ||
+        !config ||
+        config._retried ||
+        url.includes("/api/refresh") ||
+        url.includes("/api/login")
+    ) {
+        throw err;
+    }
+
+    if (!refreshing) {
+        refreshing = apiClient.post("/api/refresh").finally(() => {
+            refreshing = null;
+        });
+    }
+    try {
+        await refreshing;
+    } catch {
+        throw err;
+    }
+    config._retried = true;
+    return apiClient(config);
+});
+
 // helper function to handle Axios errors when communicating with the server
 export const handleAxiosError = (
     err: unknown,
@@ -34,4 +67,26 @@ export const handleAxiosError = (
     }
 };
 
+// A page of results from a paginated list endpoint
+interface Page<T> {
+    items: T[];
+    next_cursor: string | null;
+}
+
+// helper function to fetch every page of a paginated list endpoint
+export const fetchAllPages = async <T,>(url: string): Promise<T[]> => {
+    const items: T[] = [];
+    let cursor: string | null = null;
+    do {
+        const params: Record<string, string | number> = { limit: 100 };
+        if (cursor) {
+            params.cursor = cursor;
+        }
+        const response: { data: Page<T> } = await apiClient.get(url, { params });
+        items.push(...response.data.items);
+        cursor = response.data.next_cursor;
+    } while (cursor);
+    return items;
+};
+
 export default apiClient;